@@ -1,23 +1,127 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	_ "time/tzdata"
 
+	configfiles "github.com/NarthurN/QuitSmoking/configs"
+	"github.com/NarthurN/QuitSmoking/internal/achievements"
+	"github.com/NarthurN/QuitSmoking/internal/audit"
+	"github.com/NarthurN/QuitSmoking/internal/configs"
 	"github.com/NarthurN/QuitSmoking/internal/handlers"
+	"github.com/NarthurN/QuitSmoking/internal/helpers"
+	"github.com/NarthurN/QuitSmoking/internal/media"
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/NarthurN/QuitSmoking/internal/notify"
+	"github.com/NarthurN/QuitSmoking/internal/render"
 	"github.com/NarthurN/QuitSmoking/internal/server"
+	"github.com/NarthurN/QuitSmoking/internal/webpush"
+	"github.com/NarthurN/QuitSmoking/static"
 )
 
 func main() {
+	dev := flag.Bool("dev", false, "читать шаблоны, статику и правила достижений с диска, а не из бинарника")
+	dataDir := flag.String("data", configs.DataDir, "каталог данных: ключ VAPID и загруженные файлы")
+	vapidKey := flag.String("vapid-key", "", "файл ключа VAPID (по умолчанию <data>/"+configs.VAPIDKeyFile+")")
+	mediaDir := flag.String("media", "", "каталог загруженных файлов (по умолчанию <data>/"+configs.MediaDir+")")
+	flag.Parse()
+
+	if *vapidKey == "" {
+		*vapidKey = filepath.Join(*dataDir, configs.VAPIDKeyFile)
+	}
+	if *mediaDir == "" {
+		*mediaDir = filepath.Join(*dataDir, configs.MediaDir)
+	}
+
 	logger := server.SetupLogger("debug")
 
+	rules, err := achievements.LoadRules(configfiles.FS(*dev, configs.ConfigsDir), configs.AchievementRulesFile)
+	if err != nil && *dev {
+		// Без файла на диске работаем со встроенными правилами
+		logger.Warn("achievement rules not found on disk, using embedded", helpers.SlogErr(err))
+		rules, err = achievements.LoadRules(configfiles.FS(false, ""), configs.AchievementRulesFile)
+	}
+	if err != nil {
+		log.Fatalf("Ошибка в правилах достижений %s", err.Error())
+	}
+
 	h := handlers.New(nil, logger)
+	h.Dev = *dev
+	h.Achievements = achievements.New(rules)
+	h.Assets = static.FS(*dev, configs.StaticDir)
+	h.Templates, err = render.New(h.Assets, *dev, h.I18n)
+	if err != nil {
+		log.Fatalf("Ошибка при разборе шаблонов %s", err.Error())
+	}
+
+	for _, dir := range []string{*dataDir, filepath.Dir(*vapidKey)} {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			log.Fatalf("Ошибка при подготовке каталога данных %s", err.Error())
+		}
+	}
+
+	// Старые события журнала аудита дописываются в файл, а не теряются
+	auditFile, err := os.OpenFile(filepath.Join(*dataDir, configs.AuditArchiveFile), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		log.Fatalf("Ошибка при открытии архива аудита %s", err.Error())
+	}
+	defer auditFile.Close()
+	h.Storage.SetAuditArchive(func(events []models.AuditEvent) {
+		if err := audit.WriteJSONLines(auditFile, events); err != nil {
+			logger.Error("audit archive", helpers.SlogErr(err))
+		}
+	})
+	vapid, err := webpush.LoadVAPID(*vapidKey, configs.VAPIDSubject)
+	if err != nil {
+		log.Fatalf("Ошибка при загрузке ключа VAPID %s", err.Error())
+	}
+	h.VAPID = vapid
+
+	mediaStore, err := media.NewDisk(*mediaDir)
+	if err != nil {
+		log.Fatalf("Ошибка при подготовке хранилища файлов %s", err.Error())
+	}
+	h.Media = mediaStore
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	scheduler := notify.NewScheduler(
+		h.Storage,
+		h.I18n,
+		h.Achievements,
+		logger,
+		configs.NotifyInterval,
+		notify.NewInApp(h.Inbox),
+		notify.NewEmail(configs.SMTPAddr, configs.SMTPFrom),
+		notify.NewWebhook(),
+		notify.NewPush(webpush.NewClient(vapid, *dev), h.Storage),
+	)
+	go scheduler.Run(ctx)
 
 	mux := server.SetupRoutes(h)
 
 	srv := server.New(mux)
+	// Запросы получают контекст приложения, чтобы при остановке закрылись и долгие потоки SSE
+	srv.BaseContext = func(net.Listener) context.Context { return ctx }
+
+	go func() {
+		<-ctx.Done()
+		// Shutdown не ждёт соединения WebSocket, их закрывает сам чат
+		h.Chat.Close()
+		srv.Shutdown(context.Background())
+	}()
 
 	log.Printf("Server is listening on %s ...", srv.Addr)
-	if err := srv.ListenAndServe(); err != nil {
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatalf("Ошибка при запуске сервера %s", err.Error())
 	}
 }
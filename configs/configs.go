@@ -0,0 +1,20 @@
+// Package configs файлы настроек, встроенные в бинарник
+package configs
+
+import (
+	"embed"
+	"io/fs"
+	"os"
+)
+
+//go:embed achievements.json
+var embedded embed.FS
+
+// FS возвращает файлы настроек. В режиме разработки они читаются с диска из dir,
+// чтобы правки правил достижений были видны без пересборки
+func FS(dev bool, dir string) fs.FS {
+	if dev {
+		return os.DirFS(dir)
+	}
+	return embedded
+}
@@ -2,8 +2,6 @@ module github.com/NarthurN/QuitSmoking
 
 go 1.23.4
 
-require github.com/go-chi/chi/v5 v5.2.1
-
 require github.com/golang-jwt/jwt/v5 v5.2.1
 
 require github.com/stretchr/objx v0.5.2 // indirect
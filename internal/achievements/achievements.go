@@ -0,0 +1,147 @@
+package achievements
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/i18n"
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+// Показатели, по которым можно задавать правила
+const (
+	MetricDaysSmokeFree    = "days_smoke_free"
+	MetricMoneySaved       = "money_saved"
+	MetricCravingsResisted = "cravings_resisted"
+	MetricJournalStreak    = "journal_streak"
+)
+
+type Awarder interface {
+	AwardAchievement(username string, achievement *models.Achievement) bool
+}
+
+type Engine struct {
+	rules []models.AchievementRule
+}
+
+func New(rules []models.AchievementRule) *Engine {
+	return &Engine{rules: rules}
+}
+
+// LoadRules читает правила достижений из JSON-файла name в fsys и проверяет их
+func LoadRules(fsys fs.FS, name string) ([]models.AchievementRule, error) {
+	op := "achievements.LoadRules"
+
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var rules []models.AchievementRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ids := make(map[string]struct{}, len(rules))
+	for _, rule := range rules {
+		if rule.ID == "" {
+			return nil, fmt.Errorf("%s: rule without id", op)
+		}
+		if _, ok := ids[rule.ID]; ok {
+			return nil, fmt.Errorf("%s: duplicate rule id %q", op, rule.ID)
+		}
+		ids[rule.ID] = struct{}{}
+
+		if _, err := metricValue(rule.Metric, models.SmokerStats{}); err != nil {
+			return nil, fmt.Errorf("%s: rule %q: %w", op, rule.ID, err)
+		}
+	}
+
+	return rules, nil
+}
+
+// Evaluate возвращает правила, условия которых выполнены для переданных показателей
+func (e *Engine) Evaluate(stats models.SmokerStats) []models.AchievementRule {
+	var passed []models.AchievementRule
+	for _, rule := range e.rules {
+		value, err := metricValue(rule.Metric, stats)
+		if err != nil {
+			continue
+		}
+		if value >= rule.Threshold {
+			passed = append(passed, rule)
+		}
+	}
+	return passed
+}
+
+// Award выдаёт курильщику все заработанные достижения и возвращает только новые
+func (e *Engine) Award(awarder Awarder, username string, stats models.SmokerStats) []*models.Achievement {
+	now := time.Now().UTC()
+
+	var awarded []*models.Achievement
+	for _, rule := range e.Evaluate(stats) {
+		achievement := &models.Achievement{
+			RuleID:      rule.ID,
+			Title:       rule.Title,
+			Description: rule.Description,
+			AwardedAt:   now,
+		}
+		if awarder.AwardAchievement(username, achievement) {
+			awarded = append(awarded, achievement)
+		}
+	}
+	return awarded
+}
+
+// Text название и описание достижения ruleID на языке loc. Если в каталогах нет
+// перевода, например правило только что добавили в файл, остаются тексты из файла правил
+func Text(loc *i18n.Localizer, ruleID, title, description string) (string, string) {
+	key := "achievement." + ruleID
+	if loc.Has(key + ".title") {
+		title = loc.T(key + ".title")
+	}
+	if loc.Has(key + ".description") {
+		description = loc.T(key + ".description")
+	}
+	return title, description
+}
+
+// Localized возвращает копии достижений с названиями и описаниями на языке loc
+func Localized(loc *i18n.Localizer, achievements []*models.Achievement) []*models.Achievement {
+	localized := make([]*models.Achievement, 0, len(achievements))
+	for _, a := range achievements {
+		copied := *a
+		copied.Title, copied.Description = Text(loc, a.RuleID, a.Title, a.Description)
+		localized = append(localized, &copied)
+	}
+	return localized
+}
+
+func metricValue(metric string, stats models.SmokerStats) (float64, error) {
+	switch metric {
+	case MetricDaysSmokeFree:
+		return float64(stats.DaysSmokeFree), nil
+	case MetricMoneySaved:
+		return stats.MoneySaved, nil
+	case MetricCravingsResisted:
+		return float64(stats.CravingsResisted), nil
+	case MetricJournalStreak:
+		return float64(stats.JournalStreak), nil
+	default:
+		return 0, fmt.Errorf("unknown metric %q", metric)
+	}
+}
+
+// Upcoming возвращает достижения за дни без сигарет, которые курильщик получит в следующие сутки
+func (e *Engine) Upcoming(stats models.SmokerStats) []models.AchievementRule {
+	var upcoming []models.AchievementRule
+	for _, rule := range e.rules {
+		if rule.Metric == MetricDaysSmokeFree && rule.Threshold == float64(stats.DaysSmokeFree+1) {
+			upcoming = append(upcoming, rule)
+		}
+	}
+	return upcoming
+}
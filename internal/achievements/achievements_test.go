@@ -0,0 +1,79 @@
+package achievements
+
+import (
+	"testing"
+
+	configfiles "github.com/NarthurN/QuitSmoking/configs"
+	"github.com/NarthurN/QuitSmoking/internal/configs"
+	"github.com/NarthurN/QuitSmoking/internal/i18n"
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/NarthurN/QuitSmoking/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+var testRules = []models.AchievementRule{
+	{ID: "week", Title: "Неделя", Metric: MetricDaysSmokeFree, Threshold: 7},
+	{ID: "saved", Title: "Копилка", Metric: MetricMoneySaved, Threshold: 1000},
+	{ID: "resisted", Title: "Устоял", Metric: MetricCravingsResisted, Threshold: 1},
+}
+
+func TestEvaluate(t *testing.T) {
+	engine := New(testRules)
+
+	passed := engine.Evaluate(models.SmokerStats{DaysSmokeFree: 10, MoneySaved: 500})
+
+	assert.Len(t, passed, 1)
+	assert.Equal(t, "week", passed[0].ID)
+}
+
+func TestAwardIsIdempotent(t *testing.T) {
+	engine := New(testRules)
+	store := storage.New()
+	stats := models.SmokerStats{DaysSmokeFree: 10, MoneySaved: 1500}
+
+	first := engine.Award(store, "arthur", stats)
+	second := engine.Award(store, "arthur", stats)
+
+	// Повторная проверка не выдаёт достижения заново
+	assert.Len(t, first, 2)
+	assert.Empty(t, second)
+	assert.Len(t, store.GetAchievements("arthur"), 2)
+	assert.Equal(t, first[0].AwardedAt, store.GetAchievements("arthur")[0].AwardedAt)
+}
+
+func TestLoadRulesFromConfig(t *testing.T) {
+	rules, err := LoadRules(configfiles.FS(false, ""), configs.AchievementRulesFile)
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, rules)
+}
+
+func TestRulesHaveTranslations(t *testing.T) {
+	rules, err := LoadRules(configfiles.FS(false, ""), configs.AchievementRulesFile)
+	assert.NoError(t, err)
+
+	bundle := i18n.Default()
+	for _, locale := range bundle.Locales() {
+		loc := bundle.Localizer(locale)
+		for _, rule := range rules {
+			assert.True(t, loc.Has("achievement."+rule.ID+".title"), locale+" "+rule.ID)
+			assert.True(t, loc.Has("achievement."+rule.ID+".description"), locale+" "+rule.ID)
+		}
+	}
+}
+
+func TestLocalized(t *testing.T) {
+	stored := []*models.Achievement{
+		{RuleID: "first-week", Title: "Неделя", Description: "7 дней без сигарет"},
+		// Правило без перевода в каталогах остаётся с текстами из файла
+		{RuleID: "custom", Title: "Своё", Description: "Только по-русски"},
+	}
+
+	localized := Localized(i18n.Default().Localizer("en"), stored)
+
+	assert.Equal(t, "A week", localized[0].Title)
+	assert.Equal(t, "7 days without cigarettes", localized[0].Description)
+	assert.Equal(t, "Своё", localized[1].Title)
+	// Сохранённые достижения не меняются
+	assert.Equal(t, "Неделя", stored[0].Title)
+}
@@ -0,0 +1,83 @@
+// Package admin считает сводные показатели для панели администратора
+package admin
+
+import (
+	"math"
+	"slices"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+// RecentPeriod за какой срок срыв считается недавним
+const RecentPeriod = 30 * 24 * time.Hour
+
+// Sources откуда Stats берёт данные о каждом курильщике
+type Sources struct {
+	Cravings func(username string) []*models.Craving
+	Roles    func(username string) []string
+	Locked   func(username string) bool
+}
+
+// Stats сводка по курильщикам all на момент now. Серия — полные дни без сигарет,
+// срыв — эпизод тяги, после которого курильщик закурил
+func Stats(all []*models.Smoker, src Sources, now time.Time) models.AdminStats {
+	stats := models.AdminStats{
+		Smokers: len(all),
+		ByRole:  make(map[string]int),
+	}
+	if len(all) == 0 {
+		return stats
+	}
+
+	streaks := make([]int, 0, len(all))
+	total := 0
+	for _, smoker := range all {
+		streak := 0
+		if diff := now.Sub(smoker.StoppedSmoking); diff > 0 {
+			streak = int(diff.Hours() / 24)
+		}
+		streaks = append(streaks, streak)
+		total += streak
+
+		for _, role := range src.Roles(smoker.Username) {
+			stats.ByRole[role]++
+		}
+		if src.Locked(smoker.Username) {
+			stats.Locked++
+		}
+
+		recent := false
+		for _, craving := range src.Cravings(smoker.Username) {
+			stats.Cravings++
+			if craving.Resisted {
+				continue
+			}
+			stats.Relapses++
+			if now.Sub(craving.At) <= RecentPeriod {
+				recent = true
+			}
+		}
+		if recent {
+			stats.RecentlyRelapsed++
+		}
+	}
+
+	slices.Sort(streaks)
+	stats.AverageStreak = math.Round(float64(total)/float64(len(all))*10) / 10
+	stats.MedianStreak = streaks[len(streaks)/2]
+	if len(streaks)%2 == 0 {
+		stats.MedianStreak = (streaks[len(streaks)/2-1] + streaks[len(streaks)/2]) / 2
+	}
+	if stats.Cravings > 0 {
+		stats.RelapsePercent = percent(stats.Relapses, stats.Cravings)
+	}
+	stats.RecentRelapsePercent = percent(stats.RecentlyRelapsed, len(all))
+
+	return stats
+}
+
+// percent доля part от whole в процентах с одним знаком после запятой
+func percent(part, whole int) float64 {
+	return math.Round(float64(part)/float64(whole)*1000) / 10
+}
@@ -0,0 +1,67 @@
+package admin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/configs"
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStats(t *testing.T) {
+	day := 24 * time.Hour
+	now := time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+	all := []*models.Smoker{
+		{Username: "anna", StoppedSmoking: now.Add(-10 * day)},
+		{Username: "boris", StoppedSmoking: now.Add(-20 * day)},
+		{Username: "vera", StoppedSmoking: now.Add(-60 * day)},
+		// Дата отказа ещё не наступила: серия нулевая
+		{Username: "gleb", StoppedSmoking: now.Add(5 * day)},
+	}
+	cravings := map[string][]*models.Craving{
+		"anna": {
+			{At: now.Add(-2 * day), Resisted: true},
+			{At: now.Add(-3 * day), Resisted: false},
+		},
+		"boris": {
+			// Срыв давно, в недавние не попадает
+			{At: now.Add(-40 * day), Resisted: false},
+			{At: now.Add(-1 * day), Resisted: true},
+		},
+	}
+	src := Sources{
+		Cravings: func(username string) []*models.Craving { return cravings[username] },
+		Roles: func(username string) []string {
+			if username == "vera" {
+				return []string{configs.AdminRole}
+			}
+			return []string{configs.UserRole}
+		},
+		Locked: func(username string) bool { return username == "gleb" },
+	}
+
+	stats := Stats(all, src, now)
+
+	assert.Equal(t, 4, stats.Smokers)
+	assert.Equal(t, 1, stats.Locked)
+	assert.Equal(t, 22.5, stats.AverageStreak)
+	// Серии 0, 10, 20, 60: медиана между 10 и 20
+	assert.Equal(t, 15, stats.MedianStreak)
+	assert.Equal(t, 4, stats.Cravings)
+	assert.Equal(t, 2, stats.Relapses)
+	assert.Equal(t, 50.0, stats.RelapsePercent)
+	assert.Equal(t, 1, stats.RecentlyRelapsed)
+	assert.Equal(t, 25.0, stats.RecentRelapsePercent)
+	assert.Equal(t, map[string]int{configs.UserRole: 3, configs.AdminRole: 1}, stats.ByRole)
+}
+
+func TestStatsEmpty(t *testing.T) {
+	// Без курильщиков нет деления на ноль
+	stats := Stats(nil, Sources{}, time.Now())
+
+	assert.Equal(t, 0, stats.Smokers)
+	assert.Equal(t, 0.0, stats.AverageStreak)
+	assert.Equal(t, 0.0, stats.RelapsePercent)
+}
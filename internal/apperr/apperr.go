@@ -0,0 +1,283 @@
+// Package apperr ошибки приложения, которые можно показать клиенту: HTTP-статус,
+// стабильный код и аргументы сообщения. Текст сообщения берётся из каталога i18n
+// по ключу "error.<код>", а исходная ошибка только пишется в лог и наружу не попадает
+package apperr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/NarthurN/QuitSmoking/internal/i18n"
+)
+
+// Стабильные коды ошибок. Клиенты API опираются на них, поэтому коды не переименовываются
+const (
+	CodeInternal               = "internal"
+	CodeBadRequest             = "bad_request"
+	CodeNotFound               = "not_found"
+	CodeInvalidJSON            = "invalid_json"
+	CodeBodyTooLarge           = "body_too_large"
+	CodeUnsupportedMedia       = "unsupported_media_type"
+	CodeValidation             = "validation_failed"
+	CodeInvalidQuery           = "invalid_query"
+	CodeForbidden              = "forbidden"
+	CodeUnauthorized           = "unauthorized"
+	CodeTokenInvalid           = "token_invalid"
+	CodeTokenRevoked           = "token_revoked"
+	CodeMethodNotAllowed       = "method_not_allowed"
+	CodeWebSocketExpected      = "websocket_expected"
+	CodeWebSocketVersion       = "websocket_version"
+	CodePreconditionFailed     = "precondition_failed"
+	CodePreconditionRequired   = "precondition_required"
+	CodeUnknownUser            = "unknown_user"
+	CodeWrongPassword          = "wrong_password"
+	CodeSmokerExists           = "smoker_exists"
+	CodeSmokerNotFound         = "smoker_not_found"
+	CodeUnsupportedLanguage    = "unsupported_language"
+	CodeUnsupportedCurrency    = "unsupported_currency"
+	CodeInvalidPrice           = "invalid_price"
+	CodeInvalidDate            = "invalid_date"
+	CodePriceNotFound          = "price_not_found"
+	CodeReasonLength           = "reason_length"
+	CodeLetterTitleLength      = "letter_title_length"
+	CodeLetterLength           = "letter_length"
+	CodeOpenAfterDays          = "open_after_days"
+	CodeCaptionLength          = "caption_length"
+	CodePhotoRequired          = "photo_required"
+	CodeFileTooLarge           = "file_too_large"
+	CodeUnsupportedImage       = "unsupported_image"
+	CodePhotoNotFound          = "photo_not_found"
+	CodeReasonNotFound         = "reason_not_found"
+	CodeAccountLocked          = "account_locked"
+	CodeSelfAction             = "self_action"
+	CodeImpersonateAdmin       = "impersonate_admin"
+	CodeNotImpersonating       = "not_impersonating"
+	CodeTooManyTabs            = "too_many_tabs"
+	CodeServerBusy             = "server_busy"
+	CodeSelfInvite             = "self_invite"
+	CodeAlreadyBuddies         = "already_buddies"
+	CodeInviteExists           = "invite_exists"
+	CodeInviteNotFound         = "invite_not_found"
+	CodeInviteHandled          = "invite_handled"
+	CodeBuddyNotFound          = "buddy_not_found"
+	CodeMessageLength          = "message_length"
+	CodeChallengeTitle         = "challenge_title"
+	CodeStartInPast            = "start_in_past"
+	CodeEndBeforeStart         = "end_before_start"
+	CodeChallengeNotFound      = "challenge_not_found"
+	CodeChallengeStarted       = "challenge_started"
+	CodeAlreadyJoined          = "already_joined"
+	CodeChallengeFinished      = "challenge_finished"
+	CodeNotJoined              = "not_joined"
+	CodeMoodRange              = "mood_range"
+	CodeStressRange            = "stress_range"
+	CodeSleepRange             = "sleep_range"
+	CodeUnknownSymptom         = "unknown_symptom"
+	CodeBanned                 = "banned"
+	CodePostLength             = "post_length"
+	CodePostNotFound           = "post_not_found"
+	CodeReportReasonLength     = "report_reason_length"
+	CodeUnknownReaction        = "unknown_reaction"
+	CodeNotBanned              = "not_banned"
+	CodeGoalTitle              = "goal_title"
+	CodeGoalTitleLength        = "goal_title_length"
+	CodeDeadlinePast           = "deadline_past"
+	CodeUnknownGoalKind        = "unknown_goal_kind"
+	CodeGoalTarget             = "goal_target"
+	CodeGoalNotFound           = "goal_not_found"
+	CodeNotificationNotFound   = "notification_not_found"
+	CodeUnknownTimezone        = "unknown_timezone"
+	CodeHourRange              = "hour_range"
+	CodeUnknownChannel         = "unknown_channel"
+	CodeInvalidEmail           = "invalid_email"
+	CodeInvalidWebhook         = "invalid_webhook"
+	CodeWebhookAddress         = "webhook_address"
+	CodeUnknownNRTKind         = "unknown_nrt_kind"
+	CodeNRTDose                = "nrt_dose"
+	CodeNRTStepDays            = "nrt_step_days"
+	CodeNRTNotFound            = "nrt_not_found"
+	CodeNRTQuantity            = "nrt_quantity"
+	CodeNRTVolume              = "nrt_volume"
+	CodeNRTFinished            = "nrt_finished"
+	CodePushUnavailable        = "push_unavailable"
+	CodeInvalidSubscription    = "invalid_subscription"
+	CodeSubscriptionNotFound   = "subscription_not_found"
+	CodeQuitDateFuture         = "quit_date_future"
+	CodeStartAllowance         = "start_allowance"
+	CodeStartAllowanceRequired = "start_allowance_required"
+	CodeUnknownTaper           = "unknown_taper"
+	CodePlanNotFound           = "plan_not_found"
+	CodePlanInactive           = "plan_inactive"
+	CodeCigarettesCount        = "cigarettes_count"
+	CodeSessionNotFound        = "session_not_found"
+	CodeUnknownOutcome         = "unknown_outcome"
+	CodeSessionFinished        = "session_finished"
+)
+
+// Codes все коды ошибок. У каждого должно быть сообщение в каталогах
+func Codes() []string {
+	return []string{
+		CodeInternal, CodeBadRequest, CodeNotFound, CodeInvalidJSON,
+		CodeBodyTooLarge, CodeUnsupportedMedia, CodeValidation, CodeInvalidQuery,
+		CodeForbidden, CodeUnauthorized, CodeTokenInvalid, CodeTokenRevoked, CodeMethodNotAllowed,
+		CodeWebSocketExpected, CodeWebSocketVersion, CodePreconditionFailed, CodePreconditionRequired,
+		CodeUnknownUser, CodeWrongPassword, CodeSmokerExists, CodeSmokerNotFound,
+		CodeUnsupportedLanguage, CodeUnsupportedCurrency,
+		CodeInvalidPrice, CodeInvalidDate, CodePriceNotFound,
+		CodeReasonLength, CodeLetterTitleLength, CodeLetterLength, CodeOpenAfterDays,
+		CodeCaptionLength, CodePhotoRequired, CodeFileTooLarge, CodeUnsupportedImage,
+		CodePhotoNotFound, CodeReasonNotFound,
+		CodeAccountLocked, CodeSelfAction, CodeImpersonateAdmin, CodeNotImpersonating,
+		CodeTooManyTabs, CodeServerBusy, CodeSelfInvite, CodeAlreadyBuddies, CodeInviteExists,
+		CodeInviteNotFound, CodeInviteHandled, CodeBuddyNotFound, CodeMessageLength,
+		CodeChallengeTitle, CodeStartInPast, CodeEndBeforeStart, CodeChallengeNotFound,
+		CodeChallengeStarted, CodeAlreadyJoined, CodeChallengeFinished, CodeNotJoined,
+		CodeMoodRange, CodeStressRange, CodeSleepRange, CodeUnknownSymptom, CodeBanned,
+		CodePostLength, CodePostNotFound, CodeReportReasonLength, CodeUnknownReaction, CodeNotBanned, CodeGoalTitle,
+		CodeGoalTitleLength, CodeDeadlinePast, CodeUnknownGoalKind, CodeGoalTarget, CodeGoalNotFound, CodeNotificationNotFound,
+		CodeUnknownTimezone, CodeHourRange, CodeUnknownChannel, CodeInvalidEmail,
+		CodeInvalidWebhook, CodeWebhookAddress, CodeUnknownNRTKind, CodeNRTDose, CodeNRTStepDays, CodeNRTNotFound,
+		CodeNRTQuantity, CodeNRTVolume, CodeNRTFinished, CodePushUnavailable, CodeInvalidSubscription,
+		CodeSubscriptionNotFound, CodeQuitDateFuture, CodeStartAllowance,
+		CodeStartAllowanceRequired, CodeUnknownTaper, CodePlanNotFound, CodePlanInactive,
+		CodeCigarettesCount, CodeSessionNotFound, CodeUnknownOutcome, CodeSessionFinished,
+	}
+}
+
+// Error ошибка с HTTP-статусом и кодом для клиента
+type Error struct {
+	Status int
+	Code   string
+	// Args подставляются в сообщение из каталога, например наибольшая длина текста
+	Args []any
+	// Fields ошибки в отдельных полях запроса
+	Fields []FieldError
+	// Err исходная ошибка для лога
+	Err error
+}
+
+// FieldError ошибка в поле запроса. Сообщение берётся из каталога по ключу "validation.<код>"
+type FieldError struct {
+	Field string
+	Code  string
+	Args  []any
+}
+
+// New создаёт ошибку со статусом и кодом
+func New(status int, code string, args ...any) *Error {
+	return &Error{Status: status, Code: code, Args: args}
+}
+
+// BadRequest ошибка в данных запроса
+func BadRequest(code string, args ...any) *Error {
+	return New(http.StatusBadRequest, code, args...)
+}
+
+// NotFound запрошенного объекта нет
+func NotFound(code string, args ...any) *Error {
+	return New(http.StatusNotFound, code, args...)
+}
+
+// Internal ошибка сервера. Клиент увидит только общий текст, причина останется в логе
+func Internal(err error) *Error {
+	return &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Err: err}
+}
+
+// WithFields возвращает копию ошибки с ошибками в полях
+func (e *Error) WithFields(fields ...FieldError) *Error {
+	withFields := *e
+	withFields.Fields = fields
+	return &withFields
+}
+
+// Wrap возвращает копию ошибки с исходной причиной
+func (e *Error) Wrap(err error) *Error {
+	wrapped := *e
+	wrapped.Err = err
+	return &wrapped
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%d %s: %v", e.Status, e.Code, e.Err)
+	}
+	return fmt.Sprintf("%d %s", e.Status, e.Code)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// From достаёт *Error из цепочки ошибок. Любая другая ошибка считается ошибкой сервера
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Internal(err)
+}
+
+// Message текст ошибки на языке переводчика
+func (e *Error) Message(loc *i18n.Localizer) string {
+	return loc.T("error."+e.Code, e.Args...)
+}
+
+// ProblemContentType тип ответа с ошибкой по RFC 9457
+const ProblemContentType = "application/problem+json"
+
+// Problem тело ответа с ошибкой по RFC 9457. Code дополняет стандартные поля
+// стабильным кодом, по которому клиент может отличать ошибки друг от друга
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code"`
+	// Errors ошибки в отдельных полях, чтобы показать их рядом с полями формы
+	Errors []ProblemField `json:"errors,omitempty"`
+}
+
+// ProblemField ошибка в одном поле запроса
+type ProblemField struct {
+	Field  string `json:"field"`
+	Code   string `json:"code"`
+	Detail string `json:"detail"`
+}
+
+// Problem описание ошибки для ответа на запрос к instance
+func (e *Error) Problem(loc *i18n.Localizer, instance string) Problem {
+	problem := Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(e.Status),
+		Status:   e.Status,
+		Detail:   e.Message(loc),
+		Instance: instance,
+		Code:     e.Code,
+	}
+	for _, field := range e.Fields {
+		problem.Errors = append(problem.Errors, ProblemField{
+			Field:  field.Field,
+			Code:   field.Code,
+			Detail: loc.T("validation."+field.Code, field.Args...),
+		})
+	}
+	return problem
+}
+
+// WriteProblem отвечает ошибкой в формате application/problem+json
+func WriteProblem(w http.ResponseWriter, loc *i18n.Localizer, p Problem) {
+	body, err := json.Marshal(p)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType+";charset=utf-8")
+	w.Header().Set("Content-Language", loc.Locale())
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	w.Write(body)
+}
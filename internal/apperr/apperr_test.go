@@ -0,0 +1,71 @@
+package apperr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NarthurN/QuitSmoking/internal/i18n"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodesHaveMessages(t *testing.T) {
+	// Каталоги сверяются между собой в i18n, здесь достаточно каталога по умолчанию
+	loc := i18n.Default().Localizer(i18n.DefaultLocale)
+	for _, code := range Codes() {
+		assert.NotEqual(t, "error."+code, loc.T("error."+code), code)
+	}
+}
+
+func TestFrom(t *testing.T) {
+	notFound := NotFound(CodeReasonNotFound)
+	assert.Same(t, notFound, From(fmt.Errorf("handlers.X: %w", notFound)))
+
+	cause := errors.New("disk is full")
+	internal := From(cause)
+	assert.Equal(t, http.StatusInternalServerError, internal.Status)
+	assert.Equal(t, CodeInternal, internal.Code)
+	assert.ErrorIs(t, internal, cause)
+}
+
+func TestWrapKeepsOriginal(t *testing.T) {
+	base := BadRequest(CodeInvalidJSON)
+	cause := errors.New("unexpected EOF")
+	wrapped := base.Wrap(cause)
+
+	assert.Nil(t, base.Err)
+	assert.ErrorIs(t, wrapped, cause)
+	assert.Equal(t, base.Code, wrapped.Code)
+}
+
+func TestWriteProblem(t *testing.T) {
+	loc := i18n.Default().Localizer("en")
+	w := httptest.NewRecorder()
+	WriteProblem(w, loc, BadRequest(CodeReasonLength, 500).Problem(loc, "/reasons"))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "application/problem+json;charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "en", w.Header().Get("Content-Language"))
+
+	var problem Problem
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(t, Problem{
+		Type:     "about:blank",
+		Title:    "Bad Request",
+		Status:   http.StatusBadRequest,
+		Detail:   "A reason must be 1 to 500 characters long",
+		Instance: "/reasons",
+		Code:     CodeReasonLength,
+	}, problem)
+}
+
+func TestInternalHidesCause(t *testing.T) {
+	loc := i18n.Default().Localizer("ru")
+	problem := Internal(errors.New("password=secret")).Problem(loc, "/")
+
+	assert.NotContains(t, problem.Detail, "secret")
+	assert.Equal(t, CodeInternal, problem.Code)
+}
@@ -0,0 +1,109 @@
+// Package audit журнал действий, важных для безопасности: входы, выходы, изменения
+// ролей и учётных записей. Журнал только пополняется: записи не меняются и не удаляются
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+// Recorder дописывает событие в журнал
+type Recorder interface {
+	AddAuditEvent(event *models.AuditEvent)
+}
+
+// ContentType тип выгрузки журнала: по одному JSON-объекту на строку
+const ContentType = "application/x-ndjson"
+
+// maxFieldLength ограничивает поля, которые приходят от клиента: username при неудачном
+// входе и User-Agent, чтобы журнал нельзя было раздуть одним запросом
+const maxFieldLength = 256
+
+// Event событие action исполнителя actor над target. Время, адрес клиента, User-Agent,
+// id запроса и администратора, который вошёл от чужого имени, берёт из запроса
+func Event(r *http.Request, actor, action, target string) *models.AuditEvent {
+	event := &models.AuditEvent{
+		At:        time.Now().UTC(),
+		Actor:     actor,
+		Action:    action,
+		Target:    truncate(target),
+		IP:        ClientIP(r),
+		UserAgent: truncate(r.UserAgent()),
+	}
+	event.RequestID, _ = r.Context().Value(models.ContextString("request.id")).(string)
+	event.Impersonator, _ = r.Context().Value(models.ContextString("smoker.impersonator")).(string)
+	return event
+}
+
+// ClientIP адрес клиента. Приложение работает без обратного прокси, поэтому
+// X-Forwarded-For не учитывается: его может подставить сам клиент
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// truncate обрезает строку до maxFieldLength символов
+func truncate(s string) string {
+	if utf8.RuneCountInString(s) <= maxFieldLength {
+		return s
+	}
+	return string([]rune(s)[:maxFieldLength])
+}
+
+// Filter условия отбора событий. Пустые поля не ограничивают выборку
+type Filter struct {
+	Actor     string
+	Target    string
+	Action    string
+	RequestID string
+	// From и To границы времени события, To не включается
+	From time.Time
+	To   time.Time
+}
+
+// Match подходит ли событие под фильтр. Actor совпадает и с администратором,
+// который действовал от чужого имени
+func (f Filter) Match(event models.AuditEvent) bool {
+	switch {
+	case f.Actor != "" && f.Actor != event.Actor && f.Actor != event.Impersonator:
+		return false
+	case f.Target != "" && f.Target != event.Target:
+		return false
+	case f.Action != "" && !matchAction(f.Action, event.Action):
+		return false
+	case f.RequestID != "" && f.RequestID != event.RequestID:
+		return false
+	case !f.From.IsZero() && event.At.Before(f.From):
+		return false
+	case !f.To.IsZero() && !event.At.Before(f.To):
+		return false
+	}
+	return true
+}
+
+// matchAction действие совпадает целиком или по группе: "signin" подходит и к
+// "signin.success", и к "signin.failure"
+func matchAction(want, action string) bool {
+	return want == action || strings.HasPrefix(action, want+".")
+}
+
+// WriteJSONLines пишет события по одному JSON-объекту на строку
+func WriteJSONLines(w io.Writer, events []models.AuditEvent) error {
+	enc := json.NewEncoder(w)
+	for _, event := range events {
+		if err := enc.Encode(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
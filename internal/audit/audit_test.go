@@ -0,0 +1,127 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvent(t *testing.T) {
+	r := httptest.NewRequest("POST", "/signin", nil)
+	r.RemoteAddr = "203.0.113.7:51234"
+	r.Header.Set("User-Agent", "Firefox")
+	// Клиент не может подменить свой адрес заголовком
+	r.Header.Set("X-Forwarded-For", "10.0.0.1")
+	ctx := context.WithValue(r.Context(), models.ContextString("request.id"), "req-1")
+	ctx = context.WithValue(ctx, models.ContextString("smoker.impersonator"), "arthurCool")
+
+	event := Event(r.WithContext(ctx), "olga", models.AuditSmokerUpdate, "olga")
+
+	assert.Equal(t, "olga", event.Actor)
+	assert.Equal(t, models.AuditSmokerUpdate, event.Action)
+	assert.Equal(t, "203.0.113.7", event.IP)
+	assert.Equal(t, "Firefox", event.UserAgent)
+	assert.Equal(t, "req-1", event.RequestID)
+	assert.Equal(t, "arthurCool", event.Impersonator)
+	assert.False(t, event.At.IsZero())
+
+	// Длинные значения от клиента обрезаются
+	r = httptest.NewRequest("POST", "/signin", nil)
+	r.Header.Set("User-Agent", strings.Repeat("ю", 1000))
+	event = Event(r, "", models.AuditSigninFailure, strings.Repeat("a", 1000))
+	assert.Len(t, []rune(event.UserAgent), maxFieldLength)
+	assert.Len(t, event.Target, maxFieldLength)
+}
+
+func TestFilterMatch(t *testing.T) {
+	at := time.Date(2025, time.March, 1, 12, 0, 0, 0, time.UTC)
+	event := models.AuditEvent{
+		At:           at,
+		Actor:        "olga",
+		Impersonator: "arthurCool",
+		Action:       models.AuditSigninFailure,
+		Target:       "olga",
+		RequestID:    "req-1",
+	}
+
+	assert.True(t, Filter{}.Match(event))
+	assert.True(t, Filter{Actor: "olga"}.Match(event))
+	// Администратор, вошедший от чужого имени, тоже считается исполнителем
+	assert.True(t, Filter{Actor: "arthurCool"}.Match(event))
+	assert.False(t, Filter{Actor: "victorCool"}.Match(event))
+	assert.True(t, Filter{Target: "olga"}.Match(event))
+	assert.False(t, Filter{Target: "victorCool"}.Match(event))
+
+	// Действие целиком или группой
+	assert.True(t, Filter{Action: models.AuditSigninFailure}.Match(event))
+	assert.True(t, Filter{Action: "signin"}.Match(event))
+	assert.False(t, Filter{Action: "sign"}.Match(event))
+	assert.False(t, Filter{Action: models.AuditLogout}.Match(event))
+
+	assert.True(t, Filter{RequestID: "req-1"}.Match(event))
+	assert.False(t, Filter{RequestID: "req-2"}.Match(event))
+
+	// From включается, To нет
+	assert.True(t, Filter{From: at, To: at.Add(time.Second)}.Match(event))
+	assert.False(t, Filter{From: at.Add(time.Second)}.Match(event))
+	assert.False(t, Filter{To: at}.Match(event))
+}
+
+func TestWriteJSONLines(t *testing.T) {
+	events := []models.AuditEvent{
+		{ID: "2", Action: models.AuditLogout, Actor: "olga"},
+		{ID: "1", Action: models.AuditSigninSuccess, Actor: "olga"},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteJSONLines(&buf, events))
+
+	// Каждая строка — отдельный JSON-объект
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if assert.Len(t, lines, 2) {
+		var got models.AuditEvent
+		assert.NoError(t, json.Unmarshal([]byte(lines[1]), &got))
+		assert.Equal(t, events[1], got)
+	}
+}
+
+// recorded события, дошедшие до журнала
+type recorded []models.AuditEvent
+
+func (r *recorded) AddAuditEvent(event *models.AuditEvent) {
+	*r = append(*r, *event)
+}
+
+func TestThrottle(t *testing.T) {
+	var log recorded
+	throttle := NewThrottle(&log, 2, time.Minute)
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	failure := func(ip string, at time.Time) {
+		throttle.AddAuditEvent(&models.AuditEvent{At: at, Action: models.AuditSigninFailure, IP: ip})
+	}
+
+	for i := range 5 {
+		failure("203.0.113.7", start.Add(time.Duration(i)*time.Second))
+	}
+	// Другой адрес считается отдельно, а события курильщиков не ограничиваются
+	failure("198.51.100.1", start)
+	for range 3 {
+		throttle.AddAuditEvent(&models.AuditEvent{At: start, Actor: "olga", Action: models.AuditAccessDenied, IP: "203.0.113.7"})
+	}
+	assert.Len(t, log, 6)
+
+	// В следующем окне пропущенные события учтены в первом записанном
+	failure("203.0.113.7", start.Add(time.Minute))
+	last := log[len(log)-1]
+	assert.Equal(t, "203.0.113.7", last.IP)
+	assert.Equal(t, 3, last.Suppressed)
+	failure("203.0.113.7", start.Add(time.Minute+time.Second))
+	assert.Zero(t, log[len(log)-1].Suppressed)
+}
@@ -0,0 +1,81 @@
+package audit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+// maxTrackedIPs сколько адресов Throttle помнит. Когда их больше, окна,
+// которые уже закончились, забываются вместе с несохранёнными счётчиками
+const maxTrackedIPs = 10_000
+
+// Throttle защищает журнал от событий без исполнителя: неудачных входов и недействительных
+// токенов, которые может слать кто угодно. С одного адреса за window записывается не больше
+// limit таких событий. Остальные только считаются, и их число попадает в поле Suppressed
+// следующего записанного события с этого адреса. События курильщиков пишутся всегда
+type Throttle struct {
+	next   Recorder
+	limit  int
+	window time.Duration
+
+	mu   sync.Mutex
+	byIP map[string]*ipWindow
+}
+
+type ipWindow struct {
+	start      time.Time
+	count      int
+	suppressed int
+}
+
+func NewThrottle(next Recorder, limit int, window time.Duration) *Throttle {
+	return &Throttle{
+		next:   next,
+		limit:  limit,
+		window: window,
+		byIP:   make(map[string]*ipWindow),
+	}
+}
+
+func (t *Throttle) AddAuditEvent(event *models.AuditEvent) {
+	if event.Actor != "" {
+		t.next.AddAuditEvent(event)
+		return
+	}
+
+	t.mu.Lock()
+	w, ok := t.byIP[event.IP]
+	if !ok || event.At.Sub(w.start) >= t.window {
+		if !ok && len(t.byIP) >= maxTrackedIPs {
+			t.prune(event.At)
+		}
+		next := &ipWindow{start: event.At}
+		if ok {
+			next.suppressed = w.suppressed
+		}
+		w = next
+		t.byIP[event.IP] = w
+	}
+	if w.count >= t.limit {
+		w.suppressed++
+		t.mu.Unlock()
+		return
+	}
+	w.count++
+	event.Suppressed = w.suppressed
+	w.suppressed = 0
+	t.mu.Unlock()
+
+	t.next.AddAuditEvent(event)
+}
+
+// prune забывает адреса, окна которых закончились. Вызывается под t.mu
+func (t *Throttle) prune(now time.Time) {
+	for ip, w := range t.byIP {
+		if now.Sub(w.start) >= t.window {
+			delete(t.byIP, ip)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package buddies
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/NarthurN/QuitSmoking/internal/helpers"
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+// Сколько последних эпизодов тяги показывать напарнику
+const recentCravings = 5
+
+// Максимальная длина сообщения поддержки
+const MaxMessageLength = 500
+
+// NewInviteToken создаёт случайный токен приглашения
+func NewInviteToken() (string, error) {
+	op := "buddies.NewInviteToken"
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// View собирает данные напарника с учётом его настроек приватности
+func View(
+	viewer string,
+	buddy *models.Smoker,
+	buddyship *models.Buddyship,
+	achievements []*models.Achievement,
+	cravings []*models.Craving,
+) models.BuddyView {
+	view := models.BuddyView{
+		Username:  buddy.Username,
+		Name:      buddy.Name,
+		Since:     buddyship.Since,
+		MySharing: buddyship.Sharing[viewer],
+	}
+
+	sharing := buddyship.Sharing[buddy.Username]
+	if sharing.Streak {
+		days := helpers.GetSmokeFreeDays(buddy)
+		view.DaysSmokeFree = &days
+	}
+	if sharing.Milestones {
+		view.Milestones = achievements
+	}
+	if sharing.Cravings {
+		if len(cravings) > recentCravings {
+			cravings = cravings[len(cravings)-recentCravings:]
+		}
+		view.RecentCravings = cravings
+	}
+
+	return view
+}
@@ -0,0 +1,38 @@
+package buddies
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestViewRespectsSharing(t *testing.T) {
+	now := time.Now().UTC()
+	buddy := &models.Smoker{Username: "victor", Name: "Victor", StoppedSmoking: now.Add(-72 * time.Hour)}
+	buddyship := &models.Buddyship{
+		Users: [2]string{"arthur", "victor"},
+		Sharing: map[string]models.SharingSettings{
+			"arthur": {Streak: true, Milestones: true, Cravings: true},
+			"victor": {Streak: true},
+		},
+	}
+	achievements := []*models.Achievement{{RuleID: "first-day"}}
+	cravings := make([]*models.Craving, 7)
+
+	view := View("arthur", buddy, buddyship, achievements, cravings)
+
+	if assert.NotNil(t, view.DaysSmokeFree) {
+		assert.Equal(t, 3, *view.DaysSmokeFree)
+	}
+	assert.Nil(t, view.Milestones)
+	assert.Nil(t, view.RecentCravings)
+	assert.True(t, view.MySharing.Cravings)
+
+	buddyship.Sharing["victor"] = models.SharingSettings{Cravings: true}
+	view = View("arthur", buddy, buddyship, achievements, cravings)
+
+	assert.Nil(t, view.DaysSmokeFree)
+	assert.Len(t, view.RecentCravings, recentCravings)
+}
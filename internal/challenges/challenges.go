@@ -0,0 +1,93 @@
+package challenges
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/helpers"
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+// Status возвращает состояние челленджа в момент now
+func Status(challenge *models.Challenge, now time.Time) string {
+	switch {
+	case now.Before(challenge.StartDate):
+		return models.ChallengeUpcoming
+	case now.Before(challenge.EndDate):
+		return models.ChallengeActive
+	default:
+		return models.ChallengeFinished
+	}
+}
+
+// Leaderboard строит таблицу лидеров на момент now (но не позже окончания челленджа).
+// Учитывается только время без сигарет внутри челленджа: от более поздней из дат
+// начала челленджа и отказа курильщика до now. Экономия считается по истории цен
+// пачки из prices. Имя анонимного участника остаётся пустым: его подписывают
+// по номеру вступления на языке того, кто смотрит таблицу
+func Leaderboard(
+	challenge *models.Challenge,
+	members []*models.ChallengeMember,
+	smokers map[string]*models.Smoker,
+	prices map[string][]models.PriceChange,
+	rankBy string,
+	now time.Time,
+) []models.LeaderboardEntry {
+	if now.After(challenge.EndDate) {
+		now = challenge.EndDate
+	}
+
+	entries := make([]models.LeaderboardEntry, 0, len(members))
+	for _, member := range members {
+		smoker, ok := smokers[member.Username]
+		if !ok {
+			continue
+		}
+
+		from := challenge.StartDate
+		if smoker.StoppedSmoking.After(from) {
+			from = smoker.StoppedSmoking
+		}
+		days := math.Max(0, now.Sub(from).Hours()/24)
+		saved := helpers.GetSavedBetween(smoker, prices[member.Username], from, now)
+
+		displayName := smoker.Name
+		if member.Anonymous {
+			displayName = ""
+		}
+
+		entries = append(entries, models.LeaderboardEntry{
+			Username:      member.Username,
+			DisplayName:   displayName,
+			Anonymous:     member.Anonymous,
+			Number:        member.Number,
+			SmokeFreeDays: math.Round(days*10) / 10,
+			MoneySaved:    saved,
+		})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if rankBy == models.RankByMoneySaved {
+			return entries[i].MoneySaved > entries[j].MoneySaved
+		}
+		return entries[i].SmokeFreeDays > entries[j].SmokeFreeDays
+	})
+
+	// Одинаковый результат — одинаковое место
+	for i := range entries {
+		entries[i].Rank = i + 1
+		if i > 0 && score(entries[i], rankBy) == score(entries[i-1], rankBy) {
+			entries[i].Rank = entries[i-1].Rank
+		}
+	}
+
+	return entries
+}
+
+func score(entry models.LeaderboardEntry, rankBy string) float64 {
+	if rankBy == models.RankByMoneySaved {
+		return entry.MoneySaved
+	}
+	return entry.SmokeFreeDays
+}
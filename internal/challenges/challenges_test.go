@@ -0,0 +1,48 @@
+package challenges
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatus(t *testing.T) {
+	start := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	challenge := &models.Challenge{StartDate: start, EndDate: start.Add(30 * 24 * time.Hour)}
+
+	assert.Equal(t, models.ChallengeUpcoming, Status(challenge, start.Add(-time.Hour)))
+	assert.Equal(t, models.ChallengeActive, Status(challenge, start))
+	assert.Equal(t, models.ChallengeFinished, Status(challenge, challenge.EndDate))
+}
+
+func TestLeaderboard(t *testing.T) {
+	start := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	challenge := &models.Challenge{StartDate: start, EndDate: start.Add(30 * day)}
+	smokers := map[string]*models.Smoker{
+		"arthur": {Name: "Arthur", StoppedSmoking: start.Add(-100 * day), CigarettesPerDay: 20, PackSize: 20, PackPrice: 100},
+		"victor": {Name: "Victor", StoppedSmoking: start.Add(5 * day), CigarettesPerDay: 40, PackSize: 20, PackPrice: 100},
+	}
+	members := []*models.ChallengeMember{
+		{Username: "arthur"},
+		{Username: "victor", Anonymous: true, Number: 5},
+	}
+
+	// После окончания челленджа результат фиксируется на дате окончания
+	bySmokeFree := Leaderboard(challenge, members, smokers, nil, models.RankBySmokeFree, start.Add(60*day))
+
+	assert.Equal(t, "Arthur", bySmokeFree[0].DisplayName)
+	assert.Equal(t, 30.0, bySmokeFree[0].SmokeFreeDays)
+	// Имя анонима подставляется на языке зрителя, а номер берётся из вступления
+	assert.Empty(t, bySmokeFree[1].DisplayName)
+	assert.Equal(t, 5, bySmokeFree[1].Number)
+	assert.Equal(t, 25.0, bySmokeFree[1].SmokeFreeDays)
+
+	byMoney := Leaderboard(challenge, members, smokers, nil, models.RankByMoneySaved, start.Add(60*day))
+
+	assert.Equal(t, "victor", byMoney[0].Username)
+	assert.Equal(t, 5000.0, byMoney[0].MoneySaved)
+	assert.Equal(t, 2, byMoney[1].Rank)
+}
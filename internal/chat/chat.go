@@ -0,0 +1,589 @@
+// Package chat чат в реальном времени между напарниками и участниками челленджей
+// поверх WebSocket. Одно соединение может быть в нескольких комнатах:
+// buddy:<a>:<b> для пары напарников и challenge:<id> для челленджа
+package chat
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/NarthurN/QuitSmoking/internal/helpers"
+	"github.com/NarthurN/QuitSmoking/internal/i18n"
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/NarthurN/QuitSmoking/internal/ws"
+)
+
+const (
+	// Длина одного сообщения в символах
+	MaxMessageLength = 1000
+	// Сколько сообщений истории отдавать за раз по умолчанию и максимум
+	DefaultHistoryLimit = 50
+	MaxHistoryLimit     = 100
+
+	// Очередь исходящих сообщений клиента. Клиент, который не успевает её разбирать,
+	// отключается, чтобы не копить память и не тормозить остальных
+	sendBuffer = 64
+	writeWait  = 10 * time.Second
+	pingPeriod = 30 * time.Second
+	pongWait   = 2 * pingPeriod
+	// Как часто перепроверять сессию, если клиент молчит
+	sessionCheck = 10 * time.Second
+)
+
+var ErrAccessDenied = errors.New("access denied")
+
+// ErrReconnect сессию можно продолжить с новым токеном: соединение закрывается кодом 1001,
+// и клиент переподключается. Любая другая ошибка сессии закрывает его кодом 1008
+var ErrReconnect = errors.New("reconnect required")
+
+// Session проверяет, что соединение всё ещё можно обслуживать: курильщика не заблокировали,
+// его токен не отозван и не истёк
+type Session func(now time.Time) error
+
+type Store interface {
+	GetSmoker(username string) (*models.Smoker, bool)
+	GetBuddyship(a, b string) (*models.Buddyship, bool)
+	GetChallenge(id string) (*models.Challenge, bool)
+	GetChallengeMembers(id string) []*models.ChallengeMember
+	AddChatMessage(message *models.ChatMessage)
+	GetChatHistory(room, before string, limit int) ([]models.ChatMessage, bool)
+}
+
+// Hub хранит подключённых клиентов по комнатам и рассылает им сообщения
+type Hub struct {
+	store  Store
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	rooms   map[string]map[*client]struct{}
+	clients map[*client]struct{}
+}
+
+func New(store Store, logger *slog.Logger) *Hub {
+	return &Hub{
+		store:   store,
+		logger:  logger,
+		rooms:   make(map[string]map[*client]struct{}),
+		clients: make(map[*client]struct{}),
+	}
+}
+
+type client struct {
+	username string
+	session  Session
+	// loc переводчик на язык, выбранный при подключении: ошибки и подписи участников
+	loc    *i18n.Localizer
+	conn   *ws.Conn
+	send   chan []byte
+	rooms  map[string]struct{}
+	closed bool
+	// Код, с которым закрыть соединение, когда очередь будет отправлена
+	closeCode   int
+	closeReason string
+}
+
+// inbound сообщение от клиента
+type inbound struct {
+	Type   string `json:"type"`
+	Room   string `json:"room"`
+	Text   string `json:"text"`
+	Before string `json:"before"`
+	Limit  int    `json:"limit"`
+}
+
+// outbound сообщение клиенту
+type outbound struct {
+	Type string `json:"type"`
+	Room string `json:"room,omitempty"`
+	// Alias имя комнаты, под которым клиент в неё входил, например buddy:<username напарника>
+	Alias    string        `json:"alias,omitempty"`
+	Message  *messageView  `json:"message,omitempty"`
+	Messages []messageView `json:"messages,omitempty"`
+	HasMore  bool          `json:"hasMore,omitempty"`
+	Online   []string      `json:"online,omitempty"`
+	// Code стабильный код ошибки, Error — её текст на языке клиента
+	Code  string `json:"code,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// messageView сообщение так, как его видит получатель: вместо username — отображаемое имя
+type messageView struct {
+	ID     string    `json:"id"`
+	From   string    `json:"from"`
+	Mine   bool      `json:"mine"`
+	Text   string    `json:"text"`
+	SentAt time.Time `json:"sentAt"`
+}
+
+// Serve обслуживает соединение курильщика до его закрытия. loc — язык ошибок и подписей.
+// session перепроверяется перед каждым сообщением клиента и раз в sessionCheck
+func (h *Hub) Serve(conn *ws.Conn, username string, loc *i18n.Localizer, session Session) {
+	conn.ReadTimeout = pongWait
+	c := &client{
+		username: username,
+		session:  session,
+		loc:      loc,
+		conn:     conn,
+		send:     make(chan []byte, sendBuffer),
+		rooms:    make(map[string]struct{}),
+	}
+
+	h.mu.Lock()
+	h.clients[c] = struct{}{}
+	h.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		h.writePump(c)
+		close(done)
+	}()
+
+	h.readPump(c)
+	h.disconnect(c, ws.CloseNormal, "")
+	<-done
+	conn.Close()
+}
+
+// Close отключает всех клиентов при остановке сервера
+func (h *Hub) Close() {
+	h.mu.Lock()
+	clients := make([]*client, 0, len(h.clients))
+	for c := range h.clients {
+		clients = append(clients, c)
+	}
+	h.mu.Unlock()
+
+	for _, c := range clients {
+		h.disconnect(c, ws.CloseGoingAway, "server shutdown")
+	}
+}
+
+func (h *Hub) readPump(c *client) {
+	for {
+		op, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if op != ws.OpText {
+			h.disconnect(c, ws.CloseUnsupportedData, "text messages only")
+			return
+		}
+		if !h.authorized(c) {
+			return
+		}
+
+		var msg inbound
+		if err := json.Unmarshal(data, &msg); err != nil {
+			h.replyError(c, "", "invalid_message")
+			continue
+		}
+
+		switch msg.Type {
+		case "join":
+			h.join(c, msg.Room)
+		case "leave":
+			h.leave(c, msg.Room)
+		case "message":
+			h.message(c, msg.Room, msg.Text)
+		case "history":
+			h.history(c, msg.Room, msg.Before, msg.Limit)
+		default:
+			h.replyError(c, msg.Room, "unknown_type")
+		}
+	}
+}
+
+func (h *Hub) writePump(c *client) {
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+	check := time.NewTicker(sessionCheck)
+	defer check.Stop()
+
+	for {
+		select {
+		case payload, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				c.conn.WriteClose(c.closeCode, c.closeReason)
+				// Закрываем сокет, чтобы readPump не ждал ответного кадра от медленного клиента
+				c.conn.Close()
+				return
+			}
+			if err := c.conn.WriteMessage(ws.OpText, payload); err != nil {
+				c.conn.Close()
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(ws.OpPing, nil); err != nil {
+				c.conn.Close()
+				return
+			}
+		case <-check.C:
+			// При отказе очередь закроется, и следующая итерация отправит кадр закрытия
+			h.authorized(c)
+		}
+	}
+}
+
+// authorized перепроверяет сессию клиента и отключает его, если она больше не действует
+func (h *Hub) authorized(c *client) bool {
+	if c.session == nil {
+		return true
+	}
+	err := c.session(time.Now())
+	if err == nil {
+		return true
+	}
+	h.logger.Debug("chat.Hub.authorized", helpers.SlogDebug(err.Error()), "username", c.username)
+	if errors.Is(err, ErrReconnect) {
+		h.disconnect(c, ws.CloseGoingAway, "session expiring")
+	} else {
+		h.disconnect(c, ws.ClosePolicyViolation, "session ended")
+	}
+	return false
+}
+
+// Resolve проверяет доступ курильщика к комнате и возвращает её каноническое имя.
+// Для напарников можно указать buddy:<username напарника>
+func (h *Hub) Resolve(username, room string) (string, error) {
+	kind, rest, _ := strings.Cut(room, ":")
+	switch kind {
+	case "buddy":
+		a, b, pair := strings.Cut(rest, ":")
+		buddy := a
+		if pair {
+			if a != username && b != username {
+				return "", ErrAccessDenied
+			}
+			buddy = b
+			if b == username {
+				buddy = a
+			}
+		}
+		if _, ok := h.store.GetBuddyship(username, buddy); !ok {
+			return "", ErrAccessDenied
+		}
+		return BuddyRoom(username, buddy), nil
+	case "challenge":
+		if _, ok := h.store.GetChallenge(rest); !ok {
+			return "", ErrAccessDenied
+		}
+		if !slices.ContainsFunc(h.store.GetChallengeMembers(rest), func(m *models.ChallengeMember) bool {
+			return m.Username == username
+		}) {
+			return "", ErrAccessDenied
+		}
+		return ChallengeRoom(rest), nil
+	}
+	return "", ErrAccessDenied
+}
+
+// Evict убирает курильщиков из комнаты, когда они потеряли к ней доступ:
+// напарники разорвали пару или участник вышел из челленджа
+func (h *Hub) Evict(room string, usernames ...string) {
+	h.mu.Lock()
+	evicted := false
+	for member := range h.rooms[room] {
+		if slices.Contains(usernames, member.username) {
+			h.evict(member, room)
+			evicted = true
+		}
+	}
+	h.mu.Unlock()
+
+	if evicted {
+		h.broadcastPresence(room)
+	}
+}
+
+func (h *Hub) join(c *client, room string) {
+	key, err := h.Resolve(c.username, room)
+	if err != nil {
+		h.replyError(c, room, "access_denied")
+		return
+	}
+
+	h.mu.Lock()
+	if h.rooms[key] == nil {
+		h.rooms[key] = make(map[*client]struct{})
+	}
+	h.rooms[key][c] = struct{}{}
+	c.rooms[key] = struct{}{}
+	h.mu.Unlock()
+
+	h.reply(c, outbound{Type: "joined", Room: key, Alias: room})
+	h.history(c, key, "", DefaultHistoryLimit)
+	h.broadcastPresence(key)
+}
+
+func (h *Hub) leave(c *client, room string) {
+	h.mu.Lock()
+	_, ok := c.rooms[room]
+	if ok {
+		h.removeFromRoom(c, room)
+	}
+	h.mu.Unlock()
+
+	if ok {
+		h.broadcastPresence(room)
+	}
+}
+
+func (h *Hub) message(c *client, room, text string) {
+	h.mu.Lock()
+	_, joined := c.rooms[room]
+	h.mu.Unlock()
+	if !joined {
+		h.replyError(c, room, "not_joined")
+		return
+	}
+	// Напарники могли разорвать пару, а участник — выйти из челленджа
+	if _, err := h.Resolve(c.username, room); err != nil {
+		h.leave(c, room)
+		h.replyError(c, room, "access_denied")
+		return
+	}
+
+	text = strings.TrimSpace(text)
+	if text == "" || utf8.RuneCountInString(text) > MaxMessageLength {
+		h.replyError(c, room, "message_length", MaxMessageLength)
+		return
+	}
+
+	message := &models.ChatMessage{
+		Room:   room,
+		From:   c.username,
+		Text:   text,
+		SentAt: time.Now().UTC(),
+	}
+	h.store.AddChatMessage(message)
+
+	names := h.displayNames(room)
+	allowed := h.access(room)
+
+	h.mu.Lock()
+	evicted := false
+	for member := range h.rooms[room] {
+		// Получатель мог потерять доступ, пока оставался в комнате
+		if !allowed(member.username) {
+			h.evict(member, room)
+			evicted = true
+			continue
+		}
+		view := h.view(*message, member, names)
+		h.enqueue(member, outbound{Type: "message", Room: room, Message: &view})
+	}
+	h.mu.Unlock()
+
+	if evicted {
+		h.broadcastPresence(room)
+	}
+}
+
+func (h *Hub) history(c *client, room, before string, limit int) {
+	h.mu.Lock()
+	_, joined := c.rooms[room]
+	h.mu.Unlock()
+	if !joined {
+		h.replyError(c, room, "not_joined")
+		return
+	}
+	if _, err := h.Resolve(c.username, room); err != nil {
+		h.leave(c, room)
+		h.replyError(c, room, "access_denied")
+		return
+	}
+
+	if limit <= 0 || limit > MaxHistoryLimit {
+		limit = DefaultHistoryLimit
+	}
+	messages, hasMore := h.store.GetChatHistory(room, before, limit)
+
+	names := h.displayNames(room)
+	views := make([]messageView, 0, len(messages))
+	for _, message := range messages {
+		views = append(views, h.view(message, c, names))
+	}
+	h.reply(c, outbound{Type: "history", Room: room, Messages: views, HasMore: hasMore})
+}
+
+func (h *Hub) broadcastPresence(room string) {
+	names := h.displayNames(room)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// Подписи анонимных участников зависят от языка, поэтому список у каждого свой
+	for viewer := range h.rooms[room] {
+		var online []string
+		for member := range h.rooms[room] {
+			name := names(member.username, viewer.loc)
+			if !slices.Contains(online, name) {
+				online = append(online, name)
+			}
+		}
+		slices.Sort(online)
+		h.enqueue(viewer, outbound{Type: "presence", Room: room, Online: online})
+	}
+}
+
+func (h *Hub) reply(c *client, msg outbound) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.enqueue(c, msg)
+}
+
+// replyError отправляет клиенту ошибку. Текст берётся из каталога по ключу "chat.error.<код>"
+func (h *Hub) replyError(c *client, room, code string, args ...any) {
+	h.reply(c, errorMessage(c, room, code, args...))
+}
+
+func errorMessage(c *client, room, code string, args ...any) outbound {
+	return outbound{Type: "error", Room: room, Code: code, Error: c.loc.T("chat.error."+code, args...)}
+}
+
+// evict убирает клиента из комнаты и сообщает, что доступа больше нет. Вызывается под h.mu
+func (h *Hub) evict(c *client, room string) {
+	h.removeFromRoom(c, room)
+	h.enqueue(c, errorMessage(c, room, "access_denied"))
+}
+
+// access возвращает проверку, что курильщик всё ещё может быть в комнате.
+// Хранилище читается один раз на рассылку, а не на каждого получателя
+func (h *Hub) access(room string) func(username string) bool {
+	kind, rest, _ := strings.Cut(room, ":")
+	switch kind {
+	case "buddy":
+		a, b, _ := strings.Cut(rest, ":")
+		_, ok := h.store.GetBuddyship(a, b)
+		return func(username string) bool {
+			return ok && (username == a || username == b)
+		}
+	case "challenge":
+		members := make(map[string]bool)
+		for _, member := range h.store.GetChallengeMembers(rest) {
+			members[member.Username] = true
+		}
+		return func(username string) bool {
+			return members[username]
+		}
+	}
+	return func(string) bool { return false }
+}
+
+// enqueue ставит сообщение в очередь клиента, не блокируясь. Вызывается под h.mu
+func (h *Hub) enqueue(c *client, msg outbound) {
+	if c.closed {
+		return
+	}
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		h.logger.Error("chat.Hub.enqueue", helpers.SlogErr(err))
+		return
+	}
+
+	select {
+	case c.send <- payload:
+	default:
+		h.logger.Warn("chat.Hub.enqueue", helpers.SlogDebug("slow client disconnected"), "username", c.username)
+		h.closeClient(c, ws.CloseTryAgainLater, "too slow")
+	}
+}
+
+func (h *Hub) disconnect(c *client, code int, reason string) {
+	h.mu.Lock()
+	rooms := make([]string, 0, len(c.rooms))
+	for room := range c.rooms {
+		rooms = append(rooms, room)
+	}
+	h.closeClient(c, code, reason)
+	h.mu.Unlock()
+
+	for _, room := range rooms {
+		h.broadcastPresence(room)
+	}
+}
+
+// closeClient убирает клиента из всех комнат и закрывает его очередь. Вызывается под h.mu
+func (h *Hub) closeClient(c *client, code int, reason string) {
+	if c.closed {
+		return
+	}
+	for room := range c.rooms {
+		h.removeFromRoom(c, room)
+	}
+	delete(h.clients, c)
+	c.closed = true
+	c.closeCode = code
+	c.closeReason = reason
+	close(c.send)
+}
+
+func (h *Hub) removeFromRoom(c *client, room string) {
+	delete(c.rooms, room)
+	delete(h.rooms[room], c)
+	if len(h.rooms[room]) == 0 {
+		delete(h.rooms, room)
+	}
+}
+
+func (h *Hub) view(message models.ChatMessage, viewer *client, names displayName) messageView {
+	return messageView{
+		ID:     message.ID,
+		From:   names(message.From, viewer.loc),
+		Mine:   message.From == viewer.username,
+		Text:   message.Text,
+		SentAt: message.SentAt,
+	}
+}
+
+// displayName показывает имя курильщика в комнате на языке того, кто его видит
+type displayName func(username string, loc *i18n.Localizer) string
+
+// displayNames возвращает функцию, которая показывает имя курильщика в комнате.
+// Анонимные участники челленджа подписываются так же, как в таблице лидеров
+func (h *Hub) displayNames(room string) displayName {
+	anonymous := make(map[string]int)
+	members := make(map[string]bool)
+	if id, ok := strings.CutPrefix(room, "challenge:"); ok {
+		for _, member := range h.store.GetChallengeMembers(id) {
+			members[member.Username] = true
+			if member.Anonymous {
+				anonymous[member.Username] = member.Number
+			}
+		}
+	}
+
+	return func(username string, loc *i18n.Localizer) string {
+		if number, ok := anonymous[username]; ok {
+			return loc.T("challenge.anonymous", number)
+		}
+		if strings.HasPrefix(room, "challenge:") && !members[username] {
+			return loc.T("chat.former_member")
+		}
+		if smoker, ok := h.store.GetSmoker(username); ok {
+			return smoker.Name
+		}
+		return username
+	}
+}
+
+// BuddyRoom каноническое имя комнаты двух напарников
+func BuddyRoom(a, b string) string {
+	if a > b {
+		a, b = b, a
+	}
+	return "buddy:" + a + ":" + b
+}
+
+// ChallengeRoom имя комнаты участников челленджа
+func ChallengeRoom(id string) string {
+	return "challenge:" + id
+}
@@ -0,0 +1,219 @@
+package chat
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/i18n"
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/NarthurN/QuitSmoking/internal/storage"
+	"github.com/NarthurN/QuitSmoking/internal/ws"
+	"github.com/NarthurN/QuitSmoking/internal/ws/wstest"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestHub(t *testing.T) (*Hub, *storage.Storage, *httptest.Server) {
+	store := storage.New()
+	now := time.Now().UTC()
+	store.AddBuddyInvite(&models.BuddyInvite{Token: "t", From: "arthur", To: "victor", Status: models.InviteStatusPending, CreatedAt: now})
+	store.RespondBuddyInvite("t", true, now)
+
+	store.AddSmoker(&models.Smoker{ID: "1", Username: "arthur", Name: "Артур"})
+	store.AddSmoker(&models.Smoker{ID: "2", Username: "victor", Name: "Виктор"})
+	store.AddSmoker(&models.Smoker{ID: "3", Username: "olga", Name: "Ольга"})
+	hub := New(store, slog.Default())
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := ws.Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		username := r.URL.Query().Get("user")
+		hub.Serve(conn, username, i18n.Default().Localizer("ru"), func(time.Time) error {
+			if store.IsLocked(username) {
+				return ErrAccessDenied
+			}
+			return nil
+		})
+	}))
+	return hub, store, srv
+}
+
+func dial(t *testing.T, srv *httptest.Server, user string) *wstest.Client {
+	client, _, err := wstest.Dial(srv.URL+"?user="+user, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func send(t *testing.T, client *wstest.Client, msg inbound) {
+	payload, _ := json.Marshal(msg)
+	assert.NoError(t, client.WriteText(string(payload)))
+}
+
+// next читает сообщения, пока не придёт сообщение нужного типа
+func next(t *testing.T, client *wstest.Client, kind string) outbound {
+	for {
+		op, data, err := client.ReadFrame(time.Second)
+		if err != nil {
+			t.Fatalf("ожидали %s: %v", kind, err)
+		}
+		if op != ws.OpText {
+			continue
+		}
+		var msg outbound
+		assert.NoError(t, json.Unmarshal(data, &msg))
+		if msg.Type == kind {
+			return msg
+		}
+	}
+}
+
+func TestBuddyChat(t *testing.T) {
+	_, store, srv := newTestHub(t)
+	defer srv.Close()
+
+	arthur := dial(t, srv, "arthur")
+	defer arthur.Close()
+	victor := dial(t, srv, "victor")
+	defer victor.Close()
+	olga := dial(t, srv, "olga")
+	defer olga.Close()
+
+	send(t, arthur, inbound{Type: "join", Room: "buddy:victor"})
+	joined := next(t, arthur, "joined")
+	assert.Equal(t, "buddy:arthur:victor", joined.Room)
+
+	send(t, victor, inbound{Type: "join", Room: "buddy:arthur"})
+	next(t, victor, "joined")
+	presence := next(t, victor, "presence")
+	assert.Equal(t, []string{"Артур", "Виктор"}, presence.Online)
+
+	// Посторонний не может войти в комнату напарников
+	send(t, olga, inbound{Type: "join", Room: "buddy:arthur:victor"})
+	assert.Equal(t, "buddy:arthur:victor", next(t, olga, "error").Room)
+
+	send(t, arthur, inbound{Type: "message", Room: joined.Room, Text: "Держусь!"})
+	got := next(t, victor, "message")
+	assert.Equal(t, "Артур", got.Message.From)
+	assert.False(t, got.Message.Mine)
+	assert.Equal(t, "Держусь!", got.Message.Text)
+	assert.True(t, next(t, arthur, "message").Message.Mine)
+
+	send(t, arthur, inbound{Type: "message", Room: joined.Room, Text: "   "})
+	next(t, arthur, "error")
+
+	// История листается назад от самого раннего полученного сообщения
+	for i := range 5 {
+		store.AddChatMessage(&models.ChatMessage{Room: joined.Room, From: "victor", Text: strconv.Itoa(i), SentAt: time.Now()})
+	}
+	send(t, arthur, inbound{Type: "history", Room: joined.Room, Limit: 3})
+	page := next(t, arthur, "history")
+	assert.True(t, page.HasMore)
+	if assert.Len(t, page.Messages, 3) {
+		assert.Equal(t, "4", page.Messages[2].Text)
+		send(t, arthur, inbound{Type: "history", Room: joined.Room, Before: page.Messages[0].ID, Limit: 3})
+		page = next(t, arthur, "history")
+		assert.False(t, page.HasMore)
+		assert.Len(t, page.Messages, 3)
+		assert.Equal(t, "Держусь!", page.Messages[0].Text)
+	}
+
+	// Напарник отключился — остальные узнают об этом
+	victor.Close()
+	assert.Equal(t, []string{"Артур"}, next(t, arthur, "presence").Online)
+}
+
+func TestSlowClientIsDisconnected(t *testing.T) {
+	hub := New(storage.New(), slog.Default())
+	c := &client{username: "arthur", send: make(chan []byte, 1), rooms: map[string]struct{}{"challenge:1": {}}}
+	hub.clients[c] = struct{}{}
+	hub.rooms["challenge:1"] = map[*client]struct{}{c: {}}
+
+	hub.mu.Lock()
+	hub.enqueue(c, outbound{Type: "message"})
+	hub.enqueue(c, outbound{Type: "message"})
+	hub.mu.Unlock()
+
+	assert.True(t, c.closed)
+	assert.Equal(t, ws.CloseTryAgainLater, c.closeCode)
+	assert.Empty(t, hub.rooms)
+	assert.Empty(t, hub.clients)
+}
+
+func TestFormerMemberGetsNoMessages(t *testing.T) {
+	hub, store, srv := newTestHub(t)
+	defer srv.Close()
+
+	now := time.Now().UTC()
+	challenge := &models.Challenge{Title: "Весна", StartDate: now, EndDate: now.AddDate(0, 1, 0)}
+	store.AddChallenge(challenge)
+	store.JoinChallenge(challenge.ID, &models.ChallengeMember{Username: "arthur", JoinedAt: now})
+	store.JoinChallenge(challenge.ID, &models.ChallengeMember{Username: "olga", JoinedAt: now})
+	room := ChallengeRoom(challenge.ID)
+
+	arthur := dial(t, srv, "arthur")
+	defer arthur.Close()
+	olga := dial(t, srv, "olga")
+	defer olga.Close()
+	send(t, arthur, inbound{Type: "join", Room: room})
+	next(t, arthur, "joined")
+	send(t, olga, inbound{Type: "join", Room: room})
+	next(t, olga, "joined")
+
+	// Ольга вышла из челленджа, но соединение осталось открытым
+	store.LeaveChallenge(challenge.ID, "olga")
+	send(t, arthur, inbound{Type: "message", Room: room, Text: "Неделя позади"})
+	next(t, arthur, "message")
+	assert.Equal(t, "access_denied", next(t, olga, "error").Code)
+
+	send(t, olga, inbound{Type: "history", Room: room})
+	assert.Equal(t, "not_joined", next(t, olga, "error").Code)
+
+	// Разрыв пары сразу выводит обоих напарников из их комнаты
+	victor := dial(t, srv, "victor")
+	defer victor.Close()
+	send(t, victor, inbound{Type: "join", Room: "buddy:arthur"})
+	joined := next(t, victor, "joined")
+	store.DeleteBuddyship("arthur", "victor")
+	hub.Evict(BuddyRoom("arthur", "victor"), "arthur", "victor")
+	evicted := next(t, victor, "error")
+	assert.Equal(t, joined.Room, evicted.Room)
+	assert.Equal(t, "access_denied", evicted.Code)
+}
+
+func TestLockedClientIsDisconnected(t *testing.T) {
+	_, store, srv := newTestHub(t)
+	defer srv.Close()
+
+	arthur := dial(t, srv, "arthur")
+	defer arthur.Close()
+	send(t, arthur, inbound{Type: "join", Room: "buddy:victor"})
+	joined := next(t, arthur, "joined")
+
+	// Учётную запись заблокировали, пока чат открыт: следующее сообщение уже не проходит
+	store.LockAccount(&models.AccountLock{Username: "arthur", By: "admin", At: time.Now().UTC()})
+	send(t, arthur, inbound{Type: "message", Room: joined.Room, Text: "Привет"})
+	for {
+		op, data, err := arthur.ReadFrame(time.Second)
+		if !assert.NoError(t, err) {
+			return
+		}
+		if op == ws.OpClose {
+			assert.Equal(t, ws.ClosePolicyViolation, int(binary.BigEndian.Uint16(data)))
+			break
+		}
+		var msg outbound
+		assert.NoError(t, json.Unmarshal(data, &msg))
+		assert.NotEqual(t, "message", msg.Type)
+	}
+	history, _ := store.GetChatHistory(joined.Room, "", 10)
+	assert.Empty(t, history)
+}
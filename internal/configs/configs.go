@@ -1,34 +1,100 @@
 package configs
 
+import "time"
+
 const JwtKey = "my_secret_key"
 
 const (
-    // Объявляем привилегии нашей системы
-    ReadPermission  = "read"
-	AdminPermission  = "admin"
+	// Объявляем привилегии нашей системы
+	ReadPermission     = "read"
+	AdminPermission    = "admin"
+	ModeratePermission = "moderate"
 
-    // Объявляем роли нашей системы
-    UserRole  = "user"
-	AdminRole  = "admin"
+	// Объявляем роли нашей системы
+	UserRole      = "user"
+	AdminRole     = "admin"
+	ModeratorRole = "moderator"
 )
 
+// Все роли. Без особых ролей у пользователя есть только UserRole
+var Roles = []string{UserRole, ModeratorRole, AdminRole}
+
 var (
-    // Связка роль — привилегии
-    RolePermissions = map[string][]string{
-        AdminRole:  {AdminPermission},
-    }
+	// Связка роль — привилегии
+	RolePermissions = map[string][]string{
+		AdminRole:     {AdminPermission, ModeratePermission},
+		ModeratorRole: {ModeratePermission},
+	}
 )
 
 var (
-    // Связка пользователь — роль
-    UserRoles = map[string][]string{
-        "arthurCool": {AdminRole},
-    }
+	// Связка пользователь — роль
+	UserRoles = map[string][]string{
+		"arthurCool": {AdminRole},
+		"victorCool": {ModeratorRole},
+	}
 )
 
 var (
-    // Связка путь — роль
-    PathsRoles = map[string][]string{
-        "/smokers": {AdminRole},
-    }
-)
\ No newline at end of file
+	// Связка путь — роль
+	PathsRoles = map[string][]string{
+		"/smokers": {AdminRole},
+		// Путь с "/" на конце закрывает и все вложенные пути
+		"/admin":  {AdminRole},
+		"/admin/": {AdminRole},
+	}
+)
+
+// Файл с правилами достижений. Он встроен в бинарник, а в режиме разработки
+// читается с диска из ConfigsDir
+const (
+	AchievementRulesFile = "achievements.json"
+	ConfigsDir           = "configs"
+)
+
+// Каталог данных по умолчанию: ключ VAPID и загруженные файлы. Меняется флагом -data
+const DataDir = "data"
+
+// Настройки уведомлений. По умолчанию письма уходят в локальную заглушку SMTP (например, MailHog)
+const (
+	SMTPAddr       = "localhost:1025"
+	SMTPFrom       = "QuitSmoking <noreply@quitsmoking.local>"
+	NotifyInterval = time.Minute
+)
+
+// Настройки Web Push. Ключ VAPID создаётся при первом запуске и не должен меняться,
+// иначе подписки браузеров перестанут работать
+const (
+	VAPIDKeyFile = "vapid.pem"
+	VAPIDSubject = "mailto:noreply@quitsmoking.local"
+)
+
+// Настройки живого счётчика на странице профиля (Server-Sent Events)
+const (
+	LiveCheckInterval  = time.Second
+	LiveHeartbeat      = 15 * time.Second
+	LiveRetry          = 5 * time.Second
+	LiveMaxConnections = 1000
+	LiveMaxPerUser     = 3
+)
+
+// Загрузка фотографий к мотивирующим записям. Файлы лежат в подкаталоге MediaDir каталога данных
+const (
+	MediaDir       = "media"
+	MaxUploadSize  = 5 << 20
+	MaxImagePixels = 40_000_000
+)
+
+// Каталог с файлами фронтенда, откуда они читаются в режиме разработки
+const StaticDir = "static"
+
+// Журнал аудита: сколько событий без исполнителя (неудачные входы, недействительные токены)
+// записывается с одного адреса за окно и файл в каталоге данных, куда уходят старые события
+const (
+	AuditBurst       = 20
+	AuditWindow      = time.Minute
+	AuditArchiveFile = "audit.log"
+)
+
+// Наибольший размер тела JSON-запроса
+const MaxJSONBodySize = 64 << 10
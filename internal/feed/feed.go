@@ -0,0 +1,41 @@
+package feed
+
+import (
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+// Максимальная длина поста и причины жалобы на него
+const (
+	MaxPostLength         = 2000
+	MaxReportReasonLength = 500
+)
+
+// Threads собирает посты в ленту: новые обсуждения сверху, ответы в порядке публикации.
+// Скрытые посты и ответы видят только модераторы. Ответы на скрытый пост скрываются вместе с ним
+func Threads(posts []*models.Post, moderator bool) []models.Thread {
+	replies := make(map[string][]*models.Post)
+	var roots []*models.Post
+	for _, post := range posts {
+		if post.ParentID == "" {
+			roots = append(roots, post)
+			continue
+		}
+		if post.Hidden && !moderator {
+			continue
+		}
+		replies[post.ParentID] = append(replies[post.ParentID], post)
+	}
+
+	threads := make([]models.Thread, 0, len(roots))
+	for i := len(roots) - 1; i >= 0; i-- {
+		root := roots[i]
+		if root.Hidden && !moderator {
+			continue
+		}
+		threads = append(threads, models.Thread{
+			Post:    root,
+			Replies: replies[root.ID],
+		})
+	}
+	return threads
+}
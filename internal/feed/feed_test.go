@@ -0,0 +1,33 @@
+package feed
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThreads(t *testing.T) {
+	now := time.Now()
+	posts := []*models.Post{
+		{ID: "1", Text: "первый", CreatedAt: now},
+		{ID: "2", ParentID: "1", Text: "ответ", CreatedAt: now.Add(time.Minute)},
+		{ID: "3", ParentID: "1", Text: "спам", Hidden: true, CreatedAt: now.Add(2 * time.Minute)},
+		{ID: "4", Text: "скрытый", Hidden: true, CreatedAt: now.Add(3 * time.Minute)},
+		{ID: "5", Text: "второй", CreatedAt: now.Add(4 * time.Minute)},
+	}
+
+	threads := Threads(posts, false)
+
+	assert.Len(t, threads, 2)
+	assert.Equal(t, "5", threads[0].ID)
+	assert.Equal(t, "1", threads[1].ID)
+	assert.Len(t, threads[1].Replies, 1)
+
+	// Модератор видит всё
+	threads = Threads(posts, true)
+
+	assert.Len(t, threads, 3)
+	assert.Len(t, threads[2].Replies, 2)
+}
@@ -0,0 +1,82 @@
+package goals
+
+import (
+	"math"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+type Store interface {
+	GetGoals(username string) []*models.Goal
+	CompleteGoal(username, id string, at time.Time)
+}
+
+// StatsAt возвращает показатели курильщика на момент at
+type StatsAt func(at time.Time) models.SmokerStats
+
+// Progress считает прогресс по цели на момент now.
+// Выполненная цель остаётся выполненной, даже если показатель потом уменьшится (например, после срыва).
+// После срока цель выполнена, только если показатель достиг её к сроку
+func Progress(goal *models.Goal, statsAt StatsAt, now time.Time) models.GoalProgress {
+	at := now
+	expired := goal.Deadline != nil && now.After(*goal.Deadline)
+	if expired {
+		at = *goal.Deadline
+	}
+	current := value(goal, statsAt(at))
+
+	percent := 100.0
+	if goal.Target > 0 {
+		percent = math.Min(100, math.Round(current/goal.Target*1000)/10)
+	}
+
+	status := models.GoalStatusInProgress
+	switch {
+	case goal.CompletedAt != nil || current >= goal.Target:
+		status = models.GoalStatusCompleted
+		percent = 100
+	case expired:
+		status = models.GoalStatusFailed
+	}
+
+	return models.GoalProgress{
+		Goal:    goal,
+		Current: current,
+		Percent: percent,
+		Status:  status,
+	}
+}
+
+// Track считает прогресс по всем целям курильщика и фиксирует момент выполнения новых целей
+func Track(store Store, username string, statsAt StatsAt) []models.GoalProgress {
+	now := time.Now().UTC()
+
+	userGoals := store.GetGoals(username)
+	progress := make([]models.GoalProgress, 0, len(userGoals))
+	for _, goal := range userGoals {
+		p := Progress(goal, statsAt, now)
+		if p.Status == models.GoalStatusCompleted && goal.CompletedAt == nil {
+			// Цель, которую достигли к сроку, но заметили позже, выполнена в срок
+			completedAt := now
+			if goal.Deadline != nil && goal.Deadline.Before(now) {
+				completedAt = *goal.Deadline
+			}
+			store.CompleteGoal(username, goal.ID, completedAt)
+			goal.CompletedAt = &completedAt
+		}
+		progress = append(progress, p)
+	}
+	return progress
+}
+
+// value показатель курильщика, по которому считается цель
+func value(goal *models.Goal, stats models.SmokerStats) float64 {
+	switch goal.Kind {
+	case models.GoalKindMoney:
+		return stats.MoneySaved
+	case models.GoalKindDays:
+		return float64(stats.DaysSmokeFree)
+	}
+	return 0
+}
@@ -0,0 +1,100 @@
+package goals
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgress(t *testing.T) {
+	now := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	past := now.Add(-24 * time.Hour)
+
+	tests := []struct {
+		name    string
+		goal    *models.Goal
+		stats   models.SmokerStats
+		percent float64
+		status  string
+	}{
+		{
+			name:    "деньги в процессе",
+			goal:    &models.Goal{Kind: models.GoalKindMoney, Target: 40000},
+			stats:   models.SmokerStats{MoneySaved: 10000},
+			percent: 25,
+			status:  models.GoalStatusInProgress,
+		},
+		{
+			name:    "дни выполнены",
+			goal:    &models.Goal{Kind: models.GoalKindDays, Target: 100},
+			stats:   models.SmokerStats{DaysSmokeFree: 120},
+			percent: 100,
+			status:  models.GoalStatusCompleted,
+		},
+		{
+			name:    "срок истёк",
+			goal:    &models.Goal{Kind: models.GoalKindDays, Target: 100, Deadline: &past},
+			stats:   models.SmokerStats{DaysSmokeFree: 50},
+			percent: 50,
+			status:  models.GoalStatusFailed,
+		},
+		{
+			name:    "выполненная цель не откатывается",
+			goal:    &models.Goal{Kind: models.GoalKindDays, Target: 100, CompletedAt: &past},
+			stats:   models.SmokerStats{DaysSmokeFree: 0},
+			percent: 100,
+			status:  models.GoalStatusCompleted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Progress(tt.goal, func(time.Time) models.SmokerStats { return tt.stats }, now)
+			assert.Equal(t, tt.percent, p.Percent)
+			assert.Equal(t, tt.status, p.Status)
+		})
+	}
+}
+
+func TestProgressAfterDeadline(t *testing.T) {
+	quit := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	deadline := quit.AddDate(0, 0, 30)
+	now := quit.AddDate(0, 0, 60)
+	// Каждый день без сигарет добавляет один день к показателю
+	statsAt := func(at time.Time) models.SmokerStats {
+		return models.SmokerStats{DaysSmokeFree: int(at.Sub(quit).Hours() / 24)}
+	}
+
+	// К сроку было 30 дней из 45: то, что сейчас их 60, цель не спасает
+	late := Progress(&models.Goal{Kind: models.GoalKindDays, Target: 45, Deadline: &deadline}, statsAt, now)
+	assert.Equal(t, models.GoalStatusFailed, late.Status)
+	assert.Equal(t, 30.0, late.Current)
+
+	// Цель достигли к сроку, хотя прогресс тогда никто не смотрел
+	store := &fakeStore{goals: []*models.Goal{{ID: "1", Kind: models.GoalKindDays, Target: 20, Deadline: &deadline}}}
+	progress := Track(store, "arthur", statsAt)
+	if assert.Len(t, progress, 1) {
+		assert.Equal(t, models.GoalStatusCompleted, progress[0].Status)
+		assert.Equal(t, deadline, *progress[0].CompletedAt)
+	}
+	assert.Equal(t, deadline, store.completed["1"])
+}
+
+// fakeStore цели одного курильщика для теста
+type fakeStore struct {
+	goals     []*models.Goal
+	completed map[string]time.Time
+}
+
+func (s *fakeStore) GetGoals(username string) []*models.Goal {
+	return s.goals
+}
+
+func (s *fakeStore) CompleteGoal(username, id string, at time.Time) {
+	if s.completed == nil {
+		s.completed = make(map[string]time.Time)
+	}
+	s.completed[id] = at
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/achievements"
+	"github.com/NarthurN/QuitSmoking/internal/apperr"
+	"github.com/NarthurN/QuitSmoking/internal/helpers"
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/NarthurN/QuitSmoking/internal/notify"
+)
+
+// PostCraving записывает эпизод тяги в дневник курильщика
+func (h *Handlers) PostCraving() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		craving := &models.Craving{
+			Username: username,
+			At:       time.Now().UTC(),
+			Resisted: r.FormValue("resisted") == "on",
+			Note:     r.FormValue("note"),
+		}
+		h.Storage.AddCraving(craving)
+
+		if r.FormValue("askBuddies") == "on" {
+			notify.CravingHelp(h.Storage, h.I18n, username, h.smokerName(username), craving.Note, craving.At)
+		}
+
+		http.Redirect(w, r, `/profile`, http.StatusFound)
+		return nil
+	})
+}
+
+// GetCravings отображает дневник тяги курильщика в формате JSON
+func (h *Handlers) GetCravings() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		return writeJSON(w, http.StatusOK, h.Storage.GetCravings(username))
+	})
+}
+
+// GetAchievements отображает достижения курильщика в формате JSON
+func (h *Handlers) GetAchievements() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		smoker, ok := h.Storage.GetSmoker(username)
+		if !ok {
+			return apperr.NotFound(apperr.CodeSmokerNotFound)
+		}
+
+		stats := helpers.GetSmokerStats(smoker, h.Storage.GetCravings(username), h.Storage.GetPriceHistory(username))
+		h.Achievements.Award(h.Storage, username, stats)
+
+		return writeJSON(w, http.StatusOK, achievements.Localized(h.localizer(r), h.Storage.GetAchievements(username)))
+	})
+}
@@ -0,0 +1,424 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/NarthurN/QuitSmoking/internal/achievements"
+	"github.com/NarthurN/QuitSmoking/internal/admin"
+	"github.com/NarthurN/QuitSmoking/internal/apperr"
+	"github.com/NarthurN/QuitSmoking/internal/configs"
+	"github.com/NarthurN/QuitSmoking/internal/helpers"
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/NarthurN/QuitSmoking/internal/smokers"
+	"github.com/NarthurN/QuitSmoking/internal/validate"
+)
+
+const (
+	maxLockReasonLength = 200
+	tempPasswordLength  = 12
+	// Сколько последних записей дневника тяги и самочувствия видно в карточке курильщика
+	adminActivityLimit = 10
+)
+
+// Символы временного пароля: без похожих друг на друга 0/O и 1/l/I
+const tempPasswordAlphabet = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// requireAdmin username администратора. Пути /admin закрыты ролью в configs.PathsRoles,
+// но обработчики проверяют роль и сами, чтобы не зависеть от настройки путей
+func requireAdmin(r *http.Request) (string, error) {
+	username, err := currentUser(r)
+	if err != nil {
+		return "", err
+	}
+	if !helpers.HasRole(username, configs.AdminRole) {
+		return "", apperr.New(http.StatusForbidden, apperr.CodeForbidden)
+	}
+	return username, nil
+}
+
+// adminTarget курильщик из пути запроса
+func (h *Handlers) adminTarget(r *http.Request) (*models.Smoker, error) {
+	smoker, ok := h.Storage.FindSmoker(r.PathValue("id"))
+	if !ok {
+		return nil, apperr.NotFound(apperr.CodeSmokerNotFound)
+	}
+	return smoker, nil
+}
+
+// setTokenCookie отдаёт браузеру токен так же, как Signin
+func setTokenCookie(w http.ResponseWriter, token string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:    "token",
+		Value:   "Bearer " + token,
+		Expires: time.Now().UTC().Add(5 * time.Minute),
+		Path:    "/",
+	})
+}
+
+// adminListItem курильщик в списке на панели администратора
+type adminListItem struct {
+	smokerListItem
+	Locked bool
+}
+
+// GetAdminPage отображает панель администратора: сводку по всем курильщикам
+// и список с теми же фильтрами, сортировкой и курсором, что и GetSmokers
+func (h *Handlers) GetAdminPage() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := requireAdmin(r)
+		if err != nil {
+			return err
+		}
+
+		query, err := smokersQuery(r)
+		if err != nil {
+			return err
+		}
+		all := h.Storage.AllSmokers()
+		page, err := listSmokers(all, query)
+		if err != nil {
+			return err
+		}
+
+		items := make([]adminListItem, 0, len(page.Smokers))
+		for _, smoker := range page.Smokers {
+			items = append(items, adminListItem{
+				smokerListItem: smokerListItem{
+					Smoker:        *smoker,
+					Roles:         helpers.GetRoles(smoker.Username),
+					SmokeFreeDays: helpers.GetSmokeFreeDays(smoker),
+				},
+				Locked: h.Storage.IsLocked(smoker.Username),
+			})
+		}
+
+		// Ссылка на следующую страницу сохраняет фильтры
+		var next string
+		if page.NextCursor != "" {
+			values := r.URL.Query()
+			values.Set("cursor", page.NextCursor)
+			next = "/admin?" + values.Encode()
+		}
+
+		stats := admin.Stats(all, admin.Sources{
+			Cravings: h.Storage.GetCravings,
+			Roles:    helpers.GetRoles,
+			Locked:   h.Storage.IsLocked,
+		}, time.Now().UTC())
+
+		data := struct {
+			Name     string
+			Unread   int
+			Stats    models.AdminStats
+			Roles    []string
+			Sorts    []string
+			Query    smokers.Query
+			QuitFrom string
+			QuitTo   string
+			Items    []adminListItem
+			Total    int
+			Next     string
+		}{
+			Name:     h.smokerName(username),
+			Unread:   h.Storage.CountUnread(username),
+			Stats:    stats,
+			Roles:    configs.Roles,
+			Sorts:    []string{smokers.DefaultSort, smokers.SortCreatedAt, "-" + smokers.SortSmokeFree, smokers.SortSmokeFree},
+			Query:    query,
+			QuitFrom: r.URL.Query().Get("quitFrom"),
+			QuitTo:   r.URL.Query().Get("quitTo"),
+			Items:    items,
+			Total:    page.Total,
+			Next:     next,
+		}
+		h.render(w, r, http.StatusOK, "admin.html", data)
+		return nil
+	})
+}
+
+// adminSmokerPage данные карточки курильщика
+type adminSmokerPage struct {
+	Name          string
+	Unread        int
+	Smoker        models.Smoker
+	Self          bool
+	Roles         []string
+	AllRoles      []string
+	SmokeFreeDays int
+	Lock          *models.AccountLock
+	Cravings      []*models.Craving
+	CheckIns      []*models.CheckIn
+	Achievements  []*models.Achievement
+	Audit         []models.AuditEvent
+	// TempPassword новый пароль, который показывается один раз сразу после сброса
+	TempPassword string
+}
+
+// renderAdminSmoker отображает карточку курильщика smoker для администратора username
+func (h *Handlers) renderAdminSmoker(w http.ResponseWriter, r *http.Request, username string, smoker *models.Smoker, password string) {
+	// Дневник тяги хранится по порядку записей, а отметки самочувствия уже от новых к старым
+	cravings := h.Storage.GetCravings(smoker.Username)
+	slices.Reverse(cravings)
+	checkIns := h.Storage.GetCheckIns(smoker.Username)
+
+	data := adminSmokerPage{
+		Name:          h.smokerName(username),
+		Unread:        h.Storage.CountUnread(username),
+		Smoker:        *smoker,
+		Self:          smoker.Username == username,
+		Roles:         helpers.GetRoles(smoker.Username),
+		AllRoles:      configs.Roles,
+		SmokeFreeDays: helpers.GetSmokeFreeDays(smoker),
+		Cravings:      cravings[:min(len(cravings), adminActivityLimit)],
+		CheckIns:      checkIns[:min(len(checkIns), adminActivityLimit)],
+		Achievements:  achievements.Localized(h.localizer(r), h.Storage.GetAchievements(smoker.Username)),
+		Audit:         h.Storage.GetAuditEvents(smoker.Username),
+		TempPassword:  password,
+	}
+	data.Smoker.Password = ""
+	if lock, ok := h.Storage.GetAccountLock(smoker.Username); ok {
+		data.Lock = lock
+	}
+
+	h.render(w, r, http.StatusOK, "admin_smoker.html", data)
+}
+
+// GetAdminSmoker отображает карточку курильщика: данные, роли, блокировку и активность
+func (h *Handlers) GetAdminSmoker() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := requireAdmin(r)
+		if err != nil {
+			return err
+		}
+		smoker, err := h.adminTarget(r)
+		if err != nil {
+			return err
+		}
+
+		h.renderAdminSmoker(w, r, username, smoker, "")
+		return nil
+	})
+}
+
+// tempPassword случайный временный пароль из tempPasswordAlphabet
+func tempPassword() (string, error) {
+	const op = "handlers.tempPassword"
+
+	alphabet := big.NewInt(int64(len(tempPasswordAlphabet)))
+	password := make([]byte, tempPasswordLength)
+	for i := range password {
+		n, err := rand.Int(rand.Reader, alphabet)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+		password[i] = tempPasswordAlphabet[n.Int64()]
+	}
+	return string(password), nil
+}
+
+// redirectToAdminSmoker возвращает администратора на карточку курильщика после действия
+func redirectToAdminSmoker(w http.ResponseWriter, r *http.Request, smoker *models.Smoker) {
+	http.Redirect(w, r, "/admin/smokers/"+smoker.ID, http.StatusSeeOther)
+}
+
+// PostAdminPassword сбрасывает пароль курильщика на случайный временный
+// и показывает его администратору один раз
+func (h *Handlers) PostAdminPassword() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := requireAdmin(r)
+		if err != nil {
+			return err
+		}
+
+		password, err := tempPassword()
+		if err != nil {
+			return err
+		}
+
+		smoker, ok, err := h.Storage.UpdateSmoker(r.PathValue("id"), func(smoker *models.Smoker) error {
+			smoker.Password = password
+			smoker.Version++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return apperr.NotFound(apperr.CodeSmokerNotFound)
+		}
+
+		h.Storage.RevokeTokens(smoker.Username)
+		h.audit(r, username, models.AuditPasswordReset, smoker.Username, "")
+
+		// Страница с паролем не должна оседать в кэше и истории браузера
+		w.Header().Set("Cache-Control", "no-store")
+		h.renderAdminSmoker(w, r, username, smoker, password)
+		return nil
+	})
+}
+
+// PostAdminLock блокирует учётную запись. Заблокированный курильщик не может войти,
+// а его токены перестают действовать сразу
+func (h *Handlers) PostAdminLock() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := requireAdmin(r)
+		if err != nil {
+			return err
+		}
+		smoker, err := h.adminTarget(r)
+		if err != nil {
+			return err
+		}
+		if smoker.Username == username {
+			return apperr.New(http.StatusConflict, apperr.CodeSelfAction)
+		}
+
+		reason := strings.TrimSpace(r.FormValue("reason"))
+		if utf8.RuneCountInString(reason) > maxLockReasonLength {
+			return invalid(validate.Errors{{Field: "reason", Code: validate.CodeMaxLength, Args: []any{maxLockReasonLength}}})
+		}
+
+		h.Storage.LockAccount(&models.AccountLock{
+			Username: smoker.Username,
+			By:       username,
+			Reason:   reason,
+			At:       time.Now().UTC(),
+		})
+		h.audit(r, username, models.AuditAccountLock, smoker.Username, reason)
+
+		redirectToAdminSmoker(w, r, smoker)
+		return nil
+	})
+}
+
+// PostAdminUnlock снимает блокировку учётной записи
+func (h *Handlers) PostAdminUnlock() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := requireAdmin(r)
+		if err != nil {
+			return err
+		}
+		smoker, err := h.adminTarget(r)
+		if err != nil {
+			return err
+		}
+
+		if h.Storage.UnlockAccount(smoker.Username) {
+			h.audit(r, username, models.AuditAccountUnlock, smoker.Username, "")
+		}
+
+		redirectToAdminSmoker(w, r, smoker)
+		return nil
+	})
+}
+
+// PostAdminRoles заменяет роли курильщика отмеченными в форме. Снять роль
+// администратора с себя нельзя, чтобы не остаться без доступа к панели
+func (h *Handlers) PostAdminRoles() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := requireAdmin(r)
+		if err != nil {
+			return err
+		}
+		smoker, err := h.adminTarget(r)
+		if err != nil {
+			return err
+		}
+
+		if err := r.ParseForm(); err != nil {
+			return apperr.BadRequest(apperr.CodeBadRequest).Wrap(err)
+		}
+		roles := r.PostForm["role"]
+		for _, role := range roles {
+			if !slices.Contains(configs.Roles, role) {
+				return invalid(validate.Errors{{Field: "role", Code: validate.CodeInvalid}})
+			}
+		}
+		if smoker.Username == username && !slices.Contains(roles, configs.AdminRole) {
+			return apperr.New(http.StatusConflict, apperr.CodeSelfAction)
+		}
+
+		before := strings.Join(helpers.GetRoles(smoker.Username), ",")
+		helpers.SetRoles(smoker.Username, roles)
+		after := strings.Join(helpers.GetRoles(smoker.Username), ",")
+		if before != after {
+			h.Storage.RevokeTokens(smoker.Username)
+			h.audit(r, username, models.AuditRoleChange, smoker.Username, before+" -> "+after)
+		}
+
+		redirectToAdminSmoker(w, r, smoker)
+		return nil
+	})
+}
+
+// PostAdminImpersonate выдаёт администратору токен курильщика, чтобы увидеть
+// приложение его глазами. Входить от имени других администраторов и заблокированных нельзя
+func (h *Handlers) PostAdminImpersonate() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := requireAdmin(r)
+		if err != nil {
+			return err
+		}
+		smoker, err := h.adminTarget(r)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case smoker.Username == username:
+			return apperr.New(http.StatusConflict, apperr.CodeSelfAction)
+		case helpers.HasRole(smoker.Username, configs.AdminRole):
+			return apperr.New(http.StatusForbidden, apperr.CodeImpersonateAdmin)
+		case h.Storage.IsLocked(smoker.Username):
+			return apperr.New(http.StatusForbidden, apperr.CodeAccountLocked)
+		}
+
+		token, err := h.Mw.Tokener.GetImpersonationToken(smoker.Username, username)
+		if err != nil {
+			return err
+		}
+		setTokenCookie(w, token)
+		h.audit(r, username, models.AuditImpersonateStart, smoker.Username, "")
+
+		http.Redirect(w, r, "/profile", http.StatusSeeOther)
+		return nil
+	})
+}
+
+// StopImpersonation возвращает администратору его собственный токен
+func (h *Handlers) StopImpersonation() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+		impersonator, ok := r.Context().Value(models.ContextString("smoker.impersonator")).(string)
+		if !ok {
+			return apperr.BadRequest(apperr.CodeNotImpersonating)
+		}
+		if h.Storage.IsLocked(impersonator) {
+			return apperr.New(http.StatusForbidden, apperr.CodeAccountLocked)
+		}
+
+		token, err := h.Mw.Tokener.GetJwtToken(impersonator)
+		if err != nil {
+			return err
+		}
+		setTokenCookie(w, token)
+		h.audit(r, impersonator, models.AuditImpersonateStop, username, "")
+
+		target := "/admin"
+		if smoker, ok := h.Storage.GetSmoker(username); ok {
+			target = "/admin/smokers/" + smoker.ID
+		}
+		http.Redirect(w, r, target, http.StatusSeeOther)
+		return nil
+	})
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/apperr"
+	"github.com/NarthurN/QuitSmoking/internal/audit"
+	"github.com/NarthurN/QuitSmoking/internal/helpers"
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/NarthurN/QuitSmoking/internal/validate"
+)
+
+// Сколько последних событий журнала показывает страница. Выгрузка отдаёт все
+const (
+	auditPageLimit = 100
+	auditMaxLimit  = 1000
+)
+
+// audit записывает действие в журнал аудита вместе с адресом клиента и id запроса
+func (h *Handlers) audit(r *http.Request, actor, action, target, details string) {
+	event := audit.Event(r, actor, action, target)
+	event.Details = details
+	h.Audit.AddAuditEvent(event)
+}
+
+// auditQuery разбирает параметры журнала: actor, target, action (действие или группа,
+// например signin), requestId, from и to (ГГГГ-ММ-ДД, включительно) и limit
+func auditQuery(r *http.Request) (audit.Filter, int, error) {
+	values := r.URL.Query()
+	filter := audit.Filter{
+		Actor:     strings.TrimSpace(values.Get("actor")),
+		Target:    strings.TrimSpace(values.Get("target")),
+		Action:    values.Get("action"),
+		RequestID: strings.TrimSpace(values.Get("requestId")),
+	}
+	limit := auditPageLimit
+
+	var fields []apperr.FieldError
+	date := func(name string) time.Time {
+		raw := values.Get(name)
+		if raw == "" {
+			return time.Time{}
+		}
+		t, err := time.Parse(time.DateOnly, raw)
+		if err != nil {
+			fields = append(fields, apperr.FieldError{Field: name, Code: validate.CodeInvalid})
+		}
+		return t
+	}
+	filter.From = date("from")
+	// Дата окончания включительно: фильтр не включает свою границу, поэтому берём следующий день
+	if to := date("to"); !to.IsZero() {
+		filter.To = to.AddDate(0, 0, 1)
+	}
+
+	if filter.Action != "" && !knownAuditAction(filter.Action) {
+		fields = append(fields, apperr.FieldError{Field: "action", Code: validate.CodeInvalid})
+	}
+	if raw := values.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > auditMaxLimit {
+			fields = append(fields, apperr.FieldError{Field: "limit", Code: validate.CodeRange, Args: []any{1, auditMaxLimit}})
+		}
+		limit = n
+	}
+
+	if len(fields) > 0 {
+		return audit.Filter{}, 0, apperr.BadRequest(apperr.CodeInvalidQuery).WithFields(fields...)
+	}
+	return filter, limit, nil
+}
+
+// knownAuditAction действие из models.AuditActions или их группа, например "signin"
+func knownAuditAction(action string) bool {
+	return slices.ContainsFunc(models.AuditActions(), func(known string) bool {
+		return known == action || strings.HasPrefix(known, action+".")
+	})
+}
+
+// GetAuditPage отображает журнал аудита с фильтрами, от новых событий к старым
+func (h *Handlers) GetAuditPage() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := requireAdmin(r)
+		if err != nil {
+			return err
+		}
+		filter, limit, err := auditQuery(r)
+		if err != nil {
+			return err
+		}
+
+		// Выгрузка с теми же фильтрами, но без ограничения количества
+		export := r.URL.Query()
+		export.Del("limit")
+
+		data := struct {
+			Name    string
+			Unread  int
+			Filter  audit.Filter
+			From    string
+			To      string
+			Actions []string
+			Events  []models.AuditEvent
+			Limit   int
+			Export  string
+		}{
+			Name:    h.smokerName(username),
+			Unread:  h.Storage.CountUnread(username),
+			Filter:  filter,
+			From:    r.URL.Query().Get("from"),
+			To:      r.URL.Query().Get("to"),
+			Actions: models.AuditActions(),
+			Events:  h.Storage.ListAuditEvents(filter.Match, limit),
+			Limit:   limit,
+			Export:  "/admin/audit/export?" + export.Encode(),
+		}
+		h.render(w, r, http.StatusOK, "admin_audit.html", data)
+		return nil
+	})
+}
+
+// ExportAudit выгружает журнал аудита с фильтрами GetAuditPage в формате JSON Lines:
+// по событию на строку, от новых к старым
+func (h *Handlers) ExportAudit() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		if _, err := requireAdmin(r); err != nil {
+			return err
+		}
+		filter, _, err := auditQuery(r)
+		if err != nil {
+			return err
+		}
+
+		events := h.Storage.ListAuditEvents(filter.Match, 0)
+		filename := "audit-" + time.Now().UTC().Format("20060102") + ".jsonl"
+
+		w.Header().Set("Content-Type", audit.ContentType+";charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+		w.Header().Set("Cache-Control", "no-store")
+		// Заголовок уже отправлен: если запись оборвётся, клиенту останется неполный файл
+		if err := audit.WriteJSONLines(w, events); err != nil {
+			h.Logger.Error("handlers.ExportAudit.WriteJSONLines", helpers.SlogErr(err))
+		}
+		return nil
+	})
+}
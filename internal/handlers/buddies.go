@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/NarthurN/QuitSmoking/internal/achievements"
+	"github.com/NarthurN/QuitSmoking/internal/apperr"
+	"github.com/NarthurN/QuitSmoking/internal/buddies"
+	"github.com/NarthurN/QuitSmoking/internal/chat"
+	"github.com/NarthurN/QuitSmoking/internal/i18n"
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+// GetBuddiesPage отображает страницу напарников: приглашения, общий прогресс и сообщения
+func (h *Handlers) GetBuddiesPage() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		smoker, ok := h.Storage.GetSmoker(username)
+		if !ok {
+			return apperr.NotFound(apperr.CodeSmokerNotFound)
+		}
+
+		type buddyWithMessages struct {
+			models.BuddyView
+			Messages []*models.BuddyMessage
+		}
+
+		views := h.buddyViews(username, h.localizer(r))
+		list := make([]buddyWithMessages, 0, len(views))
+		for _, view := range views {
+			list = append(list, buddyWithMessages{
+				BuddyView: view,
+				Messages:  h.Storage.GetBuddyMessages(username, view.Username),
+			})
+		}
+
+		data := struct {
+			Name     string
+			Username string
+			Invites  []*models.BuddyInvite
+			Buddies  []buddyWithMessages
+			Unread   int
+		}{
+			Name:     smoker.Name,
+			Username: username,
+			Invites:  h.Storage.GetPendingInvites(username),
+			Buddies:  list,
+			Unread:   h.Storage.CountUnread(username),
+		}
+
+		h.render(w, r, http.StatusOK, "buddies.html", data)
+		return nil
+	})
+}
+
+// GetBuddies отображает данные всех напарников с учётом их настроек приватности в формате JSON
+func (h *Handlers) GetBuddies() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		return writeJSON(w, http.StatusOK, h.buddyViews(username, h.localizer(r)))
+	})
+}
+
+// PostBuddyInvite приглашает другого пользователя стать напарником
+func (h *Handlers) PostBuddyInvite() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		invitee := strings.TrimSpace(r.FormValue("username"))
+		if invitee == username {
+			return apperr.BadRequest(apperr.CodeSelfInvite)
+		}
+		if _, ok := h.Storage.GetSmoker(invitee); !ok {
+			return apperr.BadRequest(apperr.CodeUnknownUser)
+		}
+		if _, ok := h.Storage.GetBuddyship(username, invitee); ok {
+			return apperr.New(http.StatusConflict, apperr.CodeAlreadyBuddies)
+		}
+		for _, invite := range h.Storage.GetPendingInvites(username) {
+			if invite.From == invitee || invite.To == invitee {
+				return apperr.New(http.StatusConflict, apperr.CodeInviteExists)
+			}
+		}
+
+		token, err := buddies.NewInviteToken()
+		if err != nil {
+			return fmt.Errorf("handlers.PostBuddyInvite.NewInviteToken: %w", err)
+		}
+
+		h.Storage.AddBuddyInvite(&models.BuddyInvite{
+			Token:     token,
+			From:      username,
+			To:        invitee,
+			Status:    models.InviteStatusPending,
+			CreatedAt: time.Now().UTC(),
+		})
+		loc := h.localizerFor(invitee)
+		h.Inbox.Post(invitee, models.InboxBuddyInvite, loc.T("inbox.buddy_invite.title"), loc.T("inbox.buddy_invite.body", username), "/buddies")
+
+		http.Redirect(w, r, `/buddies`, http.StatusFound)
+		return nil
+	})
+}
+
+// AcceptBuddyInvite принимает приглашение в напарники
+func (h *Handlers) AcceptBuddyInvite() http.HandlerFunc {
+	return h.respondBuddyInvite(true)
+}
+
+// DeclineBuddyInvite отклоняет приглашение в напарники
+func (h *Handlers) DeclineBuddyInvite() http.HandlerFunc {
+	return h.respondBuddyInvite(false)
+}
+
+func (h *Handlers) respondBuddyInvite(accept bool) http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		// Отвечать на приглашение может только приглашённый
+		invite, ok := h.Storage.GetBuddyInvite(r.PathValue("token"))
+		if !ok || invite.To != username {
+			return apperr.NotFound(apperr.CodeInviteNotFound)
+		}
+
+		if !h.Storage.RespondBuddyInvite(invite.Token, accept, time.Now().UTC()) {
+			return apperr.New(http.StatusConflict, apperr.CodeInviteHandled)
+		}
+
+		http.Redirect(w, r, `/buddies`, http.StatusFound)
+		return nil
+	})
+}
+
+// PostBuddySharing меняет, что курильщик показывает напарнику
+func (h *Handlers) PostBuddySharing() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		sharing := models.SharingSettings{
+			Streak:     r.FormValue("streak") == "on",
+			Milestones: r.FormValue("milestones") == "on",
+			Cravings:   r.FormValue("cravings") == "on",
+		}
+		if !h.Storage.SetSharing(username, r.PathValue("username"), sharing) {
+			return apperr.NotFound(apperr.CodeBuddyNotFound)
+		}
+
+		http.Redirect(w, r, `/buddies`, http.StatusFound)
+		return nil
+	})
+}
+
+// PostBuddyMessage отправляет напарнику сообщение поддержки
+func (h *Handlers) PostBuddyMessage() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		buddy := r.PathValue("username")
+		if _, ok := h.Storage.GetBuddyship(username, buddy); !ok {
+			return apperr.NotFound(apperr.CodeBuddyNotFound)
+		}
+
+		text := strings.TrimSpace(r.FormValue("text"))
+		if text == "" || utf8.RuneCountInString(text) > buddies.MaxMessageLength {
+			return apperr.BadRequest(apperr.CodeMessageLength, buddies.MaxMessageLength)
+		}
+
+		h.Storage.AddBuddyMessage(&models.BuddyMessage{
+			From:   username,
+			To:     buddy,
+			Text:   text,
+			SentAt: time.Now().UTC(),
+		})
+		h.Inbox.Post(buddy, models.InboxBuddyMessage, h.localizerFor(buddy).T("inbox.buddy_message.title", username), text, "/buddies")
+
+		http.Redirect(w, r, `/buddies`, http.StatusFound)
+		return nil
+	})
+}
+
+// GetBuddyMessages отображает переписку с напарником в формате JSON
+func (h *Handlers) GetBuddyMessages() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		buddy := r.PathValue("username")
+		if _, ok := h.Storage.GetBuddyship(username, buddy); !ok {
+			return apperr.NotFound(apperr.CodeBuddyNotFound)
+		}
+
+		return writeJSON(w, http.StatusOK, h.Storage.GetBuddyMessages(username, buddy))
+	})
+}
+
+// DeleteBuddy разрывает связь с напарником
+func (h *Handlers) DeleteBuddy() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		buddy := r.PathValue("username")
+		if !h.Storage.DeleteBuddyship(username, buddy) {
+			return apperr.NotFound(apperr.CodeBuddyNotFound)
+		}
+		h.Chat.Evict(chat.BuddyRoom(username, buddy), username, buddy)
+
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	})
+}
+
+// buddyViews собирает данные всех напарников курильщика с учётом их настроек приватности.
+// Достижения напарников подписываются на языке loc
+func (h *Handlers) buddyViews(username string, loc *i18n.Localizer) []models.BuddyView {
+	names := h.Storage.GetBuddies(username)
+	views := make([]models.BuddyView, 0, len(names))
+	for _, name := range names {
+		buddy, ok := h.Storage.GetSmoker(name)
+		if !ok {
+			continue
+		}
+		buddyship, ok := h.Storage.GetBuddyship(username, name)
+		if !ok {
+			continue
+		}
+		views = append(views, buddies.View(
+			username,
+			buddy,
+			buddyship,
+			achievements.Localized(loc, h.Storage.GetAchievements(name)),
+			h.Storage.GetCravings(name),
+		))
+	}
+	return views
+}
@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/apperr"
+	"github.com/NarthurN/QuitSmoking/internal/challenges"
+	"github.com/NarthurN/QuitSmoking/internal/chat"
+	"github.com/NarthurN/QuitSmoking/internal/i18n"
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/NarthurN/QuitSmoking/internal/validate"
+)
+
+type challengeView struct {
+	*models.Challenge
+	Status  string
+	Members int
+	Joined  bool
+}
+
+// GetChallengesPage отображает список челленджей и форму создания нового
+func (h *Handlers) GetChallengesPage() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		list := h.Storage.GetChallenges()
+		views := make([]challengeView, 0, len(list))
+		for _, challenge := range list {
+			views = append(views, h.challengeView(challenge, username, now))
+		}
+
+		data := struct {
+			Name       string
+			Challenges []challengeView
+			Unread     int
+		}{
+			Name:       h.smokerName(username),
+			Challenges: views,
+			Unread:     h.Storage.CountUnread(username),
+		}
+
+		h.render(w, r, http.StatusOK, "challenges.html", data)
+		return nil
+	})
+}
+
+// PostChallenge создаёт челлендж. Создатель сразу становится участником
+func (h *Handlers) PostChallenge() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		title := strings.TrimSpace(r.FormValue("title"))
+		if title == "" {
+			return apperr.BadRequest(apperr.CodeChallengeTitle)
+		}
+
+		start, err := time.Parse(time.DateOnly, r.FormValue("startDate"))
+		if err != nil {
+			return apperr.BadRequest(apperr.CodeInvalidDate).
+				WithFields(apperr.FieldError{Field: "startDate", Code: validate.CodeInvalid})
+		}
+		end, err := time.Parse(time.DateOnly, r.FormValue("endDate"))
+		if err != nil {
+			return apperr.BadRequest(apperr.CodeInvalidDate).
+				WithFields(apperr.FieldError{Field: "endDate", Code: validate.CodeInvalid})
+		}
+
+		now := time.Now().UTC()
+		if start.Before(now.Truncate(24 * time.Hour)) {
+			return apperr.BadRequest(apperr.CodeStartInPast)
+		}
+		if !end.After(start) {
+			return apperr.BadRequest(apperr.CodeEndBeforeStart)
+		}
+
+		challenge := &models.Challenge{
+			Title:     title,
+			CreatedBy: username,
+			StartDate: start,
+			EndDate:   end,
+			CreatedAt: now,
+		}
+		h.Storage.AddChallenge(challenge)
+		h.Storage.JoinChallenge(challenge.ID, &models.ChallengeMember{
+			Username:  username,
+			JoinedAt:  now,
+			Anonymous: r.FormValue("anonymous") == "on",
+		})
+
+		http.Redirect(w, r, `/challenges/`+challenge.ID, http.StatusFound)
+		return nil
+	})
+}
+
+// GetChallengePage отображает страницу группы челленджа с таблицей лидеров
+func (h *Handlers) GetChallengePage() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		challenge, ok := h.Storage.GetChallenge(r.PathValue("id"))
+		if !ok {
+			return apperr.NotFound(apperr.CodeChallengeNotFound)
+		}
+
+		rankBy := rankByParam(r)
+		now := time.Now().UTC()
+
+		data := struct {
+			Name        string
+			Challenge   challengeView
+			RankBy      string
+			Leaderboard []models.LeaderboardEntry
+			Unread      int
+		}{
+			Name:        h.smokerName(username),
+			Challenge:   h.challengeView(challenge, username, now),
+			RankBy:      rankBy,
+			Leaderboard: h.challengeLeaderboard(challenge, rankBy, username, h.localizer(r), now),
+			Unread:      h.Storage.CountUnread(username),
+		}
+
+		h.render(w, r, http.StatusOK, "challenge.html", data)
+		return nil
+	})
+}
+
+// GetChallengeLeaderboard отображает таблицу лидеров челленджа в формате JSON.
+// Параметр rankBy: smoke_free (по умолчанию) или money_saved
+func (h *Handlers) GetChallengeLeaderboard() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		challenge, ok := h.Storage.GetChallenge(r.PathValue("id"))
+		if !ok {
+			return apperr.NotFound(apperr.CodeChallengeNotFound)
+		}
+
+		rankBy := rankByParam(r)
+		now := time.Now().UTC()
+
+		return writeJSON(w, http.StatusOK, struct {
+			*models.Challenge
+			Status      string                    `json:"status"`
+			RankBy      string                    `json:"rankBy"`
+			Leaderboard []models.LeaderboardEntry `json:"leaderboard"`
+		}{
+			Challenge:   challenge,
+			Status:      challenges.Status(challenge, now),
+			RankBy:      rankBy,
+			Leaderboard: h.challengeLeaderboard(challenge, rankBy, username, h.localizer(r), now),
+		})
+	})
+}
+
+// JoinChallenge добавляет курильщика в челлендж, который ещё не начался
+func (h *Handlers) JoinChallenge() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		challenge, ok := h.Storage.GetChallenge(r.PathValue("id"))
+		if !ok {
+			return apperr.NotFound(apperr.CodeChallengeNotFound)
+		}
+
+		now := time.Now().UTC()
+		if challenges.Status(challenge, now) != models.ChallengeUpcoming {
+			return apperr.New(http.StatusConflict, apperr.CodeChallengeStarted)
+		}
+
+		if !h.Storage.JoinChallenge(challenge.ID, &models.ChallengeMember{
+			Username:  username,
+			JoinedAt:  now,
+			Anonymous: r.FormValue("anonymous") == "on",
+		}) {
+			return apperr.New(http.StatusConflict, apperr.CodeAlreadyJoined)
+		}
+
+		http.Redirect(w, r, `/challenges/`+challenge.ID, http.StatusFound)
+		return nil
+	})
+}
+
+// LeaveChallenge удаляет курильщика из челленджа, который ещё не закончился
+func (h *Handlers) LeaveChallenge() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		challenge, ok := h.Storage.GetChallenge(r.PathValue("id"))
+		if !ok {
+			return apperr.NotFound(apperr.CodeChallengeNotFound)
+		}
+
+		if challenges.Status(challenge, time.Now().UTC()) == models.ChallengeFinished {
+			return apperr.New(http.StatusConflict, apperr.CodeChallengeFinished)
+		}
+
+		if !h.Storage.LeaveChallenge(challenge.ID, username) {
+			return apperr.New(http.StatusConflict, apperr.CodeNotJoined)
+		}
+		h.Chat.Evict(chat.ChallengeRoom(challenge.ID), username)
+
+		http.Redirect(w, r, `/challenges`, http.StatusFound)
+		return nil
+	})
+}
+
+func (h *Handlers) challengeView(challenge *models.Challenge, username string, now time.Time) challengeView {
+	members := h.Storage.GetChallengeMembers(challenge.ID)
+	view := challengeView{
+		Challenge: challenge,
+		Status:    challenges.Status(challenge, now),
+		Members:   len(members),
+	}
+	for _, member := range members {
+		if member.Username == username {
+			view.Joined = true
+		}
+	}
+	return view
+}
+
+// challengeLeaderboard возвращает таблицу лидеров. Для завершённого челленджа
+// итоги фиксируются при первом обращении и больше не меняются. Анонимные участники
+// подписываются на языке loc
+func (h *Handlers) challengeLeaderboard(challenge *models.Challenge, rankBy, viewer string, loc *i18n.Localizer, now time.Time) []models.LeaderboardEntry {
+	status := challenges.Status(challenge, now)
+
+	var entries []models.LeaderboardEntry
+	if results, ok := h.Storage.GetChallengeResults(challenge.ID, rankBy); ok {
+		entries = results
+	} else {
+		members := h.Storage.GetChallengeMembers(challenge.ID)
+		smokers := make(map[string]*models.Smoker, len(members))
+		prices := make(map[string][]models.PriceChange, len(members))
+		for _, member := range members {
+			if smoker, ok := h.Storage.GetSmoker(member.Username); ok {
+				smokers[member.Username] = smoker
+			}
+			prices[member.Username] = h.Storage.GetPriceHistory(member.Username)
+		}
+		entries = challenges.Leaderboard(challenge, members, smokers, prices, rankBy, now)
+		if status == models.ChallengeFinished {
+			entries = h.Storage.SetChallengeResults(challenge.ID, rankBy, entries)
+		}
+	}
+
+	// Копируем, чтобы отметка IsMe и подписи на языке зрителя не попали в зафиксированные итоги
+	marked := make([]models.LeaderboardEntry, len(entries))
+	copy(marked, entries)
+	for i := range marked {
+		marked[i].IsMe = marked[i].Username == viewer
+		if marked[i].Anonymous {
+			marked[i].DisplayName = loc.T("challenge.anonymous", marked[i].Number)
+		}
+	}
+	return marked
+}
+
+func rankByParam(r *http.Request) string {
+	if r.URL.Query().Get("rankBy") == models.RankByMoneySaved {
+		return models.RankByMoneySaved
+	}
+	return models.RankBySmokeFree
+}
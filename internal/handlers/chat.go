@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/apperr"
+	"github.com/NarthurN/QuitSmoking/internal/chat"
+	"github.com/NarthurN/QuitSmoking/internal/helpers"
+	"github.com/NarthurN/QuitSmoking/internal/middleware"
+	"github.com/NarthurN/QuitSmoking/internal/ws"
+)
+
+// GetChat открывает WebSocket-соединение для чата с напарниками и участниками челленджей.
+// Авторизация та же, что и у остальных страниц: JWT из cookie или заголовка Authorization
+func (h *Handlers) GetChat() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		conn, err := ws.Upgrade(w, r)
+		if err != nil {
+			// Отклонённое рукопожатие — обычная ошибка запроса, а после перехвата соединения отвечать уже некуда
+			var appErr *apperr.Error
+			if errors.As(err, &appErr) {
+				return err
+			}
+			h.Logger.Debug("handlers.GetChat.Upgrade", helpers.SlogDebug(err.Error()))
+			return nil
+		}
+
+		h.Chat.Serve(conn, username, h.localizer(r), h.chatSession(r))
+		return nil
+	})
+}
+
+// chatSession перепроверяет токен соединения. Истекающий токен — повод переподключиться,
+// а блокировка и отзыв закрывают чат насовсем
+func (h *Handlers) chatSession(r *http.Request) chat.Session {
+	session := h.Mw.Session(r)
+	return func(now time.Time) error {
+		err := session(now)
+		if errors.Is(err, middleware.ErrTokenExpiring) {
+			return fmt.Errorf("%w: %w", chat.ErrReconnect, err)
+		}
+		return err
+	}
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/apperr"
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/NarthurN/QuitSmoking/internal/wellbeing"
+)
+
+// GetCheckInForm отображает форму ежедневной отметки самочувствия и сводку за неделю
+func (h *Handlers) GetCheckInForm() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		smoker, ok := h.Storage.GetSmoker(username)
+		if !ok {
+			return apperr.NotFound(apperr.CodeSmokerNotFound)
+		}
+
+		checkIns := h.Storage.GetCheckIns(username)
+		summary := wellbeing.WeeklySummary(checkIns, h.Storage.GetCravings(username), smoker.StoppedSmoking, time.Now().UTC())
+		if len(checkIns) > 7 {
+			checkIns = checkIns[:7]
+		}
+
+		data := struct {
+			Name     string
+			Symptoms []string
+			CheckIns []*models.CheckIn
+			Summary  models.WeeklySummary
+			Unread   int
+		}{
+			Name:     smoker.Name,
+			Symptoms: models.WithdrawalSymptoms,
+			CheckIns: checkIns,
+			Summary:  summary,
+			Unread:   h.Storage.CountUnread(username),
+		}
+
+		h.render(w, r, http.StatusOK, "checkin.html", data)
+		return nil
+	})
+}
+
+// PostCheckIn сохраняет отметку самочувствия за сегодня
+func (h *Handlers) PostCheckIn() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		if err := r.ParseForm(); err != nil {
+			return apperr.BadRequest(apperr.CodeBadRequest)
+		}
+
+		mood, err := strconv.Atoi(r.FormValue("mood"))
+		if err != nil || mood < 1 || mood > 5 {
+			return apperr.BadRequest(apperr.CodeMoodRange, 1, 5)
+		}
+
+		stress, err := strconv.Atoi(r.FormValue("stress"))
+		if err != nil || stress < 1 || stress > 5 {
+			return apperr.BadRequest(apperr.CodeStressRange, 1, 5)
+		}
+
+		sleep, err := parseNumber(r.FormValue("sleep"))
+		if err != nil || sleep < 0 || sleep > 24 {
+			return apperr.BadRequest(apperr.CodeSleepRange, 0, 24)
+		}
+
+		symptoms := r.Form["symptoms"]
+		for _, symptom := range symptoms {
+			if !slices.Contains(models.WithdrawalSymptoms, symptom) {
+				return apperr.BadRequest(apperr.CodeUnknownSymptom)
+			}
+		}
+
+		h.Storage.SaveCheckIn(&models.CheckIn{
+			Username:   username,
+			Date:       time.Now().UTC(),
+			Mood:       mood,
+			SleepHours: sleep,
+			Stress:     stress,
+			Symptoms:   symptoms,
+			Note:       r.FormValue("note"),
+		})
+
+		http.Redirect(w, r, `/checkin`, http.StatusFound)
+		return nil
+	})
+}
+
+// GetCheckIns отображает отметки самочувствия курильщика в формате JSON
+func (h *Handlers) GetCheckIns() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		return writeJSON(w, http.StatusOK, h.Storage.GetCheckIns(username))
+	})
+}
+
+// GetWeeklySummary отображает сводку самочувствия за неделю в формате JSON
+func (h *Handlers) GetWeeklySummary() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		smoker, ok := h.Storage.GetSmoker(username)
+		if !ok {
+			return apperr.NotFound(apperr.CodeSmokerNotFound)
+		}
+
+		summary := wellbeing.WeeklySummary(h.Storage.GetCheckIns(username), h.Storage.GetCravings(username), smoker.StoppedSmoking, time.Now().UTC())
+
+		return writeJSON(w, http.StatusOK, summary)
+	})
+}
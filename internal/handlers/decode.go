@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/NarthurN/QuitSmoking/internal/apperr"
+	"github.com/NarthurN/QuitSmoking/internal/configs"
+	"github.com/NarthurN/QuitSmoking/internal/validate"
+)
+
+// decodeJSON читает тело запроса в v. Тело должно быть application/json
+// не больше configs.MaxJSONBodySize, без неизвестных полей и без данных после объекта
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	if err := requireContentType(r, "application/json"); err != nil {
+		return err
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, configs.MaxJSONBodySize)
+	return decodeStrict(r.Body, v)
+}
+
+// requireContentType проверяет, что тело запроса нужного типа
+func requireContentType(r *http.Request, want string) error {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != want {
+		return apperr.New(http.StatusUnsupportedMediaType, apperr.CodeUnsupportedMedia, want)
+	}
+	return nil
+}
+
+// decodeStrict читает из body ровно один JSON-объект без неизвестных полей
+func decodeStrict(body io.Reader, v any) error {
+	decoder := json.NewDecoder(body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return decodeError(err)
+	}
+	if err := decoder.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		if err == nil {
+			err = errors.New("handlers.decodeStrict: trailing data after object")
+		}
+		return decodeError(err)
+	}
+	return nil
+}
+
+// decodeError объясняет ошибку разбора JSON: по возможности указывает поле
+func decodeError(err error) error {
+	var (
+		maxBytesErr *http.MaxBytesError
+		typeErr     *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return apperr.New(http.StatusRequestEntityTooLarge, apperr.CodeBodyTooLarge).Wrap(err)
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return apperr.BadRequest(apperr.CodeInvalidJSON).
+			WithFields(apperr.FieldError{Field: typeErr.Field, Code: validate.CodeType}).Wrap(err)
+	}
+
+	// У encoding/json нет отдельного типа для неизвестного поля, только текст ошибки
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return apperr.BadRequest(apperr.CodeInvalidJSON).
+			WithFields(apperr.FieldError{Field: strings.Trim(field, `"`), Code: validate.CodeUnknownField}).Wrap(err)
+	}
+	return apperr.BadRequest(apperr.CodeInvalidJSON).Wrap(err)
+}
+
+// parseNumber читает число из поля формы. Допускает запятую вместо точки и отклоняет
+// NaN и бесконечность: strconv.ParseFloat их принимает, а в JSON их не записать
+func parseNumber(raw string) (float64, error) {
+	value, err := strconv.ParseFloat(strings.ReplaceAll(raw, ",", "."), 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("handlers.parseNumber: %q is not a finite number", raw)
+	}
+	return value, nil
+}
+
+// invalid превращает нарушения правил из validate в ответ 422 с ошибками по полям
+func invalid(err error) error {
+	var violations validate.Errors
+	if !errors.As(err, &violations) {
+		return err
+	}
+
+	fields := make([]apperr.FieldError, 0, len(violations))
+	for _, v := range violations {
+		fields = append(fields, apperr.FieldError{Field: v.Field, Code: v.Code, Args: v.Args})
+	}
+	return apperr.New(http.StatusUnprocessableEntity, apperr.CodeValidation).WithFields(fields...).Wrap(err)
+}
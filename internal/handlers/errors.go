@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/NarthurN/QuitSmoking/internal/apperr"
+	"github.com/NarthurN/QuitSmoking/internal/helpers"
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+// handle превращает обработчик, который возвращает ошибку, в http.HandlerFunc.
+// Обработчик до ошибки ничего не должен писать в ответ: ответ с ошибкой пишет h.fail
+func (h *Handlers) handle(fn func(w http.ResponseWriter, r *http.Request) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := fn(w, r); err != nil {
+			h.fail(w, r, err)
+		}
+	}
+}
+
+// fail отвечает на ошибку: браузеру — HTML-страницей, остальным — application/problem+json.
+// Ошибки сервера пишутся в лог вместе с причиной, а клиент видит только общий текст
+func (h *Handlers) fail(w http.ResponseWriter, r *http.Request, err error) {
+	appErr := apperr.From(err)
+	if appErr.Status >= http.StatusInternalServerError {
+		h.Logger.Error("handlers.fail", slog.String("path", r.URL.Path), helpers.SlogErr(err))
+	} else {
+		h.Logger.Debug("handlers.fail", slog.String("path", r.URL.Path), slog.String("code", appErr.Code))
+	}
+
+	loc := h.localizer(r)
+	problem := appErr.Problem(loc, r.URL.Path)
+	if !wantsHTML(r) {
+		apperr.WriteProblem(w, loc, problem)
+		return
+	}
+
+	data := struct {
+		Name   string
+		Unread int
+		apperr.Problem
+	}{
+		Problem: problem,
+	}
+	if username, ok := r.Context().Value(models.ContextString("smoker.name")).(string); ok {
+		if smoker, ok := h.Storage.GetSmoker(username); ok {
+			data.Name = smoker.Name
+			data.Unread = h.Storage.CountUnread(username)
+		}
+	}
+	if err := h.Templates.Render(w, appErr.Status, loc.Locale(), "error.html", data); err != nil {
+		h.Logger.Error("handlers.fail.Render", helpers.SlogErr(err))
+		http.Error(w, data.Detail, appErr.Status)
+	}
+}
+
+// wantsHTML сообщает, что запрос пришёл из браузера при переходе по ссылке или отправке формы.
+// Такие запросы просят text/html, а fetch из скриптов — JSON или что угодно
+func wantsHTML(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "text/html", "application/xhtml+xml":
+			return true
+		case "application/json", apperr.ProblemContentType:
+			return false
+		}
+	}
+	return false
+}
+
+// currentUser username курильщика, которого пропустил middleware.JwtAuth
+func currentUser(r *http.Request) (string, error) {
+	username, ok := r.Context().Value(models.ContextString("smoker.name")).(string)
+	if !ok {
+		return "", apperr.Internal(errors.New("handlers.currentUser: smoker name is not in context"))
+	}
+	return username, nil
+}
+
+// writeJSON отвечает v в формате JSON. Если v не сериализуется, в ответ ничего не пишет
+func writeJSON(w http.ResponseWriter, status int, v any) error {
+	const op = "handlers.writeJSON"
+
+	body, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(body)
+	return nil
+}
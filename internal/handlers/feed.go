@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/NarthurN/QuitSmoking/internal/apperr"
+	"github.com/NarthurN/QuitSmoking/internal/configs"
+	"github.com/NarthurN/QuitSmoking/internal/feed"
+	"github.com/NarthurN/QuitSmoking/internal/helpers"
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+type reactionCount struct {
+	Kind  string
+	Count int
+	Mine  bool
+}
+
+type feedPost struct {
+	*models.Post
+	Moderator bool
+	Counts    []reactionCount
+}
+
+type feedThread struct {
+	Post    feedPost
+	Replies []feedPost
+}
+
+func newFeedPost(post *models.Post, viewer string, moderator bool) feedPost {
+	view := feedPost{Post: post, Moderator: moderator}
+	for _, kind := range models.Reactions {
+		view.Counts = append(view.Counts, reactionCount{
+			Kind:  kind,
+			Count: len(post.Reactions[kind]),
+			Mine:  slices.Contains(post.Reactions[kind], viewer),
+		})
+	}
+	return view
+}
+
+// GetFeedPage отображает ленту сообщества.
+// Посты пишут пользователи, поэтому страница собирается через html/template, который экранирует разметку
+func (h *Handlers) GetFeedPage() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		moderator := helpers.HasPermission(username, configs.ModeratePermission)
+		_, banned := h.Storage.GetBan(username)
+
+		var reports []*models.PostReport
+		if moderator {
+			reports = h.Storage.GetReports()
+		}
+
+		threads := feed.Threads(h.Storage.GetPosts(), moderator)
+		views := make([]feedThread, 0, len(threads))
+		for _, thread := range threads {
+			view := feedThread{Post: newFeedPost(thread.Post, username, moderator)}
+			for _, reply := range thread.Replies {
+				view.Replies = append(view.Replies, newFeedPost(reply, username, moderator))
+			}
+			views = append(views, view)
+		}
+
+		data := struct {
+			Name      string
+			Moderator bool
+			Banned    bool
+			Threads   []feedThread
+			Reports   []*models.PostReport
+			Unread    int
+		}{
+			Name:      h.smokerName(username),
+			Moderator: moderator,
+			Banned:    banned,
+			Threads:   views,
+			Reports:   reports,
+			Unread:    h.Storage.CountUnread(username),
+		}
+
+		h.render(w, r, http.StatusOK, "feed.html", data)
+		return nil
+	})
+}
+
+// GetFeed отображает ленту сообщества в формате JSON
+func (h *Handlers) GetFeed() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		moderator := helpers.HasPermission(username, configs.ModeratePermission)
+
+		return writeJSON(w, http.StatusOK, feed.Threads(h.Storage.GetPosts(), moderator))
+	})
+}
+
+// PostFeedPost публикует пост или ответ на пост (parentId)
+func (h *Handlers) PostFeedPost() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		if _, banned := h.Storage.GetBan(username); banned {
+			return apperr.New(http.StatusForbidden, apperr.CodeBanned)
+		}
+
+		text := strings.TrimSpace(r.FormValue("text"))
+		if text == "" || utf8.RuneCountInString(text) > feed.MaxPostLength {
+			return apperr.BadRequest(apperr.CodePostLength, feed.MaxPostLength)
+		}
+
+		parentID := r.FormValue("parentId")
+		var parentAuthor string
+		if parentID != "" {
+			parent, ok := h.Storage.GetPost(parentID)
+			if !ok || parent.Hidden {
+				return apperr.NotFound(apperr.CodePostNotFound)
+			}
+			// Ответы только на посты верхнего уровня, без вложенных веток
+			if parent.ParentID != "" {
+				parentID = parent.ParentID
+			}
+			parentAuthor = parent.Author
+		}
+
+		h.Storage.AddPost(&models.Post{
+			ParentID:  parentID,
+			Author:    username,
+			Text:      text,
+			CreatedAt: time.Now().UTC(),
+		})
+		if parentAuthor != "" && parentAuthor != username {
+			h.Inbox.Post(parentAuthor, models.InboxPostReply, h.localizerFor(parentAuthor).T("inbox.post_reply.title", username), text, "/feed")
+		}
+
+		http.Redirect(w, r, `/feed`, http.StatusFound)
+		return nil
+	})
+}
+
+// PostReaction ставит или снимает реакцию на пост
+func (h *Handlers) PostReaction() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		if _, banned := h.Storage.GetBan(username); banned {
+			return apperr.New(http.StatusForbidden, apperr.CodeBanned)
+		}
+
+		kind := r.FormValue("kind")
+		if !slices.Contains(models.Reactions, kind) {
+			return apperr.BadRequest(apperr.CodeUnknownReaction)
+		}
+
+		if !h.Storage.ToggleReaction(r.PathValue("id"), kind, username) {
+			return apperr.NotFound(apperr.CodePostNotFound)
+		}
+
+		http.Redirect(w, r, `/feed`, http.StatusFound)
+		return nil
+	})
+}
+
+// PostReport отправляет жалобу на пост модераторам
+func (h *Handlers) PostReport() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		id := r.PathValue("id")
+		if _, ok := h.Storage.GetPost(id); !ok {
+			return apperr.NotFound(apperr.CodePostNotFound)
+		}
+
+		reason := strings.TrimSpace(r.FormValue("reason"))
+		if utf8.RuneCountInString(reason) > feed.MaxReportReasonLength {
+			return apperr.BadRequest(apperr.CodeReportReasonLength, feed.MaxReportReasonLength)
+		}
+
+		h.Storage.AddReport(&models.PostReport{
+			PostID:    id,
+			Reporter:  username,
+			Reason:    reason,
+			CreatedAt: time.Now().UTC(),
+		})
+
+		http.Redirect(w, r, `/feed`, http.StatusFound)
+		return nil
+	})
+}
+
+// HidePost скрывает пост из ленты. Доступно модераторам
+func (h *Handlers) HidePost() http.HandlerFunc {
+	return h.setPostHidden("handlers.HidePost", true)
+}
+
+// UnhidePost возвращает скрытый пост в ленту. Доступно модераторам
+func (h *Handlers) UnhidePost() http.HandlerFunc {
+	return h.setPostHidden("handlers.UnhidePost", false)
+}
+
+func (h *Handlers) setPostHidden(op string, hidden bool) http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		if !helpers.HasPermission(username, configs.ModeratePermission) {
+			h.Logger.Debug(op+".HasPermission", helpers.SlogDebug("permition denied"))
+			return apperr.New(http.StatusForbidden, apperr.CodeForbidden)
+		}
+
+		if !h.Storage.SetPostHidden(r.PathValue("id"), hidden, username) {
+			return apperr.NotFound(apperr.CodePostNotFound)
+		}
+
+		http.Redirect(w, r, `/feed`, http.StatusFound)
+		return nil
+	})
+}
+
+// PostBan блокирует пользователя в сообществе. Доступно модераторам
+func (h *Handlers) PostBan() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		if !helpers.HasPermission(username, configs.ModeratePermission) {
+			h.Logger.Debug("handlers.PostBan.HasPermission", helpers.SlogDebug("permition denied"))
+			return apperr.New(http.StatusForbidden, apperr.CodeForbidden)
+		}
+
+		target := strings.TrimSpace(r.FormValue("username"))
+		if _, ok := h.Storage.GetSmoker(target); !ok {
+			return apperr.BadRequest(apperr.CodeUnknownUser)
+		}
+		// Модератора может заблокировать только администратор
+		if helpers.HasPermission(target, configs.ModeratePermission) && !helpers.HasPermission(username, configs.AdminPermission) {
+			return apperr.New(http.StatusForbidden, apperr.CodeForbidden)
+		}
+
+		h.Storage.BanUser(&models.Ban{
+			Username:  target,
+			By:        username,
+			Reason:    strings.TrimSpace(r.FormValue("reason")),
+			CreatedAt: time.Now().UTC(),
+		})
+
+		http.Redirect(w, r, `/feed`, http.StatusFound)
+		return nil
+	})
+}
+
+// DeleteBan снимает блокировку пользователя в сообществе. Доступно модераторам
+func (h *Handlers) DeleteBan() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		if !helpers.HasPermission(username, configs.ModeratePermission) {
+			h.Logger.Debug("handlers.DeleteBan.HasPermission", helpers.SlogDebug("permition denied"))
+			return apperr.New(http.StatusForbidden, apperr.CodeForbidden)
+		}
+
+		if !h.Storage.UnbanUser(r.PathValue("username")) {
+			return apperr.NotFound(apperr.CodeNotBanned)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	})
+}
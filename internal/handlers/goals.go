@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/NarthurN/QuitSmoking/internal/apperr"
+	"github.com/NarthurN/QuitSmoking/internal/goals"
+	"github.com/NarthurN/QuitSmoking/internal/helpers"
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/NarthurN/QuitSmoking/internal/validate"
+)
+
+// Самое длинное название цели
+const maxGoalTitleLength = 100
+
+// PostGoal создаёт личную цель курильщика
+func (h *Handlers) PostGoal() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		title := strings.TrimSpace(r.FormValue("title"))
+		if title == "" {
+			return apperr.BadRequest(apperr.CodeGoalTitle)
+		}
+		if utf8.RuneCountInString(title) > maxGoalTitleLength {
+			return apperr.BadRequest(apperr.CodeGoalTitleLength, maxGoalTitleLength)
+		}
+
+		kind := r.FormValue("kind")
+		if kind != models.GoalKindMoney && kind != models.GoalKindDays {
+			return apperr.BadRequest(apperr.CodeUnknownGoalKind)
+		}
+
+		// Разрешаем ввод вида "40 000" и "40000,50"
+		rawTarget := strings.NewReplacer(" ", "", "\u00a0", "").Replace(r.FormValue("target"))
+		target, err := parseNumber(rawTarget)
+		if err != nil || target <= 0 {
+			return apperr.BadRequest(apperr.CodeGoalTarget)
+		}
+
+		now := time.Now().UTC()
+		goal := &models.Goal{
+			Username:  username,
+			Title:     title,
+			Kind:      kind,
+			Target:    target,
+			CreatedAt: now,
+		}
+
+		if rawDeadline := r.FormValue("deadline"); rawDeadline != "" {
+			deadline, err := time.Parse(time.DateOnly, rawDeadline)
+			if err != nil {
+				return apperr.BadRequest(apperr.CodeInvalidDate).
+					WithFields(apperr.FieldError{Field: "deadline", Code: validate.CodeInvalid})
+			}
+			// Цель считается проваленной только после окончания последнего дня
+			deadline = deadline.Add(24*time.Hour - time.Nanosecond)
+			if deadline.Before(now) {
+				return apperr.BadRequest(apperr.CodeDeadlinePast).
+					WithFields(apperr.FieldError{Field: "deadline", Code: validate.CodeTooEarly})
+			}
+			goal.Deadline = &deadline
+		}
+
+		h.Storage.AddGoal(goal)
+
+		http.Redirect(w, r, `/profile`, http.StatusFound)
+		return nil
+	})
+}
+
+// GetGoals отображает цели курильщика с прогрессом в формате JSON
+func (h *Handlers) GetGoals() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		smoker, ok := h.Storage.GetSmoker(username)
+		if !ok {
+			return apperr.NotFound(apperr.CodeSmokerNotFound)
+		}
+
+		stats := helpers.GoalStats(smoker, h.Storage.GetPriceHistory(username))
+
+		return writeJSON(w, http.StatusOK, goals.Track(h.Storage, username, stats))
+	})
+}
+
+// DeleteGoal удаляет цель курильщика по id
+func (h *Handlers) DeleteGoal() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		id := r.PathValue("id")
+		if !h.Storage.DeleteGoal(username, id) {
+			return apperr.NotFound(apperr.CodeGoalNotFound)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	})
+}
@@ -2,50 +2,131 @@ package handlers
 
 import (
 	"database/sql"
-	"encoding/json"
+	"fmt"
+	"io/fs"
 	"log/slog"
 	"net/http"
-	"text/template"
+	"strings"
 	"time"
 
+	"github.com/NarthurN/QuitSmoking/internal/achievements"
+	"github.com/NarthurN/QuitSmoking/internal/apperr"
+	"github.com/NarthurN/QuitSmoking/internal/audit"
+	"github.com/NarthurN/QuitSmoking/internal/chat"
+	"github.com/NarthurN/QuitSmoking/internal/configs"
+	"github.com/NarthurN/QuitSmoking/internal/goals"
 	"github.com/NarthurN/QuitSmoking/internal/helpers"
+	"github.com/NarthurN/QuitSmoking/internal/i18n"
+	"github.com/NarthurN/QuitSmoking/internal/inbox"
+	"github.com/NarthurN/QuitSmoking/internal/media"
 	"github.com/NarthurN/QuitSmoking/internal/middleware"
 	"github.com/NarthurN/QuitSmoking/internal/mocks"
 	"github.com/NarthurN/QuitSmoking/internal/models"
-	"github.com/go-chi/chi/v5"
+	"github.com/NarthurN/QuitSmoking/internal/nrt"
+	"github.com/NarthurN/QuitSmoking/internal/reduction"
+	"github.com/NarthurN/QuitSmoking/internal/render"
+	"github.com/NarthurN/QuitSmoking/internal/sse"
+	"github.com/NarthurN/QuitSmoking/internal/storage"
+	"github.com/NarthurN/QuitSmoking/internal/webpush"
+	"github.com/NarthurN/QuitSmoking/static"
 )
 
 type Handlers struct {
-	db     *sql.DB
-	Logger *slog.Logger
-	Mw     *middleware.Middleware
+	db      *sql.DB
+	Logger  *slog.Logger
+	Mw      *middleware.Middleware
+	Storage *storage.Storage
+	// Audit журнал аудита с защитой от наводнения событиями без исполнителя
+	Audit        audit.Recorder
+	Achievements *achievements.Engine
+	Inbox        *inbox.Inbox
+	VAPID        *webpush.VAPID
+	Live         *sse.Limiter
+	Chat         *chat.Hub
+	Media        media.Store
+	Assets       fs.FS
+	Templates    *render.Templates
+	I18n         *i18n.Bundle
+	// Dev режим разработки: разрешает локальный push-сервис
+	Dev bool
 }
 
 func New(db *sql.DB, logger *slog.Logger) *Handlers {
-	return &Handlers{
-		db:     db,
-		Logger: logger,
-		Mw:     middleware.New(logger, helpers.NewTokener()),
+	store := storage.New()
+	for _, smoker := range mocks.Smokers {
+		store.AddSmoker(smoker)
 	}
+	bundle := i18n.Default()
+	tokener := helpers.NewTokener()
+	tokener.Generation = store.TokenGeneration
+	mw := middleware.New(logger, tokener)
+	mw.Accounts = store
+	auditLog := audit.NewThrottle(store, configs.AuditBurst, configs.AuditWindow)
+	mw.Audit = auditLog
+	h := &Handlers{
+		db:           db,
+		Logger:       logger,
+		Mw:           mw,
+		Storage:      store,
+		Audit:        auditLog,
+		Achievements: achievements.New(nil),
+		Inbox:        inbox.New(store),
+		Live:         sse.NewLimiter(configs.LiveMaxConnections, configs.LiveMaxPerUser),
+		Chat:         chat.New(store, logger),
+		Media:        media.NewMemory(),
+		Assets:       static.FS(false, ""),
+		Templates:    render.Must(render.New(static.FS(false, ""), false, bundle)),
+		I18n:         bundle,
+	}
+	mw.Fail = h.fail
+	return h
+}
+
+// smokerName имя курильщика для страниц. Если курильщика уже нет, показываем username
+func (h *Handlers) smokerName(username string) string {
+	if smoker, ok := h.Storage.GetSmoker(username); ok {
+		return smoker.Name
+	}
+	return username
+}
+
+// localizer выбирает язык запроса. Настройка курильщика важнее языка браузера
+func (h *Handlers) localizer(r *http.Request) *i18n.Localizer {
+	var preferred string
+	if username, ok := r.Context().Value(models.ContextString("smoker.name")).(string); ok {
+		preferred, _ = h.Storage.GetLanguage(username)
+	}
+	return h.I18n.FromRequest(r, preferred)
 }
 
-// Home отображает стартовую страницу
+// localizerFor выбирает язык курильщика, которому адресован текст, например запись во входящих
+func (h *Handlers) localizerFor(username string) *i18n.Localizer {
+	locale, _ := h.Storage.GetLanguage(username)
+	return h.I18n.Localizer(locale)
+}
+
+// render отрисовывает страницу на языке запроса, а если шаблон не выполнился, отвечает ошибкой сервера
+func (h *Handlers) render(w http.ResponseWriter, r *http.Request, status int, name string, data any) {
+	if err := h.Templates.Render(w, status, h.localizer(r).Locale(), name, data); err != nil {
+		h.fail(w, r, fmt.Errorf("handlers.render: %w", err))
+	}
+}
+
+// Home отображает стартовую страницу. Шаблон "GET /" совпадает с любым путём,
+// поэтому на неизвестные адреса отвечает 404
 func (h *Handlers) Home() http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		tmpl, err := template.ParseFiles("static/templates/index.html")
-		if err != nil {
-			h.Logger.Error("handlers.Home.ParseFIles", helpers.SlogErr(err))
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-			return
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		if r.URL.Path != "/" {
+			return apperr.NotFound(apperr.CodeNotFound)
 		}
-		tmpl.Execute(w, nil)
-	}
+		h.render(w, r, http.StatusOK, "index.html", nil)
+		return nil
+	})
 }
 
 // Signin записывает JWT-токен в заголовок Authorization и проверяет корректность username и password
 func (h *Handlers) Signin() http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
 		username := r.FormValue("username")
 		password := r.FormValue("password")
 
@@ -53,88 +134,74 @@ func (h *Handlers) Signin() http.HandlerFunc {
 		creds.Username = username
 		creds.Password = password
 
-		smoker, ok := mocks.Smokers[creds.Username]
+		smoker, ok := h.Storage.GetSmoker(creds.Username)
 		if !ok {
-			h.Logger.Debug("handlers.Signin.CheckSmokerInBase", helpers.SlogDebug("smoker in base not found"))
-			http.Error(w, "Пользователя с таким username не существует", http.StatusBadRequest)
-			return
+			h.audit(r, "", models.AuditSigninFailure, creds.Username, apperr.CodeUnknownUser)
+			return apperr.BadRequest(apperr.CodeUnknownUser)
 		}
 
 		expectedPassword := creds.Password
 		if expectedPassword != smoker.Password {
-			h.Logger.Debug("handlers.Signin.CheckPasswordOfSmokerInBase", helpers.SlogDebug("password of smoker isn't correct"))
-			http.Error(w, "Пароль неверный", http.StatusUnauthorized)
-			return
+			h.audit(r, "", models.AuditSigninFailure, creds.Username, apperr.CodeWrongPassword)
+			return apperr.New(http.StatusUnauthorized, apperr.CodeWrongPassword)
+		}
+		if h.Storage.IsLocked(creds.Username) {
+			h.audit(r, "", models.AuditSigninFailure, creds.Username, apperr.CodeAccountLocked)
+			return apperr.New(http.StatusForbidden, apperr.CodeAccountLocked)
 		}
 
 		tokenString, err := h.Mw.Tokener.GetJwtToken(creds.Username)
 		if err != nil {
-			h.Logger.Error("handlers.Signin.GetJwtToken", helpers.SlogErr(err))
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-			return
+			return fmt.Errorf("handlers.Signin.GetJwtToken: %w", err)
 		}
 
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-
 		http.SetCookie(w, &http.Cookie{
 			Name:    "token",
 			Value:   "Bearer " + tokenString,
 			Expires: time.Now().UTC().Add(5 * time.Minute),
 			Path:    "/",
 		})
+		h.audit(r, creds.Username, models.AuditSigninSuccess, creds.Username, "")
 
-		w.WriteHeader(http.StatusOK)
-		tmpl, err := template.ParseFiles("static/templates/signin.html")
-		if err != nil {
-			h.Logger.Error("handlers.Signin.ParseFIles", helpers.SlogErr(err))
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-			return
+		data := struct {
+			Name   string
+			Unread int
+		}{
+			Name:   smoker.Name,
+			Unread: h.Storage.CountUnread(smoker.Username),
 		}
-
-		tmpl.Execute(w, smoker)
-	}
+		h.render(w, r, http.StatusOK, "signin.html", data)
+		return nil
+	})
 }
 
 func (h *Handlers) Logout() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		// /logout открыт без токена, поэтому кто выходит, узнаём из cookie сами
+		if cookie, err := r.Cookie("token"); err == nil {
+			if claims, err := h.Mw.Tokener.VerifyUser(strings.TrimPrefix(cookie.Value, "Bearer ")); err == nil {
+				event := audit.Event(r, claims.Username, models.AuditLogout, claims.Username)
+				event.Impersonator = claims.Impersonator
+				h.Storage.AddAuditEvent(event)
+			}
+		}
+
 		http.SetCookie(w, &http.Cookie{
 			Name:    "token",
 			Value:   "",
-			Path:     "/",
+			Path:    "/",
 			Expires: time.Now(),
-			MaxAge:   -1,
+			MaxAge:  -1,
 		})
 		http.Redirect(w, r, "/", http.StatusFound)
 	}
 }
 
-// GetSmokers отображает всех Smokers в формате JSON
-func (h *Handlers) GetSmokers() http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		smokers, err := json.Marshal(&mocks.Smokers)
-		if err != nil {
-			h.Logger.Error("handlers.GetSmokers.Marshal", helpers.SlogErr(err))
-			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
-			return
-		}
-
-		w.Header().Set("Content-Type", "application/json;charset=utf-8")
-		w.WriteHeader(http.StatusOK)
-		w.Write(smokers)
-	}
-}
-
 func (h *Handlers) GetForm() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		_, err := r.Cookie("token")
 		if err != nil {
-			tmpl, err := template.ParseFiles("static/templates/form.html")
-			if err != nil {
-				h.Logger.Error("handlers.GetForm.ParseFIles", helpers.SlogErr(err))
-				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-				return
-			}
-			tmpl.Execute(w, nil)
+			h.render(w, r, http.StatusOK, "form.html", nil)
 			return
 		}
 		http.Redirect(w, r, `/profile`, http.StatusFound)
@@ -143,105 +210,75 @@ func (h *Handlers) GetForm() http.HandlerFunc {
 
 // GetSmokerProfile отображает данные одного Smoker по его id
 func (h *Handlers) GetSmokerProfile() http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
 
-		username, ok := r.Context().Value(models.ContextString("smoker.name")).(string)
+		smoker, ok := h.Storage.GetSmoker(username)
 		if !ok {
-			h.Logger.Error("handlers.GetSmokerProfile.ctxNameToString")
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-			return
+			return apperr.NotFound(apperr.CodeSmokerNotFound)
 		}
+		timeNotSmoke := helpers.GetSmokersDiffTime(smoker, h.localizer(r))
+
+		prices := h.Storage.GetPriceHistory(username)
+		stats := helpers.GetSmokerStats(smoker, h.Storage.GetCravings(username), prices)
+		h.Achievements.Award(h.Storage, username, stats)
 
-		smoker := mocks.Smokers[username]
-		timeNotSmoke := helpers.GetSmokersDiffTime(smoker)
+		now := time.Now().UTC()
+		plan := h.Storage.GetReductionPlan(username)
+		if !reduction.Active(plan, now) {
+			plan = nil
+		}
+		today, _ := reduction.Today(plan, h.Storage.GetSmokedByDay(username), now)
+
+		var nrtStatuses []models.NRTStatus
+		for _, product := range h.Storage.GetNRTProducts(username) {
+			nrtStatuses = append(nrtStatuses, nrt.Status(product, now))
+		}
 
-		data := struct{
-			Name string
+		data := struct {
+			Name         string
 			TimeNotSmoke string
+			MoneySaved   float64
+			Currency     string
+			Currencies   []string
+			PackPrice    float64
+			Prices       []models.PriceChange
+			Achievements []*models.Achievement
+			Goals        []models.GoalProgress
+			Reduction    *models.ReductionPlan
+			Today        models.DailyAllowance
+			NRT          []models.NRTStatus
+			Reasons      []*models.Reason
+			Motivation   *models.MotivationPick
+			StreakDays   int
+			Unread       int
+			IsAdmin      bool
+			Impersonator string
 		}{
-			Name: smoker.Name,
+			Name:         smoker.Name,
 			TimeNotSmoke: timeNotSmoke,
+			MoneySaved:   stats.MoneySaved,
+			Currency:     h.Storage.GetCurrency(username),
+			Currencies:   i18n.Currencies(),
+			PackPrice:    helpers.GetPackPrice(smoker, prices, now),
+			Prices:       prices,
+			Achievements: achievements.Localized(h.localizer(r), h.Storage.GetAchievements(username)),
+			Goals:        goals.Track(h.Storage, username, helpers.GoalStats(smoker, prices)),
+			Reduction:    plan,
+			Today:        today,
+			NRT:          nrtStatuses,
+			Reasons:      h.Storage.GetReasons(username),
+			Motivation:   h.pickMotivation(smoker),
+			StreakDays:   stats.DaysSmokeFree,
+			Unread:       h.Storage.CountUnread(username),
+			IsAdmin:      helpers.HasRole(username, configs.AdminRole),
 		}
-		w.WriteHeader(http.StatusOK)
-		tmpl, err := template.ParseFiles("static/templates/profile.html")
-		if err != nil {
-			h.Logger.Error("handlers.GetSmokerProfile.ParseFIles", helpers.SlogErr(err))
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-			return
-		}
-		tmpl.Execute(w, data)
-	}
-}
-
-// PostSmoker создаёт нового Smoker
-func PostSmoker(w http.ResponseWriter, r *http.Request) {
-	var smoker models.Smoker
-
-	if err := json.NewDecoder(r.Body).Decode(&smoker); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	if _, ok := mocks.Smokers[smoker.ID]; ok {
-		http.Error(w, "Такой курильщик уже существует", http.StatusBadRequest)
-		return
-	}
-
-	mocks.Smokers[smoker.ID] = &smoker
-
-	message := map[string]string{"message": "Пользователь записан", "id": smoker.ID}
-	w.Header().Set("Content-Type", "application/json;charset=utf-8")
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(message); err != nil {
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
-		return
-	}
-}
-
-// DeleteSmoker удаляет Smoker по id
-func DeleteSmoker(w http.ResponseWriter, r *http.Request) {
-	id := chi.URLParam(r, "id")
-	if _, ok := mocks.Smokers[id]; !ok {
-		http.Error(w, "Такого курильщика не существует", http.StatusBadRequest)
-		return
-	}
-
-	delete(mocks.Smokers, id)
-
-	message := map[string]string{"message": "Пользователь удалён", "id": id}
-	w.Header().Set("Content-Type", "application/json;charset=utf-8")
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(message); err != nil {
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
-		return
-	}
-}
-
-// PutSmoker обновляет данные курильщика по id
-func PutSmoker(w http.ResponseWriter, r *http.Request) {
-	id := chi.URLParam(r, "id")
-	if _, ok := mocks.Smokers[id]; !ok {
-		http.Error(w, "Такого курильщика не существует", http.StatusBadRequest)
-		return
-	}
-
-	var smoker models.Smoker
-
-	if err := json.NewDecoder(r.Body).Decode(&smoker); err != nil {
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
-		return
-	}
-
-	mocks.Smokers[id].ID = smoker.ID
-	mocks.Smokers[id].Name = smoker.Name
-	mocks.Smokers[id].StoppedSmoking = smoker.StoppedSmoking
-
-	message := map[string]string{"message": "Данные пользователя изменены", "id": id}
-	w.Header().Set("Content-Type", "application/json;charset=utf-8")
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(message); err != nil {
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
-		return
-	}
+		// Администратор, который вошёл от имени курильщика, видит, как вернуться к себе
+		data.Impersonator, _ = r.Context().Value(models.ContextString("smoker.impersonator")).(string)
+		h.render(w, r, http.StatusOK, "profile.html", data)
+		return nil
+	})
 }
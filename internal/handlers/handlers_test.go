@@ -1,11 +1,27 @@
 package handlers
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/NarthurN/QuitSmoking/internal/apperr"
+	"github.com/NarthurN/QuitSmoking/internal/configs"
+	"github.com/NarthurN/QuitSmoking/internal/feed"
+	"github.com/NarthurN/QuitSmoking/internal/helpers"
+	"github.com/NarthurN/QuitSmoking/internal/mocks"
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/NarthurN/QuitSmoking/internal/nrt"
+	"github.com/NarthurN/QuitSmoking/internal/validate"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -17,7 +33,7 @@ func TestHomeWhenOk(t *testing.T) {
 	handler := http.HandlerFunc(h.Home())
 	handler.ServeHTTP(responseRecorder, r)
 
-	assert.Equal(t, responseRecorder.Code, http.StatusInternalServerError)
+	assert.Equal(t, responseRecorder.Code, http.StatusOK)
 
 	body := responseRecorder.Body.String()
 	assert.NotEmpty(t, body)
@@ -33,4 +49,924 @@ func TestGetSmokersWhenOk(t *testing.T) {
 
 	assert.Equal(t, responseRecorder.Code, http.StatusOK)
 
+	var response struct {
+		Items []struct {
+			Username string   `json:"username"`
+			Password string   `json:"password"`
+			Roles    []string `json:"roles"`
+		} `json:"items"`
+		Total      int    `json:"total"`
+		All        int    `json:"all"`
+		NextCursor string `json:"nextCursor"`
+	}
+	assert.NoError(t, json.Unmarshal(responseRecorder.Body.Bytes(), &response))
+	assert.Equal(t, len(mocks.Smokers), response.All)
+	assert.Equal(t, response.All, response.Total)
+	for _, item := range response.Items {
+		assert.Empty(t, item.Password)
+		assert.NotEmpty(t, item.Roles)
+	}
+}
+
+func TestGetSmokersFilterAndPages(t *testing.T) {
+	h := New(nil, slog.Default())
+	get := func(query string) *httptest.ResponseRecorder {
+		responseRecorder := httptest.NewRecorder()
+		h.GetSmokers().ServeHTTP(responseRecorder, httptest.NewRequest("GET", "/smokers?"+query, nil))
+		return responseRecorder
+	}
+
+	// victorCool бросил раньше, поэтому не курит дольше
+	responseRecorder := get("sort=-smokeFree&limit=1")
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	var page struct {
+		Items []struct {
+			Username string `json:"username"`
+		} `json:"items"`
+		Total      int    `json:"total"`
+		NextCursor string `json:"nextCursor"`
+	}
+	assert.NoError(t, json.Unmarshal(responseRecorder.Body.Bytes(), &page))
+	assert.Equal(t, "victorCool", page.Items[0].Username)
+	assert.NotEmpty(t, page.NextCursor)
+
+	responseRecorder = get("sort=-smokeFree&limit=1&cursor=" + page.NextCursor)
+	assert.NoError(t, json.Unmarshal(responseRecorder.Body.Bytes(), &page))
+	assert.Equal(t, "arthurCool", page.Items[0].Username)
+
+	responseRecorder = get("role=moderator&quitFrom=2024-01-15&quitTo=2024-01-15&q=VIC")
+	assert.NoError(t, json.Unmarshal(responseRecorder.Body.Bytes(), &page))
+	assert.Equal(t, 1, page.Total)
+	assert.Equal(t, "victorCool", page.Items[0].Username)
+}
+
+func TestGetSmokersInvalidQuery(t *testing.T) {
+	h := New(nil, slog.Default())
+	r := httptest.NewRequest("GET", "/smokers?sort=name&limit=1000&role=root&quitFrom=yesterday", nil)
+	responseRecorder := httptest.NewRecorder()
+	h.GetSmokers().ServeHTTP(responseRecorder, r)
+
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	var problem apperr.Problem
+	assert.NoError(t, json.Unmarshal(responseRecorder.Body.Bytes(), &problem))
+	assert.Equal(t, apperr.CodeInvalidQuery, problem.Code)
+
+	fields := map[string]string{}
+	for _, field := range problem.Errors {
+		fields[field.Field] = field.Code
+	}
+	assert.Equal(t, map[string]string{
+		"quitFrom": validate.CodeInvalid,
+		"role":     validate.CodeInvalid,
+		"sort":     validate.CodeInvalid,
+		"limit":    validate.CodeRange,
+	}, fields)
+}
+
+func TestSigninUnknownUserProblem(t *testing.T) {
+	r := httptest.NewRequest("POST", "/signin", strings.NewReader("username=nobody&password=x"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("Accept-Language", "en")
+	h := New(nil, slog.Default())
+
+	responseRecorder := httptest.NewRecorder()
+	h.Signin().ServeHTTP(responseRecorder, r)
+
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	assert.Equal(t, "application/problem+json;charset=utf-8", responseRecorder.Header().Get("Content-Type"))
+
+	var problem apperr.Problem
+	assert.NoError(t, json.Unmarshal(responseRecorder.Body.Bytes(), &problem))
+	assert.Equal(t, apperr.CodeUnknownUser, problem.Code)
+	assert.Equal(t, "No user with this username", problem.Detail)
+	assert.Equal(t, "/signin", problem.Instance)
+}
+
+func TestPostBuddyInviteProblem(t *testing.T) {
+	h := New(nil, slog.Default())
+	r := httptest.NewRequest("POST", "/buddies/invite", strings.NewReader("username=arthurCool"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("Accept-Language", "en")
+	r = asSmoker(r, "arthurCool")
+
+	responseRecorder := httptest.NewRecorder()
+	h.PostBuddyInvite().ServeHTTP(responseRecorder, r)
+
+	// Ошибки напарников отдаются тем же problem+json, что и остальные
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	var problem apperr.Problem
+	assert.NoError(t, json.Unmarshal(responseRecorder.Body.Bytes(), &problem))
+	assert.Equal(t, apperr.CodeSelfInvite, problem.Code)
+	assert.Equal(t, "You cannot invite yourself", problem.Detail)
+}
+
+func TestSigninUnknownUserPage(t *testing.T) {
+	// Форма входа в браузере получает страницу с ошибкой, а не JSON
+	r := httptest.NewRequest("POST", "/signin", strings.NewReader("username=nobody&password=x"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	h := New(nil, slog.Default())
+
+	responseRecorder := httptest.NewRecorder()
+	h.Signin().ServeHTTP(responseRecorder, r)
+
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	assert.Equal(t, "text/html; charset=utf-8", responseRecorder.Header().Get("Content-Type"))
+	assert.Contains(t, responseRecorder.Body.String(), "Пользователя с таким username не существует")
+}
+
+func TestHandleHidesInternalError(t *testing.T) {
+	r := httptest.NewRequest("GET", "/prices", nil)
+	h := New(nil, slog.Default())
+
+	responseRecorder := httptest.NewRecorder()
+	h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		return errors.New("storage is down")
+	}).ServeHTTP(responseRecorder, r)
+
+	assert.Equal(t, http.StatusInternalServerError, responseRecorder.Code)
+	assert.NotContains(t, responseRecorder.Body.String(), "storage is down")
+	assert.Contains(t, responseRecorder.Body.String(), `"code":"internal"`)
+}
+
+func TestWantsHTML(t *testing.T) {
+	tests := map[string]bool{
+		"":                                    false,
+		"*/*":                                 false,
+		"application/json":                    false,
+		"application/problem+json, text/html": false,
+		"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8": true,
+	}
+	for accept, want := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept", accept)
+		assert.Equal(t, want, wantsHTML(r), accept)
+	}
+}
+
+func postSmoker(t *testing.T, h *Handlers, contentType, body string) (*httptest.ResponseRecorder, apperr.Problem) {
+	r := httptest.NewRequest("POST", "/smokers", strings.NewReader(body))
+	r.Header.Set("Content-Type", contentType)
+	r.Header.Set("Accept-Language", "en")
+	r = asSmoker(r, "arthurCool")
+
+	responseRecorder := httptest.NewRecorder()
+	h.PostSmoker().ServeHTTP(responseRecorder, r)
+
+	var problem apperr.Problem
+	if responseRecorder.Code >= http.StatusBadRequest {
+		assert.NoError(t, json.Unmarshal(responseRecorder.Body.Bytes(), &problem))
+	}
+	return responseRecorder, problem
+}
+
+func TestPostSmokerWhenOk(t *testing.T) {
+	h := New(nil, slog.Default())
+
+	responseRecorder, _ := postSmoker(t, h, "application/json", `{
+		"id": "42", "name": "Ivan", "username": "ivan", "password": "secret1",
+		"stoppedSmoking": "2025-01-10T00:00:00Z", "cigarettesPerDay": 15, "packSize": 20, "packPrice": 230
+	}`)
+
+	assert.Equal(t, http.StatusCreated, responseRecorder.Code)
+	assert.Equal(t, `"1"`, responseRecorder.Header().Get("ETag"))
+	assert.NotContains(t, responseRecorder.Body.String(), "secret1")
+	smoker, ok := h.Storage.GetSmoker("ivan")
+	assert.True(t, ok)
+	assert.Equal(t, "Ivan", smoker.Name)
+}
+
+func TestPostSmokerValidation(t *testing.T) {
+	h := New(nil, slog.Default())
+
+	// Пустой id, будущая дата и нулевые числа — все ошибки приходят разом
+	responseRecorder, problem := postSmoker(t, h, "application/json", `{
+		"id": "", "name": "Ivan", "username": "ivan", "password": "secret1",
+		"stoppedSmoking": "2999-01-01T00:00:00Z"
+	}`)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, responseRecorder.Code)
+	assert.Equal(t, apperr.CodeValidation, problem.Code)
+
+	fields := map[string]string{}
+	for _, field := range problem.Errors {
+		fields[field.Field] = field.Code
+	}
+	assert.Equal(t, map[string]string{
+		"id":               validate.CodeRequired,
+		"stoppedSmoking":   validate.CodeFuture,
+		"cigarettesPerDay": validate.CodeRange,
+		"packSize":         validate.CodeRange,
+		"packPrice":        validate.CodeRange,
+	}, fields)
+	assert.Equal(t, "Must be between 1 and 200", problem.Errors[2].Detail)
+}
+
+func TestPostSmokerUnknownField(t *testing.T) {
+	h := New(nil, slog.Default())
+
+	responseRecorder, problem := postSmoker(t, h, "application/json", `{"id": "42", "role": "admin"}`)
+
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	assert.Equal(t, apperr.CodeInvalidJSON, problem.Code)
+	assert.Equal(t, []apperr.ProblemField{{Field: "role", Code: validate.CodeUnknownField, Detail: "Unknown field"}}, problem.Errors)
+}
+
+func TestPostSmokerWrongType(t *testing.T) {
+	h := New(nil, slog.Default())
+
+	responseRecorder, problem := postSmoker(t, h, "application/json", `{"packSize": "twenty"}`)
+
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	assert.Equal(t, []apperr.ProblemField{{Field: "packSize", Code: validate.CodeType, Detail: "Wrong value type"}}, problem.Errors)
+}
+
+func TestPostSmokerBodyLimits(t *testing.T) {
+	h := New(nil, slog.Default())
+
+	responseRecorder, problem := postSmoker(t, h, "application/json", `{"name": "`+strings.Repeat("a", configs.MaxJSONBodySize)+`"}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, responseRecorder.Code)
+	assert.Equal(t, apperr.CodeBodyTooLarge, problem.Code)
+
+	responseRecorder, problem = postSmoker(t, h, "application/json", `{"id": "42"} {"id": "43"}`)
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	assert.Equal(t, apperr.CodeInvalidJSON, problem.Code)
+
+	responseRecorder, problem = postSmoker(t, h, "text/plain", `{"id": "42"}`)
+	assert.Equal(t, http.StatusUnsupportedMediaType, responseRecorder.Code)
+	assert.Equal(t, apperr.CodeUnsupportedMedia, problem.Code)
+}
+
+// asSmoker добавляет в запрос курильщика, как это делает middleware.JwtAuth
+func asSmoker(r *http.Request, username string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), models.ContextString("smoker.name"), username))
+}
+
+// withSmoker добавляет курильщика в хранилище обработчиков
+func withSmoker(t *testing.T, h *Handlers, smoker models.Smoker) {
+	assert.True(t, h.Storage.AddSmoker(&smoker))
+}
+
+func patchSmoker(h *Handlers, username, id, ifMatch, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("PATCH", "/smokers/"+id, strings.NewReader(body))
+	r.SetPathValue("id", id)
+	r.Header.Set("Content-Type", "application/merge-patch+json")
+	if ifMatch != "" {
+		r.Header.Set("If-Match", ifMatch)
+	}
+
+	responseRecorder := httptest.NewRecorder()
+	h.PatchSmoker().ServeHTTP(responseRecorder, asSmoker(r, username))
+	return responseRecorder
+}
+
+var testSmoker = models.Smoker{
+	ID:               "77",
+	Name:             "Olga",
+	Username:         "olga",
+	Password:         "secret1",
+	StoppedSmoking:   time.Date(2025, time.January, 10, 0, 0, 0, 0, time.UTC),
+	CigarettesPerDay: 10,
+	PackSize:         20,
+	PackPrice:        200,
+	Version:          3,
+}
+
+func TestPatchSmokerWhenOk(t *testing.T) {
+	h := New(nil, slog.Default())
+	withSmoker(t, h, testSmoker)
+
+	responseRecorder := patchSmoker(h, "olga", "77", `"3"`, `{"name": "Ольга", "packPrice": 250}`)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, `"4"`, responseRecorder.Header().Get("ETag"))
+	smoker, _ := h.Storage.GetSmoker("olga")
+	assert.Equal(t, "Ольга", smoker.Name)
+	assert.Equal(t, 250.0, smoker.PackPrice)
+	assert.Equal(t, 10, smoker.CigarettesPerDay)
+	assert.Equal(t, "secret1", smoker.Password)
+}
+
+func TestPatchSmokerPreconditions(t *testing.T) {
+	h := New(nil, slog.Default())
+	withSmoker(t, h, testSmoker)
+
+	// Без If-Match изменять нельзя
+	responseRecorder := patchSmoker(h, "olga", "77", "", `{"name": "Ольга"}`)
+	assert.Equal(t, http.StatusPreconditionRequired, responseRecorder.Code)
+
+	// Кто-то уже изменил курильщика: версия 2 устарела
+	responseRecorder = patchSmoker(h, "olga", "77", `"2"`, `{"name": "Ольга"}`)
+	assert.Equal(t, http.StatusPreconditionFailed, responseRecorder.Code)
+	assert.Contains(t, responseRecorder.Body.String(), apperr.CodePreconditionFailed)
+
+	// Слабый ETag не подходит для If-Match
+	responseRecorder = patchSmoker(h, "olga", "77", `W/"3"`, `{"name": "Ольга"}`)
+	assert.Equal(t, http.StatusPreconditionFailed, responseRecorder.Code)
+
+	smoker, _ := h.Storage.GetSmoker("olga")
+	assert.Equal(t, "Olga", smoker.Name)
+	assert.Equal(t, 3, smoker.Version)
+}
+
+func TestPatchSmokerImmutableFields(t *testing.T) {
+	h := New(nil, slog.Default())
+	withSmoker(t, h, testSmoker)
+
+	responseRecorder := patchSmoker(h, "olga", "77", `"3"`, `{"id": "78", "username": "olga2", "name": null}`)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, responseRecorder.Code)
+	var problem apperr.Problem
+	assert.NoError(t, json.Unmarshal(responseRecorder.Body.Bytes(), &problem))
+	assert.Equal(t, []apperr.ProblemField{
+		{Field: "id", Code: validate.CodeImmutable, Detail: "Поле нельзя изменить"},
+		{Field: "username", Code: validate.CodeImmutable, Detail: "Поле нельзя изменить"},
+		{Field: "name", Code: validate.CodeRequired, Detail: "Обязательное поле"},
+	}, problem.Errors)
+	smoker, _ := h.Storage.GetSmoker("olga")
+	assert.Equal(t, "77", smoker.ID)
+}
+
+func TestPatchSmokerKeepsShortPassword(t *testing.T) {
+	h := New(nil, slog.Default())
+
+	// У victorCool пароль короче нынешнего минимума, но патч его не трогает
+	responseRecorder := patchSmoker(h, "victorCool", "2", `"1"`, `{"name": "Виктор"}`)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	smoker, _ := h.Storage.GetSmoker("victorCool")
+	assert.Equal(t, "Виктор", smoker.Name)
+	assert.Equal(t, "qasw", smoker.Password)
+
+	// Новый пароль проверяется по полным правилам
+	responseRecorder = patchSmoker(h, "victorCool", "2", `"2"`, `{"password": "abc"}`)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, responseRecorder.Code)
+	var problem apperr.Problem
+	assert.NoError(t, json.Unmarshal(responseRecorder.Body.Bytes(), &problem))
+	assert.Equal(t, "password", problem.Errors[0].Field)
+}
+
+func TestPatchSmokerForbidden(t *testing.T) {
+	h := New(nil, slog.Default())
+	withSmoker(t, h, testSmoker)
+
+	// Обычный курильщик не может менять чужие данные, администратор может
+	responseRecorder := patchSmoker(h, "victorCool", "77", `"3"`, `{"name": "Ольга"}`)
+	assert.Equal(t, http.StatusForbidden, responseRecorder.Code)
+
+	responseRecorder = patchSmoker(h, "arthurCool", "77", `"3"`, `{"name": "Ольга"}`)
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+}
+
+func TestGetSmokerNotModified(t *testing.T) {
+	h := New(nil, slog.Default())
+	withSmoker(t, h, testSmoker)
+
+	r := httptest.NewRequest("GET", "/smokers/77", nil)
+	r.SetPathValue("id", "77")
+	r.Header.Set("If-None-Match", `W/"3"`)
+	responseRecorder := httptest.NewRecorder()
+	h.GetSmoker().ServeHTTP(responseRecorder, asSmoker(r, "olga"))
+
+	assert.Equal(t, http.StatusNotModified, responseRecorder.Code)
+	assert.Equal(t, `"3"`, responseRecorder.Header().Get("ETag"))
+}
+
+// adminRequest запрос к панели администратора от имени username
+func adminRequest(h *Handlers, handler http.HandlerFunc, username, method, path, id string, form url.Values) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+	r.SetPathValue("id", id)
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("Accept", "text/html")
+
+	responseRecorder := httptest.NewRecorder()
+	handler.ServeHTTP(responseRecorder, asSmoker(r, username))
+	return responseRecorder
+}
+
+func TestAdminPage(t *testing.T) {
+	h := New(nil, slog.Default())
+	withSmoker(t, h, testSmoker)
+
+	responseRecorder := adminRequest(h, h.GetAdminPage(), "arthurCool", "GET", "/admin?q=olga", "", nil)
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Contains(t, responseRecorder.Body.String(), `href="/admin/smokers/77"`)
+
+	// Без роли администратора панель закрыта, даже если middleware пропустил запрос
+	responseRecorder = adminRequest(h, h.GetAdminPage(), "olga", "GET", "/admin", "", nil)
+	assert.Equal(t, http.StatusForbidden, responseRecorder.Code)
+}
+
+func TestAdminLock(t *testing.T) {
+	h := New(nil, slog.Default())
+	withSmoker(t, h, testSmoker)
+
+	responseRecorder := adminRequest(h, h.PostAdminLock(), "arthurCool", "POST", "/admin/smokers/77/lock", "77", url.Values{"reason": {"спам"}})
+	assert.Equal(t, http.StatusSeeOther, responseRecorder.Code)
+	assert.Equal(t, "/admin/smokers/77", responseRecorder.Header().Get("Location"))
+	assert.True(t, h.Storage.IsLocked("olga"))
+
+	// Заблокированный курильщик не может войти даже с верным паролем
+	r := httptest.NewRequest("POST", "/signin", strings.NewReader("username=olga&password=secret1"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	responseRecorder = httptest.NewRecorder()
+	h.Signin().ServeHTTP(responseRecorder, r)
+	assert.Equal(t, http.StatusForbidden, responseRecorder.Code)
+
+	// Блокировка и попытка входа попали в журнал
+	events := h.Storage.GetAuditEvents("olga")
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, models.AuditSigninFailure, events[0].Action)
+		assert.Equal(t, apperr.CodeAccountLocked, events[0].Details)
+		assert.Equal(t, "arthurCool", events[1].Actor)
+		assert.Equal(t, models.AuditAccountLock, events[1].Action)
+		assert.Equal(t, "спам", events[1].Details)
+	}
+
+	responseRecorder = adminRequest(h, h.PostAdminUnlock(), "arthurCool", "POST", "/admin/smokers/77/unlock", "77", nil)
+	assert.Equal(t, http.StatusSeeOther, responseRecorder.Code)
+	assert.False(t, h.Storage.IsLocked("olga"))
+
+	// Себя заблокировать нельзя
+	responseRecorder = adminRequest(h, h.PostAdminLock(), "arthurCool", "POST", "/admin/smokers/1/lock", "1", nil)
+	assert.Equal(t, http.StatusConflict, responseRecorder.Code)
+}
+
+func TestAdminRoles(t *testing.T) {
+	h := New(nil, slog.Default())
+	withSmoker(t, h, testSmoker)
+	t.Cleanup(func() { helpers.SetRoles("olga", nil) })
+
+	responseRecorder := adminRequest(h, h.PostAdminRoles(), "arthurCool", "POST", "/admin/smokers/77/roles", "77",
+		url.Values{"role": {configs.UserRole, configs.ModeratorRole}})
+	assert.Equal(t, http.StatusSeeOther, responseRecorder.Code)
+	assert.Equal(t, []string{configs.ModeratorRole}, helpers.GetRoles("olga"))
+
+	// Неизвестная роль
+	responseRecorder = adminRequest(h, h.PostAdminRoles(), "arthurCool", "POST", "/admin/smokers/77/roles", "77",
+		url.Values{"role": {"root"}})
+	assert.Equal(t, http.StatusUnprocessableEntity, responseRecorder.Code)
+
+	// Администратор не может снять роль администратора с себя
+	responseRecorder = adminRequest(h, h.PostAdminRoles(), "arthurCool", "POST", "/admin/smokers/1/roles", "1",
+		url.Values{"role": {configs.UserRole}})
+	assert.Equal(t, http.StatusConflict, responseRecorder.Code)
+	assert.True(t, helpers.HasRole("arthurCool", configs.AdminRole))
+}
+
+func TestAdminPasswordReset(t *testing.T) {
+	h := New(nil, slog.Default())
+	withSmoker(t, h, testSmoker)
+	oldToken, err := h.Mw.Tokener.GetJwtToken("olga")
+	assert.NoError(t, err)
+
+	responseRecorder := adminRequest(h, h.PostAdminPassword(), "arthurCool", "POST", "/admin/smokers/77/password", "77", nil)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, "no-store", responseRecorder.Header().Get("Cache-Control"))
+	smoker, _ := h.Storage.GetSmoker("olga")
+	password := smoker.Password
+	assert.Len(t, password, tempPasswordLength)
+	assert.NotEqual(t, testSmoker.Password, password)
+	// Новый пароль показан администратору
+	assert.Contains(t, responseRecorder.Body.String(), password)
+
+	// Сессии, открытые со старым паролем, больше не действуют
+	authorized := func(token string) int {
+		r := httptest.NewRequest("GET", "/profile", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		h.Mw.JwtAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rr, r)
+		return rr.Code
+	}
+	assert.Equal(t, http.StatusUnauthorized, authorized(oldToken))
+	newToken, err := h.Mw.Tokener.GetJwtToken("olga")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, authorized(newToken))
+}
+
+func TestAdminImpersonate(t *testing.T) {
+	h := New(nil, slog.Default())
+	withSmoker(t, h, testSmoker)
+	tokener := helpers.NewTokener()
+
+	responseRecorder := adminRequest(h, h.PostAdminImpersonate(), "arthurCool", "POST", "/admin/smokers/77/impersonate", "77", nil)
+	assert.Equal(t, http.StatusSeeOther, responseRecorder.Code)
+
+	// Токен выдан на курильщика, но помнит администратора
+	cookies := responseRecorder.Result().Cookies()
+	if assert.Len(t, cookies, 1) {
+		claims, err := tokener.VerifyUser(strings.TrimPrefix(cookies[0].Value, "Bearer "))
+		assert.NoError(t, err)
+		assert.Equal(t, "olga", claims.Username)
+		assert.Equal(t, "arthurCool", claims.Impersonator)
+	}
+
+	// Возврат к своей учётной записи
+	r := httptest.NewRequest("POST", "/impersonation/stop", nil)
+	ctx := context.WithValue(r.Context(), models.ContextString("smoker.impersonator"), "arthurCool")
+	responseRecorder = httptest.NewRecorder()
+	h.StopImpersonation().ServeHTTP(responseRecorder, asSmoker(r.WithContext(ctx), "olga"))
+	assert.Equal(t, http.StatusSeeOther, responseRecorder.Code)
+	assert.Equal(t, "/admin/smokers/77", responseRecorder.Header().Get("Location"))
+	cookies = responseRecorder.Result().Cookies()
+	if assert.Len(t, cookies, 1) {
+		claims, err := tokener.VerifyUser(strings.TrimPrefix(cookies[0].Value, "Bearer "))
+		assert.NoError(t, err)
+		assert.Equal(t, "arthurCool", claims.Username)
+		assert.Empty(t, claims.Impersonator)
+	}
+
+	events := h.Storage.GetAuditEvents("olga")
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, models.AuditImpersonateStop, events[0].Action)
+		assert.Equal(t, models.AuditImpersonateStart, events[1].Action)
+	}
+
+	// Без входа от чужого имени возвращаться некуда
+	responseRecorder = httptest.NewRecorder()
+	h.StopImpersonation().ServeHTTP(responseRecorder, asSmoker(httptest.NewRequest("POST", "/impersonation/stop", nil), "olga"))
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+}
+
+// signin отправляет форму входа
+func signin(h *Handlers, username, password string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("POST", "/signin", strings.NewReader(url.Values{"username": {username}, "password": {password}}.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("User-Agent", "test-agent")
+
+	responseRecorder := httptest.NewRecorder()
+	h.Signin().ServeHTTP(responseRecorder, r)
+	return responseRecorder
+}
+
+func TestSigninAudit(t *testing.T) {
+	h := New(nil, slog.Default())
+	withSmoker(t, h, testSmoker)
+
+	assert.Equal(t, http.StatusUnauthorized, signin(h, "olga", "wrong").Code)
+	assert.Equal(t, http.StatusOK, signin(h, "olga", "secret1").Code)
+
+	events := h.Storage.GetAuditEvents("olga")
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, models.AuditSigninSuccess, events[0].Action)
+		assert.Equal(t, "olga", events[0].Actor)
+
+		// При неудачном входе исполнитель неизвестен
+		assert.Equal(t, models.AuditSigninFailure, events[1].Action)
+		assert.Empty(t, events[1].Actor)
+		assert.Equal(t, "olga", events[1].Target)
+		assert.Equal(t, apperr.CodeWrongPassword, events[1].Details)
+		assert.Equal(t, "192.0.2.1", events[1].IP)
+		assert.Equal(t, "test-agent", events[1].UserAgent)
+	}
+}
+
+func TestLogoutAudit(t *testing.T) {
+	h := New(nil, slog.Default())
+	withSmoker(t, h, testSmoker)
+
+	token, err := helpers.NewTokener().GetJwtToken("olga")
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest("GET", "/logout", nil)
+	r.AddCookie(&http.Cookie{Name: "token", Value: "Bearer " + token})
+	responseRecorder := httptest.NewRecorder()
+	h.Logout().ServeHTTP(responseRecorder, r)
+
+	assert.Equal(t, http.StatusFound, responseRecorder.Code)
+	events := h.Storage.GetAuditEvents("olga")
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, models.AuditLogout, events[0].Action)
+	}
+}
+
+func TestPatchSmokerAudit(t *testing.T) {
+	h := New(nil, slog.Default())
+	withSmoker(t, h, testSmoker)
+
+	responseRecorder := patchSmoker(h, "arthurCool", "77", `"3"`, `{"packPrice": 250, "name": "Ольга"}`)
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+
+	// В журнале только имена полей, без значений
+	events := h.Storage.GetAuditEvents("olga")
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, models.AuditSmokerUpdate, events[0].Action)
+		assert.Equal(t, "arthurCool", events[0].Actor)
+		assert.Equal(t, "name, packPrice", events[0].Details)
+	}
+}
+
+func TestExportAudit(t *testing.T) {
+	h := New(nil, slog.Default())
+	withSmoker(t, h, testSmoker)
+	signin(h, "olga", "wrong")
+	signin(h, "olga", "secret1")
+	signin(h, "victorCool", "wrong")
+
+	responseRecorder := adminRequest(h, h.ExportAudit(), "arthurCool", "GET", "/admin/audit/export?action=signin&target=olga", "", nil)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, "application/x-ndjson;charset=utf-8", responseRecorder.Header().Get("Content-Type"))
+	lines := strings.Split(strings.TrimSuffix(responseRecorder.Body.String(), "\n"), "\n")
+	if assert.Len(t, lines, 2) {
+		var event models.AuditEvent
+		assert.NoError(t, json.Unmarshal([]byte(lines[0]), &event))
+		assert.Equal(t, models.AuditSigninSuccess, event.Action)
+	}
+
+	// Неизвестное действие — ошибка в параметре
+	responseRecorder = adminRequest(h, h.ExportAudit(), "arthurCool", "GET", "/admin/audit/export?action=sign", "", nil)
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+
+	// Журнал видят только администраторы
+	responseRecorder = adminRequest(h, h.ExportAudit(), "olga", "GET", "/admin/audit/export", "", nil)
+	assert.Equal(t, http.StatusForbidden, responseRecorder.Code)
+}
+
+func TestAuditPage(t *testing.T) {
+	h := New(nil, slog.Default())
+	withSmoker(t, h, testSmoker)
+	signin(h, "olga", "wrong")
+
+	responseRecorder := adminRequest(h, h.GetAuditPage(), "arthurCool", "GET", "/admin/audit?actor=&target=olga", "", nil)
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Contains(t, responseRecorder.Body.String(), models.AuditSigninFailure)
+}
+
+func TestPostReductionPlanKeepsPreviousDate(t *testing.T) {
+	h := New(nil, slog.Default())
+	withSmoker(t, h, testSmoker)
+
+	postPlan := func(quitDate string) int {
+		form := url.Values{"quitDate": {quitDate}}
+		r := httptest.NewRequest("POST", "/reduction", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		responseRecorder := httptest.NewRecorder()
+		h.PostReductionPlan().ServeHTTP(responseRecorder, asSmoker(r, "olga"))
+		return responseRecorder.Code
+	}
+	first := time.Now().UTC().AddDate(0, 0, 10).Truncate(24 * time.Hour)
+	second := first.AddDate(0, 0, 5)
+
+	assert.Equal(t, http.StatusFound, postPlan(first.Format(time.DateOnly)))
+	assert.Equal(t, http.StatusFound, postPlan(second.Format(time.DateOnly)))
+
+	smoker, _ := h.Storage.GetSmoker("olga")
+	assert.Equal(t, second, smoker.StoppedSmoking)
+	assert.Equal(t, testSmoker.Version+2, smoker.Version)
+	// Второй план не затирает дату, которая была до первого
+	assert.Equal(t, testSmoker.StoppedSmoking, h.Storage.GetReductionPlan("olga").PreviousStoppedSmoking)
+	events := h.Storage.GetAuditEvents("olga")
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, models.AuditSmokerUpdate, events[0].Action)
+		assert.Equal(t, "stoppedSmoking", events[0].Details)
+	}
+}
+
+func TestProfileLiveSeesSmokerChanges(t *testing.T) {
+	h := New(nil, slog.Default())
+	withSmoker(t, h, testSmoker)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.GetProfileLive().ServeHTTP(w, asSmoker(r, "olga"))
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	lines := bufio.NewScanner(resp.Body)
+	nextStats := func() liveStats {
+		for lines.Scan() {
+			if data, ok := strings.CutPrefix(lines.Text(), "data: "); ok {
+				var stats liveStats
+				if json.Unmarshal([]byte(data), &stats) == nil && stats.TimeNotSmoke != "" {
+					return stats
+				}
+			}
+		}
+		t.Fatal("поток закончился")
+		return liveStats{}
+	}
+
+	before := nextStats()
+	// Курильщик сорвался и начал заново: счётчик должен это увидеть без переподключения
+	h.Storage.UpdateSmoker(testSmoker.ID, func(smoker *models.Smoker) error {
+		smoker.StoppedSmoking = time.Now().UTC()
+		return nil
+	})
+	after := nextStats()
+	assert.Positive(t, before.DaysSmokeFree)
+	assert.Zero(t, after.DaysSmokeFree)
+}
+
+func TestProfileLiveEndsWhenAccountLocked(t *testing.T) {
+	h := New(nil, slog.Default())
+	withSmoker(t, h, testSmoker)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.GetProfileLive().ServeHTTP(w, asSmoker(r, "olga"))
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	lines := bufio.NewScanner(resp.Body)
+	for lines.Scan() && !strings.HasPrefix(lines.Text(), "data: ") {
+	}
+
+	// Администратор заблокировал курильщика, пока страница профиля открыта
+	h.Storage.LockAccount(&models.AccountLock{Username: "olga", By: "admin", At: time.Now().UTC()})
+	ended := make(chan struct{})
+	go func() {
+		for lines.Scan() {
+		}
+		close(ended)
+	}()
+	select {
+	case <-ended:
+	case <-time.After(5 * time.Second):
+		t.Fatal("поток не закрылся после блокировки")
+	}
+}
+
+func TestChallengeLeaderboardAnonymousNames(t *testing.T) {
+	h := New(nil, slog.Default())
+	now := time.Now().UTC()
+	challenge := &models.Challenge{Title: "Месяц", StartDate: now.Add(-time.Hour), EndDate: now.AddDate(0, 0, 30)}
+	h.Storage.AddChallenge(challenge)
+	for i, username := range []string{"olga", "victor", "maria"} {
+		smoker := testSmoker
+		smoker.ID, smoker.Username = strconv.Itoa(100+i), username
+		withSmoker(t, h, smoker)
+		h.Storage.JoinChallenge(challenge.ID, &models.ChallengeMember{Username: username, JoinedAt: now, Anonymous: username == "maria"})
+	}
+	// Выход другого участника не меняет номер анонима
+	h.Storage.LeaveChallenge(challenge.ID, "victor")
+
+	r := httptest.NewRequest("GET", "/challenges/"+challenge.ID+"/leaderboard", nil)
+	r.SetPathValue("id", challenge.ID)
+	r.Header.Set("Accept-Language", "en")
+	responseRecorder := httptest.NewRecorder()
+	h.GetChallengeLeaderboard().ServeHTTP(responseRecorder, asSmoker(r, "olga"))
+
+	var body struct {
+		Leaderboard []models.LeaderboardEntry `json:"leaderboard"`
+	}
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.NoError(t, json.NewDecoder(responseRecorder.Body).Decode(&body))
+	names := make([]string, 0, len(body.Leaderboard))
+	for _, entry := range body.Leaderboard {
+		names = append(names, entry.DisplayName)
+	}
+	assert.ElementsMatch(t, []string{"Olga", "Participant #3"}, names)
+}
+
+func TestPostNRTUsageVape(t *testing.T) {
+	h := New(nil, slog.Default())
+	product := &models.NRTProduct{Username: "olga", Type: models.NRTVape, Strength: 12, StartDate: time.Now().UTC(), StepDays: 30}
+	h.Storage.AddNRTProduct(product)
+
+	postUsage := func(form url.Values) int {
+		r := httptest.NewRequest("POST", "/nrt/"+product.ID+"/usage", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.SetPathValue("id", product.ID)
+		responseRecorder := httptest.NewRecorder()
+		h.PostNRTUsage().ServeHTTP(responseRecorder, asSmoker(r, "olga"))
+		return responseRecorder.Code
+	}
+
+	// Для вейпа количество штук не имеет смысла: нужен объём жидкости
+	assert.Equal(t, http.StatusBadRequest, postUsage(url.Values{"units": {"1"}}))
+	assert.Equal(t, http.StatusBadRequest, postUsage(url.Values{"milliliters": {"NaN"}}))
+	assert.Equal(t, http.StatusBadRequest, postUsage(url.Values{"milliliters": {"+Inf"}}))
+	assert.Equal(t, http.StatusFound, postUsage(url.Values{"milliliters": {"1,5"}}))
+
+	usages := h.Storage.GetNRTUsages("olga")
+	if assert.Len(t, usages, 1) {
+		assert.Equal(t, 1.5, usages[0].Milliliters)
+		assert.Equal(t, 18.0, nrt.Milligrams(usages[0]))
+	}
+}
+
+func TestPostNRTUsageAfterTaper(t *testing.T) {
+	h := New(nil, slog.Default())
+	// Курс пластыря 21 → 14 → 7 → 0 по дню на ступень давно закончился
+	product := &models.NRTProduct{Username: "olga", Type: models.NRTPatch, Strength: 21, StartDate: time.Now().UTC().AddDate(0, 0, -10), StepDays: 1}
+	h.Storage.AddNRTProduct(product)
+
+	r := httptest.NewRequest("POST", "/nrt/"+product.ID+"/usage", nil)
+	r.SetPathValue("id", product.ID)
+	responseRecorder := httptest.NewRecorder()
+	h.PostNRTUsage().ServeHTTP(responseRecorder, asSmoker(r, "olga"))
+
+	assert.Equal(t, http.StatusConflict, responseRecorder.Code)
+	assert.Empty(t, h.Storage.GetNRTUsages("olga"))
+}
+
+func TestPatchSmokerAfterReductionPlan(t *testing.T) {
+	h := New(nil, slog.Default())
+	withSmoker(t, h, testSmoker)
+
+	quitDate := time.Now().UTC().AddDate(0, 0, 10).Truncate(24 * time.Hour)
+	form := url.Values{"quitDate": {quitDate.Format(time.DateOnly)}}
+	r := httptest.NewRequest("POST", "/reduction", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	h.PostReductionPlan().ServeHTTP(httptest.NewRecorder(), asSmoker(r, "olga"))
+
+	// Дата отказа в будущем из плана не мешает менять другие поля
+	responseRecorder := patchSmoker(h, "olga", "77", `"4"`, `{"name": "Ольга"}`)
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+
+	// А самому поставить другую дату в будущем по-прежнему нельзя
+	later := quitDate.AddDate(0, 0, 1).Format(time.RFC3339)
+	responseRecorder = patchSmoker(h, "olga", "77", `"5"`, `{"stoppedSmoking": "`+later+`"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, responseRecorder.Code)
+}
+
+func TestPostPriceRejectsNonFinite(t *testing.T) {
+	h := New(nil, slog.Default())
+	withSmoker(t, h, testSmoker)
+
+	for _, price := range []string{"NaN", "nan", "Inf", "+Inf", "1e400"} {
+		form := url.Values{"price": {price}}
+		r := httptest.NewRequest("POST", "/prices", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		responseRecorder := httptest.NewRecorder()
+		h.PostPrice().ServeHTTP(responseRecorder, asSmoker(r, "olga"))
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code, price)
+	}
+	assert.Empty(t, h.Storage.GetPriceHistory("olga"))
+}
+
+func TestPostGoalValidation(t *testing.T) {
+	h := New(nil, slog.Default())
+	withSmoker(t, h, testSmoker)
+
+	postGoal := func(form url.Values) int {
+		r := httptest.NewRequest("POST", "/goals", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		responseRecorder := httptest.NewRecorder()
+		h.PostGoal().ServeHTTP(responseRecorder, asSmoker(r, "olga"))
+		return responseRecorder.Code
+	}
+	goal := func(title, target, deadline string) url.Values {
+		return url.Values{"title": {title}, "kind": {models.GoalKindMoney}, "target": {target}, "deadline": {deadline}}
+	}
+	today := time.Now().UTC().Format(time.DateOnly)
+	yesterday := time.Now().UTC().AddDate(0, 0, -1).Format(time.DateOnly)
+
+	assert.Equal(t, http.StatusBadRequest, postGoal(goal("Отпуск", "NaN", "")))
+	assert.Equal(t, http.StatusBadRequest, postGoal(goal("Отпуск", "Inf", "")))
+	assert.Equal(t, http.StatusBadRequest, postGoal(goal(strings.Repeat("я", 101), "1000", "")))
+	assert.Equal(t, http.StatusBadRequest, postGoal(goal("Отпуск", "1000", yesterday)))
+	assert.Empty(t, h.Storage.GetGoals("olga"))
+
+	// Срок сегодня ещё не прошёл
+	assert.Equal(t, http.StatusFound, postGoal(goal("Отпуск", "1 000", today)))
+	assert.Len(t, h.Storage.GetGoals("olga"), 1)
+}
+
+func TestPostCheckInRejectsNonFiniteSleep(t *testing.T) {
+	h := New(nil, slog.Default())
+	withSmoker(t, h, testSmoker)
+
+	for _, sleep := range []string{"NaN", "Inf", "-Inf"} {
+		form := url.Values{"mood": {"3"}, "stress": {"3"}, "sleep": {sleep}}
+		r := httptest.NewRequest("POST", "/checkin", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		responseRecorder := httptest.NewRecorder()
+		h.PostCheckIn().ServeHTTP(responseRecorder, asSmoker(r, "olga"))
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code, sleep)
+	}
+	assert.Empty(t, h.Storage.GetCheckIns("olga"))
+}
+
+func TestPostReportReasonLength(t *testing.T) {
+	h := New(nil, slog.Default())
+	withSmoker(t, h, testSmoker)
+	post := &models.Post{Author: "olga", Text: "Держусь", CreatedAt: time.Now().UTC()}
+	h.Storage.AddPost(post)
+
+	postReport := func(reason string) int {
+		form := url.Values{"reason": {reason}}
+		r := httptest.NewRequest("POST", "/feed/posts/"+post.ID+"/report", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.SetPathValue("id", post.ID)
+		responseRecorder := httptest.NewRecorder()
+		h.PostReport().ServeHTTP(responseRecorder, asSmoker(r, "olga"))
+		return responseRecorder.Code
+	}
+
+	// Длина считается в символах, а не в байтах
+	assert.Equal(t, http.StatusBadRequest, postReport(strings.Repeat("я", feed.MaxReportReasonLength+1)))
+	assert.Empty(t, h.Storage.GetReports())
+	assert.Equal(t, http.StatusFound, postReport(strings.Repeat("я", feed.MaxReportReasonLength)))
+	assert.Len(t, h.Storage.GetReports(), 1)
 }
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/apperr"
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/NarthurN/QuitSmoking/internal/validate"
+)
+
+// Сколько записей входящих показывать на странице и сколько можно запросить в API
+const (
+	inboxPageSize    = 20
+	maxInboxPageSize = 100
+)
+
+// GetInboxPage отображает входящие курильщика постранично
+func (h *Handlers) GetInboxPage() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		page := pageParam(r)
+		items, total := h.Storage.GetInbox(username, (page-1)*inboxPageSize, inboxPageSize)
+		pages := max((total+inboxPageSize-1)/inboxPageSize, 1)
+
+		data := struct {
+			Name     string
+			Unread   int
+			Items    []models.InboxItem
+			Page     int
+			Pages    int
+			PrevPage int
+			NextPage int
+		}{
+			Name:   h.smokerName(username),
+			Unread: h.Storage.CountUnread(username),
+			Items:  items,
+			Page:   page,
+			Pages:  pages,
+		}
+		if page > 1 {
+			data.PrevPage = page - 1
+		}
+		if page < pages {
+			data.NextPage = page + 1
+		}
+
+		h.render(w, r, http.StatusOK, "inbox.html", data)
+		return nil
+	})
+}
+
+// GetInbox отображает страницу входящих в формате JSON. Параметры: page (с 1) и limit (до 100)
+func (h *Handlers) GetInbox() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		limit := inboxPageSize
+		if raw := r.URL.Query().Get("limit"); raw != "" {
+			l, err := strconv.Atoi(raw)
+			if err != nil || l < 1 || l > maxInboxPageSize {
+				return apperr.BadRequest(apperr.CodeInvalidQuery).
+					WithFields(apperr.FieldError{Field: "limit", Code: validate.CodeRange, Args: []any{1, maxInboxPageSize}})
+			}
+			limit = l
+		}
+		page := pageParam(r)
+		items, total := h.Storage.GetInbox(username, (page-1)*limit, limit)
+
+		return writeJSON(w, http.StatusOK, struct {
+			Items  []models.InboxItem `json:"items"`
+			Page   int                `json:"page"`
+			Limit  int                `json:"limit"`
+			Total  int                `json:"total"`
+			Unread int                `json:"unread"`
+		}{
+			Items:  items,
+			Page:   page,
+			Limit:  limit,
+			Total:  total,
+			Unread: h.Storage.CountUnread(username),
+		})
+	})
+}
+
+// MarkInboxItemRead отмечает запись во входящих прочитанной
+func (h *Handlers) MarkInboxItemRead() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		if !h.Storage.MarkInboxItemRead(username, r.PathValue("id"), time.Now().UTC()) {
+			return apperr.NotFound(apperr.CodeNotificationNotFound)
+		}
+
+		http.Redirect(w, r, `/inbox`, http.StatusFound)
+		return nil
+	})
+}
+
+// MarkAllInboxRead отмечает прочитанными все записи во входящих
+func (h *Handlers) MarkAllInboxRead() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		h.Storage.MarkAllInboxRead(username, time.Now().UTC())
+
+		http.Redirect(w, r, `/inbox`, http.StatusFound)
+		return nil
+	})
+}
+
+func pageParam(r *http.Request) int {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		return 1
+	}
+	return page
+}
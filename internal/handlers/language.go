@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/apperr"
+	"github.com/NarthurN/QuitSmoking/internal/i18n"
+)
+
+// PostLanguage сохраняет язык интерфейса курильщика и запоминает его в cookie,
+// чтобы и страницы для гостей открывались на нём после выхода
+func (h *Handlers) PostLanguage() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		locale := r.FormValue("lang")
+		if !h.I18n.Supported(locale) {
+			return apperr.BadRequest(apperr.CodeUnsupportedLanguage)
+		}
+
+		h.Storage.SetLanguage(username, locale)
+		http.SetCookie(w, &http.Cookie{
+			Name:     i18n.CookieName,
+			Value:    locale,
+			Path:     "/",
+			Expires:  time.Now().UTC().AddDate(1, 0, 0),
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+
+		http.Redirect(w, r, backPath(r), http.StatusFound)
+		return nil
+	})
+}
+
+// backPath возвращает страницу, с которой пришёл запрос, если она на нашем сайте, иначе профиль
+func backPath(r *http.Request) string {
+	referer, err := url.Parse(r.Referer())
+	if err != nil || referer.Host != r.Host || !strings.HasPrefix(referer.Path, "/") || strings.HasPrefix(referer.Path, "//") {
+		return "/profile"
+	}
+	if referer.RawQuery != "" {
+		return referer.Path + "?" + referer.RawQuery
+	}
+	return referer.Path
+}
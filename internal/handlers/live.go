@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/achievements"
+	"github.com/NarthurN/QuitSmoking/internal/apperr"
+	"github.com/NarthurN/QuitSmoking/internal/configs"
+	"github.com/NarthurN/QuitSmoking/internal/helpers"
+	"github.com/NarthurN/QuitSmoking/internal/sse"
+)
+
+// liveStats данные живого счётчика на странице профиля
+type liveStats struct {
+	TimeNotSmoke   string  `json:"timeNotSmoke"`
+	DaysSmokeFree  int     `json:"daysSmokeFree"`
+	MoneySaved     float64 `json:"moneySaved"`
+	MoneySavedText string  `json:"moneySavedText"`
+}
+
+// GetProfileLive отправляет по SSE обновлённое время без сигарет и сэкономленную сумму,
+// когда они меняются, и новые достижения, как только курильщик их получает
+func (h *Handlers) GetProfileLive() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := h.Storage.GetSmoker(username); !ok {
+			return apperr.NotFound(apperr.CodeSmokerNotFound)
+		}
+
+		if err := h.Live.Acquire(username); err != nil {
+			if errors.Is(err, sse.ErrTooManyForUser) {
+				return apperr.New(http.StatusTooManyRequests, apperr.CodeTooManyTabs)
+			}
+			return apperr.New(http.StatusServiceUnavailable, apperr.CodeServerBusy)
+		}
+		defer h.Live.Release(username)
+
+		stream, err := sse.NewStream(w, configs.LiveRetry)
+		if err != nil {
+			// Заголовки уже могли уйти клиенту, поэтому ответить ошибкой нельзя
+			h.Logger.Error("handlers.GetProfileLive.NewStream", helpers.SlogErr(err))
+			return nil
+		}
+
+		check := time.NewTicker(configs.LiveCheckInterval)
+		defer check.Stop()
+		heartbeat := time.NewTicker(configs.LiveHeartbeat)
+		defer heartbeat.Stop()
+
+		session := h.Mw.Session(r)
+		loc := h.localizer(r)
+		var last liveStats
+		for {
+			// Блокировка, отзыв и истечение токена закрывают уже открытый поток.
+			// EventSource переподключится и снова пройдёт проверку в JwtAuth
+			if err := session(time.Now()); err != nil {
+				h.Logger.Debug("handlers.GetProfileLive.session", helpers.SlogDebug(err.Error()))
+				return nil
+			}
+			// Дату отказа или цену пачки могли поменять, пока поток открыт
+			smoker, ok := h.Storage.GetSmoker(username)
+			if !ok {
+				return nil
+			}
+			stats := helpers.GetSmokerStats(smoker, h.Storage.GetCravings(username), h.Storage.GetPriceHistory(username))
+			current := liveStats{
+				TimeNotSmoke:   helpers.GetSmokersDiffTime(smoker, loc),
+				DaysSmokeFree:  stats.DaysSmokeFree,
+				MoneySaved:     stats.MoneySaved,
+				MoneySavedText: loc.Money(stats.MoneySaved, h.Storage.GetCurrency(username)),
+			}
+			if current != last {
+				if err := stream.Event("stats", current); err != nil {
+					return nil
+				}
+				last = current
+			}
+
+			awarded := h.Achievements.Award(h.Storage, username, stats)
+			for _, achievement := range achievements.Localized(loc, awarded) {
+				if err := stream.Event("achievement", achievement); err != nil {
+					return nil
+				}
+			}
+
+			select {
+			case <-r.Context().Done():
+				return nil
+			case <-heartbeat.C:
+				if err := stream.Heartbeat(); err != nil {
+					return nil
+				}
+			case <-check.C:
+			}
+		}
+	})
+}
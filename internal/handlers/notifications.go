@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/apperr"
+	"github.com/NarthurN/QuitSmoking/internal/notify"
+)
+
+// GetNotificationSettings отображает настройки уведомлений курильщика в формате JSON
+func (h *Handlers) GetNotificationSettings() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		settings, ok := h.Storage.GetNotificationSettings(username)
+		if !ok {
+			settings = notify.DefaultSettings(username)
+		}
+
+		return writeJSON(w, http.StatusOK, settings)
+	})
+}
+
+// PostNotificationSettings сохраняет часовой пояс, тихие часы и каналы доставки уведомлений
+func (h *Handlers) PostNotificationSettings() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		if err := r.ParseForm(); err != nil {
+			return apperr.BadRequest(apperr.CodeBadRequest)
+		}
+
+		settings := notify.DefaultSettings(username)
+
+		if tz := r.FormValue("timezone"); tz != "" {
+			if _, err := time.LoadLocation(tz); err != nil {
+				return apperr.BadRequest(apperr.CodeUnknownTimezone)
+			}
+			settings.Timezone = tz
+		}
+
+		hours := map[string]*int{
+			"quietStart":  &settings.QuietStart,
+			"quietEnd":    &settings.QuietEnd,
+			"checkInHour": &settings.CheckInHour,
+		}
+		for field, value := range hours {
+			raw := r.FormValue(field)
+			if raw == "" {
+				continue
+			}
+			hour, err := strconv.Atoi(raw)
+			if err != nil || hour < 0 || hour > 23 {
+				return apperr.BadRequest(apperr.CodeHourRange, 0, 23)
+			}
+			*value = hour
+		}
+
+		if channels := r.Form["channels"]; len(channels) > 0 {
+			for _, channel := range channels {
+				if !notify.ValidChannel(channel) {
+					return apperr.BadRequest(apperr.CodeUnknownChannel)
+				}
+			}
+			settings.Channels = channels
+		}
+
+		settings.Email = strings.TrimSpace(r.FormValue("email"))
+		if settings.Email != "" && (!strings.Contains(settings.Email, "@") || strings.ContainsAny(settings.Email, " \r\n")) {
+			return apperr.BadRequest(apperr.CodeInvalidEmail)
+		}
+
+		settings.WebhookURL = strings.TrimSpace(r.FormValue("webhookUrl"))
+		if settings.WebhookURL != "" {
+			err := notify.CheckWebhookURL(r.Context(), settings.WebhookURL)
+			if errors.Is(err, notify.ErrPrivateAddress) {
+				return apperr.BadRequest(apperr.CodeWebhookAddress)
+			}
+			if err != nil {
+				return apperr.BadRequest(apperr.CodeInvalidWebhook)
+			}
+		}
+
+		h.Storage.SaveNotificationSettings(&settings)
+
+		http.Redirect(w, r, `/profile`, http.StatusFound)
+		return nil
+	})
+}
+
+// GetDeliveries отображает состояние доставки уведомлений курильщика в формате JSON
+func (h *Handlers) GetDeliveries() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		return writeJSON(w, http.StatusOK, h.Storage.GetDeliveries(username))
+	})
+}
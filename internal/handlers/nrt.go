@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/apperr"
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/NarthurN/QuitSmoking/internal/nrt"
+)
+
+// PostNRTProduct добавляет средство никотинозаместительной терапии
+func (h *Handlers) PostNRTProduct() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		productType := r.FormValue("type")
+		if !nrt.KnownType(productType) {
+			return apperr.BadRequest(apperr.CodeUnknownNRTKind)
+		}
+
+		strength, err := parseNumber(r.FormValue("strength"))
+		if err != nil || strength <= 0 {
+			return apperr.BadRequest(apperr.CodeNRTDose)
+		}
+
+		stepDays := nrt.DefaultStepDays(productType)
+		if raw := r.FormValue("stepDays"); raw != "" {
+			stepDays, err = strconv.Atoi(raw)
+			if err != nil || stepDays <= 0 {
+				return apperr.BadRequest(apperr.CodeNRTStepDays)
+			}
+		}
+
+		h.Storage.AddNRTProduct(&models.NRTProduct{
+			Username:  username,
+			Type:      productType,
+			Strength:  strength,
+			StartDate: time.Now().UTC().Truncate(24 * time.Hour),
+			StepDays:  stepDays,
+		})
+
+		http.Redirect(w, r, `/profile`, http.StatusFound)
+		return nil
+	})
+}
+
+// PostNRTUsage записывает использование средства НЗТ с дозировкой текущей ступени.
+// Для вейпа вместо количества штук передаётся объём жидкости в мл (milliliters).
+// После окончания курса дозировка нулевая, и использование не записывается: иначе
+// никотин, который всё-таки был, посчитался бы как 0 мг
+func (h *Handlers) PostNRTUsage() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		product, ok := h.Storage.GetNRTProduct(username, r.PathValue("id"))
+		if !ok {
+			return apperr.NotFound(apperr.CodeNRTNotFound)
+		}
+
+		now := time.Now().UTC()
+		step := nrt.CurrentStep(product, now)
+		if step.Strength == 0 {
+			return apperr.New(http.StatusConflict, apperr.CodeNRTFinished)
+		}
+		usage := &models.NRTUsage{
+			ProductID: product.ID,
+			Date:      now,
+			Strength:  step.Strength,
+		}
+		if product.Type == models.NRTVape {
+			milliliters, err := parseNumber(r.FormValue("milliliters"))
+			if err != nil || milliliters <= 0 {
+				return apperr.BadRequest(apperr.CodeNRTVolume)
+			}
+			usage.Milliliters = milliliters
+		} else {
+			usage.Units = 1.0
+			if raw := r.FormValue("units"); raw != "" {
+				usage.Units, err = parseNumber(raw)
+				if err != nil || usage.Units <= 0 {
+					return apperr.BadRequest(apperr.CodeNRTQuantity)
+				}
+			}
+		}
+		h.Storage.AddNRTUsage(username, usage)
+
+		http.Redirect(w, r, `/profile`, http.StatusFound)
+		return nil
+	})
+}
+
+// GetNRT отображает средства НЗТ, графики снижения и динамику потребления никотина в формате JSON
+func (h *Handlers) GetNRT() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		products := h.Storage.GetNRTProducts(username)
+		statuses := make([]models.NRTStatus, 0, len(products))
+		for _, product := range products {
+			statuses = append(statuses, nrt.Status(product, now))
+		}
+		intake := nrt.Intake(h.Storage.GetNRTUsages(username))
+
+		return writeJSON(w, http.StatusOK, struct {
+			Products []models.NRTStatus      `json:"products"`
+			Intake   []models.NicotineIntake `json:"intake"`
+			Trend    float64                 `json:"trend"`
+		}{
+			Products: statuses,
+			Intake:   intake,
+			Trend:    nrt.Trend(intake),
+		})
+	})
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/apperr"
+	"github.com/NarthurN/QuitSmoking/internal/helpers"
+	"github.com/NarthurN/QuitSmoking/internal/i18n"
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+// Самая большая цена пачки, которую принимаем: защищает от опечаток на пару лишних нулей
+const maxPackPrice = 1_000_000
+
+// PostPrice записывает новую цену пачки с указанной даты (по умолчанию с сегодняшней).
+// Экономия до этой даты по-прежнему считается по старой цене
+func (h *Handlers) PostPrice() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		// Разрешаем ввод вида "1 250" и "249,90"
+		rawPrice := strings.NewReplacer(" ", "", "\u00a0", "").Replace(r.FormValue("price"))
+		price, err := parseNumber(rawPrice)
+		if err != nil || price <= 0 || price > maxPackPrice {
+			return apperr.BadRequest(apperr.CodeInvalidPrice)
+		}
+
+		now := time.Now().UTC()
+		from := now.Truncate(24 * time.Hour)
+		if raw := r.FormValue("from"); raw != "" {
+			from, err = time.Parse(time.DateOnly, raw)
+			if err != nil {
+				return apperr.BadRequest(apperr.CodeInvalidDate)
+			}
+		}
+
+		h.Storage.SetPriceChange(username, models.PriceChange{
+			Price:     price,
+			From:      from,
+			CreatedAt: now,
+		})
+
+		http.Redirect(w, r, `/profile`, http.StatusFound)
+		return nil
+	})
+}
+
+// GetPrices отображает валюту, цену пачки при регистрации и историю её изменений в формате JSON
+func (h *Handlers) GetPrices() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		smoker, ok := h.Storage.GetSmoker(username)
+		if !ok {
+			return apperr.NotFound(apperr.CodeSmokerNotFound)
+		}
+
+		prices := h.Storage.GetPriceHistory(username)
+		return writeJSON(w, http.StatusOK, struct {
+			Currency     string               `json:"currency"`
+			InitialPrice float64              `json:"initialPrice"`
+			CurrentPrice float64              `json:"currentPrice"`
+			History      []models.PriceChange `json:"history"`
+		}{
+			Currency:     h.Storage.GetCurrency(username),
+			InitialPrice: smoker.PackPrice,
+			CurrentPrice: helpers.GetPackPrice(smoker, prices, time.Now().UTC()),
+			History:      prices,
+		})
+	})
+}
+
+// DeletePrice удаляет изменение цены с даты {from} в формате ГГГГ-ММ-ДД
+func (h *Handlers) DeletePrice() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		from, err := time.Parse(time.DateOnly, r.PathValue("from"))
+		if err != nil {
+			return apperr.BadRequest(apperr.CodeInvalidDate)
+		}
+
+		if !h.Storage.DeletePriceChange(username, from) {
+			return apperr.NotFound(apperr.CodePriceNotFound)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	})
+}
+
+// PostCurrency сохраняет валюту, в которой курильщик покупает сигареты.
+// Суммы не пересчитываются: меняется только то, как они показываются
+func (h *Handlers) PostCurrency() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		currency := strings.ToUpper(r.FormValue("currency"))
+		if !i18n.SupportedCurrency(currency) {
+			return apperr.BadRequest(apperr.CodeUnsupportedCurrency)
+		}
+
+		h.Storage.SetCurrency(username, currency)
+
+		http.Redirect(w, r, backPath(r), http.StatusFound)
+		return nil
+	})
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/apperr"
+	"github.com/NarthurN/QuitSmoking/internal/helpers"
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/NarthurN/QuitSmoking/internal/webpush"
+)
+
+// Подписка браузера — небольшой JSON, больше принимать незачем
+const maxPushSubscriptionSize = 4 << 10
+
+// GetPushKey отдаёт публичный ключ VAPID, нужный браузеру для подписки на Web Push
+func (h *Handlers) GetPushKey() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		if h.VAPID == nil {
+			return apperr.New(http.StatusServiceUnavailable, apperr.CodePushUnavailable)
+		}
+
+		return writeJSON(w, http.StatusOK, map[string]string{"publicKey": h.VAPID.PublicKey()})
+	})
+}
+
+// PostPushSubscription сохраняет подписку браузера на Web Push
+func (h *Handlers) PostPushSubscription() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		var subscription models.PushSubscription
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPushSubscriptionSize)).Decode(&subscription); err != nil {
+			return apperr.BadRequest(apperr.CodeBadRequest)
+		}
+		if err := webpush.ValidateSubscription(r.Context(), subscription, h.Dev); err != nil {
+			h.Logger.Debug("handlers.PostPushSubscription.ValidateSubscription", helpers.SlogDebug(err.Error()))
+			return apperr.BadRequest(apperr.CodeInvalidSubscription)
+		}
+
+		subscription.Username = username
+		subscription.CreatedAt = time.Now().UTC()
+		h.Storage.SavePushSubscription(&subscription)
+
+		w.WriteHeader(http.StatusCreated)
+		return nil
+	})
+}
+
+// DeletePushSubscription удаляет подписку браузера, когда курильщик отключает push-уведомления
+func (h *Handlers) DeletePushSubscription() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		var subscription models.PushSubscription
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPushSubscriptionSize)).Decode(&subscription); err != nil {
+			return apperr.BadRequest(apperr.CodeBadRequest)
+		}
+
+		if !h.Storage.DeletePushSubscription(username, subscription.Endpoint) {
+			return apperr.NotFound(apperr.CodeSubscriptionNotFound)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	})
+}
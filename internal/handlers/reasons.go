@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/NarthurN/QuitSmoking/internal/apperr"
+	"github.com/NarthurN/QuitSmoking/internal/configs"
+	"github.com/NarthurN/QuitSmoking/internal/helpers"
+	"github.com/NarthurN/QuitSmoking/internal/media"
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/NarthurN/QuitSmoking/internal/motivation"
+	"github.com/NarthurN/QuitSmoking/internal/notify"
+)
+
+// Длина причины или подписи к фотографии, заголовка и текста письма в символах
+// и самый долгий срок, на который письмо можно запечатать
+const (
+	maxReasonLength      = 500
+	maxLetterTitleLength = 100
+	maxLetterLength      = 5000
+	maxOpenAfterDays     = 3650
+)
+
+// PostReason сохраняет причину бросить курить, которую курильщик увидит в трудную минуту
+func (h *Handlers) PostReason() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		text := strings.TrimSpace(r.FormValue("text"))
+		if text == "" || utf8.RuneCountInString(text) > maxReasonLength {
+			return apperr.BadRequest(apperr.CodeReasonLength, maxReasonLength)
+		}
+
+		h.Storage.AddReason(&models.Reason{
+			Username:  username,
+			Kind:      models.ReasonText,
+			Text:      text,
+			CreatedAt: time.Now().UTC(),
+		})
+
+		http.Redirect(w, r, `/profile`, http.StatusFound)
+		return nil
+	})
+}
+
+// PostReasonLetter сохраняет письмо себе. Письмо можно запечатать до нужного числа дней без сигарет
+func (h *Handlers) PostReasonLetter() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		title := strings.TrimSpace(r.FormValue("title"))
+		if utf8.RuneCountInString(title) > maxLetterTitleLength {
+			return apperr.BadRequest(apperr.CodeLetterTitleLength, maxLetterTitleLength)
+		}
+		text := strings.TrimSpace(r.FormValue("text"))
+		if text == "" || utf8.RuneCountInString(text) > maxLetterLength {
+			return apperr.BadRequest(apperr.CodeLetterLength, maxLetterLength)
+		}
+
+		var openAfterDays int
+		if raw := r.FormValue("openAfterDays"); raw != "" {
+			days, err := strconv.Atoi(raw)
+			if err != nil || days < 0 || days > maxOpenAfterDays {
+				return apperr.BadRequest(apperr.CodeOpenAfterDays, maxOpenAfterDays)
+			}
+			openAfterDays = days
+		}
+
+		h.Storage.AddReason(&models.Reason{
+			Username:      username,
+			Kind:          models.ReasonLetter,
+			Title:         title,
+			Text:          text,
+			OpenAfterDays: openAfterDays,
+			CreatedAt:     time.Now().UTC(),
+		})
+
+		http.Redirect(w, r, `/profile`, http.StatusFound)
+		return nil
+	})
+}
+
+// PostReasonPhoto загружает фотографию с подписью: JPEG, PNG или GIF не больше configs.MaxUploadSize
+func (h *Handlers) PostReasonPhoto() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		// Запас на подпись и служебные части multipart
+		r.Body = http.MaxBytesReader(w, r.Body, configs.MaxUploadSize+64<<10)
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				return apperr.New(http.StatusRequestEntityTooLarge, apperr.CodeFileTooLarge)
+			}
+			return apperr.BadRequest(apperr.CodeBadRequest).Wrap(err)
+		}
+		defer r.MultipartForm.RemoveAll()
+
+		caption := strings.TrimSpace(r.FormValue("text"))
+		if utf8.RuneCountInString(caption) > maxReasonLength {
+			return apperr.BadRequest(apperr.CodeCaptionLength, maxReasonLength)
+		}
+
+		file, _, err := r.FormFile("photo")
+		if err != nil {
+			return apperr.BadRequest(apperr.CodePhotoRequired)
+		}
+		defer file.Close()
+
+		data, contentType, err := media.ReadImage(file, media.Limits{MaxSize: configs.MaxUploadSize, MaxPixels: configs.MaxImagePixels})
+		switch {
+		case errors.Is(err, media.ErrTooLarge):
+			return apperr.New(http.StatusRequestEntityTooLarge, apperr.CodeFileTooLarge)
+		case errors.Is(err, media.ErrUnsupportedType), errors.Is(err, media.ErrInvalidImage):
+			return apperr.New(http.StatusUnsupportedMediaType, apperr.CodeUnsupportedImage)
+		case err != nil:
+			return apperr.BadRequest(apperr.CodeBadRequest).Wrap(err)
+		}
+
+		key, err := media.NewKey(contentType)
+		if err != nil {
+			return fmt.Errorf("handlers.PostReasonPhoto.NewKey: %w", err)
+		}
+		if err := h.Media.Save(r.Context(), key, data); err != nil {
+			return fmt.Errorf("handlers.PostReasonPhoto.Save: %w", err)
+		}
+
+		h.Storage.AddReason(&models.Reason{
+			Username:  username,
+			Kind:      models.ReasonPhoto,
+			Text:      caption,
+			MediaKey:  key,
+			MediaType: contentType,
+			CreatedAt: time.Now().UTC(),
+		})
+
+		http.Redirect(w, r, `/profile`, http.StatusFound)
+		return nil
+	})
+}
+
+// GetReasonPhoto отдаёт фотографию из мотивирующей записи. Фотографии видит только их владелец
+func (h *Handlers) GetReasonPhoto() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		reason, ok := h.Storage.GetReason(username, r.PathValue("id"))
+		if !ok || reason.Kind != models.ReasonPhoto {
+			return apperr.NotFound(apperr.CodePhotoNotFound)
+		}
+
+		file, err := h.Media.Open(r.Context(), reason.MediaKey)
+		if err != nil {
+			if errors.Is(err, media.ErrNotFound) {
+				return apperr.NotFound(apperr.CodePhotoNotFound).Wrap(err)
+			}
+			return fmt.Errorf("handlers.GetReasonPhoto.Open: %w", err)
+		}
+		defer file.Close()
+
+		w.Header().Set("Content-Type", reason.MediaType)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Cache-Control", "private, max-age=86400")
+		w.WriteHeader(http.StatusOK)
+		io.Copy(w, file)
+		return nil
+	})
+}
+
+// GetMotivation отображает запись, которую стоит показать курильщику сейчас, в формате JSON
+func (h *Handlers) GetMotivation() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		smoker, ok := h.Storage.GetSmoker(username)
+		if !ok {
+			return apperr.NotFound(apperr.CodeSmokerNotFound)
+		}
+
+		pick := h.pickMotivation(smoker)
+		if pick == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		}
+
+		return writeJSON(w, http.StatusOK, pick)
+	})
+}
+
+// pickMotivation выбирает запись по местному времени курильщика из его настроек уведомлений
+func (h *Handlers) pickMotivation(smoker *models.Smoker) *models.MotivationPick {
+	settings, ok := h.Storage.GetNotificationSettings(smoker.Username)
+	if !ok {
+		settings = notify.DefaultSettings(smoker.Username)
+	}
+	loc, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	now := time.Now()
+	return motivation.Pick(h.Storage.GetReasons(smoker.Username), motivation.Context{
+		Now:            now.In(loc),
+		StreakDays:     helpers.GetSmokeFreeDays(smoker),
+		RecentCravings: motivation.RecentCravings(h.Storage.GetCravings(smoker.Username), now),
+	}, rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())))
+}
+
+// GetReasons отображает мотивирующие записи курильщика в формате JSON
+func (h *Handlers) GetReasons() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		var streakDays int
+		if smoker, ok := h.Storage.GetSmoker(username); ok {
+			streakDays = helpers.GetSmokeFreeDays(smoker)
+		}
+
+		// Текст запечатанных писем не отдаём, пока не наступит их день
+		reasons := h.Storage.GetReasons(username)
+		for i, reason := range reasons {
+			if !motivation.Unlocked(reason, streakDays) {
+				sealed := *reason
+				sealed.Text = ""
+				reasons[i] = &sealed
+			}
+		}
+
+		return writeJSON(w, http.StatusOK, reasons)
+	})
+}
+
+// DeleteReason удаляет мотивирующую запись курильщика по id вместе с фотографией
+func (h *Handlers) DeleteReason() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		reason, ok := h.Storage.DeleteReason(username, r.PathValue("id"))
+		if !ok {
+			return apperr.NotFound(apperr.CodeReasonNotFound)
+		}
+		if reason.MediaKey != "" {
+			if err := h.Media.Delete(r.Context(), reason.MediaKey); err != nil {
+				h.Logger.Error("handlers.DeleteReason.Delete", helpers.SlogErr(err))
+			}
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	})
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/apperr"
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/NarthurN/QuitSmoking/internal/reduction"
+	"github.com/NarthurN/QuitSmoking/internal/validate"
+)
+
+// PostReductionPlan включает режим постепенного сокращения с датой отказа от курения.
+// До этой даты время без сигарет не считается, после неё начинается обычное отслеживание
+func (h *Handlers) PostReductionPlan() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		smoker, ok := h.Storage.GetSmoker(username)
+		if !ok {
+			return apperr.NotFound(apperr.CodeSmokerNotFound)
+		}
+
+		today := time.Now().UTC().Truncate(24 * time.Hour)
+
+		quitDate, err := time.Parse(time.DateOnly, r.FormValue("quitDate"))
+		if err != nil {
+			return apperr.BadRequest(apperr.CodeInvalidDate).
+				WithFields(apperr.FieldError{Field: "quitDate", Code: validate.CodeInvalid})
+		}
+		if !quitDate.After(today) {
+			return apperr.BadRequest(apperr.CodeQuitDateFuture)
+		}
+
+		startAllowance := smoker.CigarettesPerDay
+		if raw := r.FormValue("startAllowance"); raw != "" {
+			startAllowance, err = strconv.Atoi(raw)
+			if err != nil || startAllowance <= 0 {
+				return apperr.BadRequest(apperr.CodeStartAllowance)
+			}
+		}
+		if startAllowance <= 0 {
+			return apperr.BadRequest(apperr.CodeStartAllowanceRequired)
+		}
+
+		taper := r.FormValue("taper")
+		if taper == "" {
+			taper = models.TaperLinear
+		}
+		if taper != models.TaperLinear && taper != models.TaperExponential {
+			return apperr.BadRequest(apperr.CodeUnknownTaper)
+		}
+
+		// Новый план заменяет прежний, но дата отказа до планов остаётся прежней
+		current := h.Storage.GetReductionPlan(username)
+		var previous time.Time
+		updated, ok, err := h.Storage.UpdateSmoker(smoker.ID, func(smoker *models.Smoker) error {
+			previous = smoker.StoppedSmoking
+			if current != nil {
+				previous = current.PreviousStoppedSmoking
+			}
+			smoker.StoppedSmoking = quitDate
+			smoker.Version++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return apperr.NotFound(apperr.CodeSmokerNotFound)
+		}
+
+		h.Storage.SetReductionPlan(&models.ReductionPlan{
+			Username:               username,
+			StartDate:              today,
+			QuitDate:               quitDate,
+			StartAllowance:         startAllowance,
+			Taper:                  taper,
+			PreviousStoppedSmoking: previous,
+		})
+		h.audit(r, username, models.AuditSmokerUpdate, updated.Username, "stoppedSmoking")
+
+		http.Redirect(w, r, `/profile`, http.StatusFound)
+		return nil
+	})
+}
+
+// GetReductionPlan отображает план сокращения с расписанием в формате JSON
+func (h *Handlers) GetReductionPlan() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		plan := h.Storage.GetReductionPlan(username)
+		if plan == nil {
+			return apperr.NotFound(apperr.CodePlanNotFound)
+		}
+
+		now := time.Now().UTC()
+		smoked := h.Storage.GetSmokedByDay(username)
+		today, _ := reduction.Today(plan, smoked, now)
+
+		return writeJSON(w, http.StatusOK, struct {
+			*models.ReductionPlan
+			Active   bool                    `json:"active"`
+			Today    models.DailyAllowance   `json:"today"`
+			Schedule []models.DailyAllowance `json:"schedule"`
+		}{
+			ReductionPlan: plan,
+			Active:        reduction.Active(plan, now),
+			Today:         today,
+			Schedule:      reduction.Schedule(plan, smoked),
+		})
+	})
+}
+
+// PostCigarette записывает выкуренные сигареты в дневной учёт плана сокращения
+func (h *Handlers) PostCigarette() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		if !reduction.Active(h.Storage.GetReductionPlan(username), now) {
+			return apperr.New(http.StatusConflict, apperr.CodePlanInactive)
+		}
+
+		count := 1
+		if raw := r.FormValue("count"); raw != "" {
+			var err error
+			count, err = strconv.Atoi(raw)
+			if err != nil || count <= 0 {
+				return apperr.BadRequest(apperr.CodeCigarettesCount)
+			}
+		}
+
+		h.Storage.AddCigarettes(username, now, count)
+
+		http.Redirect(w, r, `/profile`, http.StatusFound)
+		return nil
+	})
+}
@@ -0,0 +1,473 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/apperr"
+	"github.com/NarthurN/QuitSmoking/internal/configs"
+	"github.com/NarthurN/QuitSmoking/internal/helpers"
+	"github.com/NarthurN/QuitSmoking/internal/mergepatch"
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/NarthurN/QuitSmoking/internal/smokers"
+	"github.com/NarthurN/QuitSmoking/internal/validate"
+)
+
+// Допустимые символы в id и username курильщика
+var (
+	smokerIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+)
+
+// Самая ранняя дата отказа от курения, которую принимаем: защищает от нулевой даты
+var earliestQuitDate = time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func smokerID(s *models.Smoker) string                { return s.ID }
+func smokerUsername(s *models.Smoker) string          { return s.Username }
+func smokerVersion(s *models.Smoker) int              { return s.Version }
+func smokerCreatedAt(s *models.Smoker) time.Time      { return s.CreatedAt }
+func smokerStoppedSmoking(s *models.Smoker) time.Time { return s.StoppedSmoking }
+
+// Правила для полей курильщика, общие для создания и изменения
+var (
+	smokerIDRule = validate.Field("id", smokerID,
+		validate.NotBlank(), validate.MaxLength(64), validate.Match(smokerIDPattern))
+	smokerUsernameRule = validate.Field("username", smokerUsername,
+		validate.NotBlank(), validate.MinLength(3), validate.MaxLength(32), validate.Match(usernamePattern))
+	smokerNameRule = validate.Field("name", func(s *models.Smoker) string { return s.Name },
+		validate.NotBlank(), validate.MaxLength(100))
+	stoppedSmokingRule = validate.Field("stoppedSmoking", smokerStoppedSmoking,
+		validate.NotBefore(earliestQuitDate))
+	// План снижения сам ставит дату отказа в будущем, поэтому при изменении курильщика
+	// дата проверяется на будущее, только если её меняют
+	stoppedSmokingPastRule = validate.Field("stoppedSmoking", smokerStoppedSmoking,
+		validate.NotFuture(time.Now))
+	// Пароль проверяется, только когда его задают: у старых учётных записей он бывает короче
+	smokerPasswordRule = validate.Field("password", func(s *models.Smoker) string { return s.Password },
+		validate.NotBlank(), validate.MinLength(6), validate.MaxLength(72))
+)
+
+// smokerProfileRules правила для полей, которые курильщик может менять, кроме пароля
+var smokerProfileRules = validate.Rules[models.Smoker]{
+	smokerNameRule,
+	stoppedSmokingRule,
+	validate.Field("cigarettesPerDay", func(s *models.Smoker) int { return s.CigarettesPerDay },
+		validate.Range(1, 200)),
+	validate.Field("packSize", func(s *models.Smoker) int { return s.PackSize },
+		validate.Range(1, 100)),
+	validate.Field("packPrice", func(s *models.Smoker) float64 { return s.PackPrice },
+		validate.Range(0.01, maxPackPrice)),
+}
+
+// newSmokerRules правила для нового курильщика
+var newSmokerRules = append(validate.Rules[models.Smoker]{smokerIDRule, smokerUsernameRule, smokerPasswordRule, stoppedSmokingPastRule}, smokerProfileRules...)
+
+// immutableSmokerRules запрещают менять id, username, версию и дату регистрации: по username
+// курильщик входит, по id его находят в API, а версию и дату регистрации ставит сервер
+func immutableSmokerRules(current *models.Smoker) validate.Rules[models.Smoker] {
+	return validate.Rules[models.Smoker]{
+		validate.Field("id", smokerID, validate.Unchanged(current.ID)),
+		validate.Field("username", smokerUsername, validate.Unchanged(current.Username)),
+		validate.Field("version", smokerVersion, validate.Unchanged(current.Version)),
+		validate.Field("createdAt", smokerCreatedAt, validate.Unchanged(current.CreatedAt)),
+	}
+}
+
+// canManageSmoker данные курильщика может менять он сам или администратор
+func canManageSmoker(username string, smoker *models.Smoker) bool {
+	return smoker.Username == username || helpers.HasPermission(username, configs.AdminPermission)
+}
+
+// smokerETag сильный ETag версии курильщика
+func smokerETag(smoker *models.Smoker) string {
+	return `"` + strconv.Itoa(smoker.Version) + `"`
+}
+
+// checkIfMatch сравнивает If-Match с ETag по строгому правилу RFC 9110: слабые
+// ETag не совпадают ни с чем. Без заголовка проверка проходит, если он не обязателен
+func checkIfMatch(r *http.Request, etag string, required bool) error {
+	header := strings.Join(r.Header.Values("If-Match"), ",")
+	if header == "" {
+		if required {
+			return apperr.New(http.StatusPreconditionRequired, apperr.CodePreconditionRequired)
+		}
+		return nil
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == etag {
+			return nil
+		}
+	}
+	return apperr.New(http.StatusPreconditionFailed, apperr.CodePreconditionFailed)
+}
+
+// noneMatch сообщает, что If-None-Match совпал с ETag и можно ответить 304.
+// Для If-None-Match ETag сравниваются без учёта слабости
+func noneMatch(r *http.Request, etag string) bool {
+	for _, tag := range strings.Split(strings.Join(r.Header.Values("If-None-Match"), ","), ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writeSmoker отвечает данными курильщика без пароля и с ETag его версии
+func writeSmoker(w http.ResponseWriter, status int, smoker *models.Smoker) error {
+	view := *smoker
+	view.Password = ""
+
+	w.Header().Set("ETag", smokerETag(smoker))
+	return writeJSON(w, status, &view)
+}
+
+// smokerListItem курильщик в списке администратора
+type smokerListItem struct {
+	models.Smoker
+	Roles         []string `json:"roles"`
+	SmokeFreeDays int      `json:"smokeFreeDays"`
+}
+
+// GetSmokers отображает страницу списка курильщиков в формате JSON.
+// Параметры: quitFrom и quitTo (ГГГГ-ММ-ДД, включительно), role, q (поиск по имени
+// и username), sort (smokeFree, createdAt, с "-" по убыванию), cursor и limit (до 100).
+// total — сколько курильщиков подходит под фильтр, all — сколько их всего
+func (h *Handlers) GetSmokers() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		query, err := smokersQuery(r)
+		if err != nil {
+			return err
+		}
+
+		all := h.Storage.AllSmokers()
+		page, err := listSmokers(all, query)
+		if err != nil {
+			return err
+		}
+
+		items := make([]smokerListItem, 0, len(page.Smokers))
+		for _, smoker := range page.Smokers {
+			item := smokerListItem{
+				Smoker:        *smoker,
+				Roles:         helpers.GetRoles(smoker.Username),
+				SmokeFreeDays: helpers.GetSmokeFreeDays(smoker),
+			}
+			item.Password = ""
+			items = append(items, item)
+		}
+
+		return writeJSON(w, http.StatusOK, struct {
+			Items      []smokerListItem `json:"items"`
+			Total      int              `json:"total"`
+			All        int              `json:"all"`
+			NextCursor string           `json:"nextCursor,omitempty"`
+		}{
+			Items:      items,
+			Total:      page.Total,
+			All:        len(all),
+			NextCursor: page.NextCursor,
+		})
+	})
+}
+
+// listSmokers выбирает страницу списка. Испорченный курсор — ошибка в параметре cursor
+func listSmokers(all []*models.Smoker, query smokers.Query) (smokers.Page, error) {
+	page, err := smokers.List(all, helpers.GetRoles, query)
+	if errors.Is(err, smokers.ErrInvalidCursor) {
+		return smokers.Page{}, apperr.BadRequest(apperr.CodeInvalidQuery).
+			WithFields(apperr.FieldError{Field: "cursor", Code: validate.CodeInvalid})
+	}
+	return page, err
+}
+
+// smokersQuery разбирает параметры GetSmokers. Ошибки во всех параметрах возвращаются разом
+func smokersQuery(r *http.Request) (smokers.Query, error) {
+	values := r.URL.Query()
+	query := smokers.Query{
+		Filter: smokers.Filter{
+			Role:   values.Get("role"),
+			Search: strings.TrimSpace(values.Get("q")),
+		},
+		Sort:   values.Get("sort"),
+		Cursor: values.Get("cursor"),
+		Limit:  smokers.DefaultLimit,
+	}
+
+	var fields []apperr.FieldError
+	date := func(name string) time.Time {
+		raw := values.Get(name)
+		if raw == "" {
+			return time.Time{}
+		}
+		t, err := time.Parse(time.DateOnly, raw)
+		if err != nil {
+			fields = append(fields, apperr.FieldError{Field: name, Code: validate.CodeInvalid})
+		}
+		return t
+	}
+	query.QuitFrom = date("quitFrom")
+	// Дата окончания включительно: до конца этого дня
+	if quitTo := date("quitTo"); !quitTo.IsZero() {
+		query.QuitTo = quitTo.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+
+	if query.Role != "" && !slices.Contains(configs.Roles, query.Role) {
+		fields = append(fields, apperr.FieldError{Field: "role", Code: validate.CodeInvalid})
+	}
+	if query.Sort != "" && !smokers.ValidSort(query.Sort) {
+		fields = append(fields, apperr.FieldError{Field: "sort", Code: validate.CodeInvalid})
+	}
+	if raw := values.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > smokers.MaxLimit {
+			fields = append(fields, apperr.FieldError{Field: "limit", Code: validate.CodeRange, Args: []any{1, smokers.MaxLimit}})
+		}
+		query.Limit = limit
+	}
+
+	if len(fields) > 0 {
+		return smokers.Query{}, apperr.BadRequest(apperr.CodeInvalidQuery).WithFields(fields...)
+	}
+	return query, nil
+}
+
+// GetSmoker отображает курильщика по id в формате JSON. ETag ответа нужен для If-Match в PatchSmoker
+func (h *Handlers) GetSmoker() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		smoker, ok := h.Storage.FindSmoker(r.PathValue("id"))
+		if !ok {
+			return apperr.NotFound(apperr.CodeSmokerNotFound)
+		}
+		if !canManageSmoker(username, smoker) {
+			return apperr.New(http.StatusForbidden, apperr.CodeForbidden)
+		}
+
+		if noneMatch(r, smokerETag(smoker)) {
+			w.Header().Set("ETag", smokerETag(smoker))
+			w.WriteHeader(http.StatusNotModified)
+			return nil
+		}
+		return writeSmoker(w, http.StatusOK, smoker)
+	})
+}
+
+// PostSmoker создаёт нового Smoker. Создавать курильщиков может только администратор
+func (h *Handlers) PostSmoker() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+		if !helpers.HasPermission(username, configs.AdminPermission) {
+			return apperr.New(http.StatusForbidden, apperr.CodeForbidden)
+		}
+
+		var smoker models.Smoker
+		if err := decodeJSON(w, r, &smoker); err != nil {
+			return err
+		}
+		if err := newSmokerRules.Validate(&smoker); err != nil {
+			return invalid(err)
+		}
+		smoker.Version = 1
+		smoker.CreatedAt = time.Now().UTC()
+
+		if !h.Storage.AddSmoker(&smoker) {
+			return apperr.New(http.StatusConflict, apperr.CodeSmokerExists)
+		}
+		h.audit(r, username, models.AuditSmokerCreate, smoker.Username, "")
+
+		w.Header().Set("Location", "/smokers/"+smoker.ID)
+		return writeSmoker(w, http.StatusCreated, &smoker)
+	})
+}
+
+// DeleteSmoker удаляет Smoker по id. Если передан If-Match, удаляет только эту версию
+func (h *Handlers) DeleteSmoker() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		// Проверка версии и удаление идут под одной блокировкой хранилища,
+		// чтобы между ними курильщика не успели изменить
+		smoker, ok, err := h.Storage.DeleteSmoker(r.PathValue("id"), func(smoker *models.Smoker) error {
+			if !canManageSmoker(username, smoker) {
+				return apperr.New(http.StatusForbidden, apperr.CodeForbidden)
+			}
+			return checkIfMatch(r, smokerETag(smoker), false)
+		})
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return apperr.NotFound(apperr.CodeSmokerNotFound)
+		}
+
+		h.audit(r, username, models.AuditSmokerDelete, smoker.Username, "")
+
+		message := map[string]string{"message": h.localizer(r).T("smoker.deleted"), "id": smoker.ID}
+		return writeJSON(w, http.StatusOK, message)
+	})
+}
+
+// PutSmoker обновляет имя и дату отказа от курения по id. Id в теле должен совпадать с прежним.
+// If-Match необязателен, но если передан, изменение пройдёт только для этой версии
+func (h *Handlers) PutSmoker() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		var input models.Smoker
+		if err := decodeJSON(w, r, &input); err != nil {
+			return err
+		}
+
+		updated, ok, err := h.Storage.UpdateSmoker(r.PathValue("id"), func(smoker *models.Smoker) error {
+			if !canManageSmoker(username, smoker) {
+				return apperr.New(http.StatusForbidden, apperr.CodeForbidden)
+			}
+			if err := checkIfMatch(r, smokerETag(smoker), false); err != nil {
+				return err
+			}
+
+			rules := validate.Rules[models.Smoker]{
+				validate.Field("id", smokerID, validate.Unchanged(smoker.ID)),
+				smokerNameRule,
+				stoppedSmokingRule,
+			}
+			if !input.StoppedSmoking.Equal(smoker.StoppedSmoking) {
+				rules = append(rules, stoppedSmokingPastRule)
+			}
+			if err := rules.Validate(&input); err != nil {
+				return invalid(err)
+			}
+
+			smoker.Name = input.Name
+			smoker.StoppedSmoking = input.StoppedSmoking
+			smoker.Version++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return apperr.NotFound(apperr.CodeSmokerNotFound)
+		}
+		h.audit(r, username, models.AuditSmokerUpdate, updated.Username, "name, stoppedSmoking")
+
+		return writeSmoker(w, http.StatusOK, updated)
+	})
+}
+
+// PatchSmoker частично обновляет курильщика по JSON Merge Patch (RFC 7396).
+// Нужен If-Match с ETag текущей версии: без него 428, с устаревшим — 412
+func (h *Handlers) PatchSmoker() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		const op = "handlers.PatchSmoker"
+
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		if err := requireContentType(r, mergepatch.ContentType); err != nil {
+			return err
+		}
+		patch, err := io.ReadAll(http.MaxBytesReader(w, r.Body, configs.MaxJSONBodySize))
+		if err != nil {
+			return decodeError(err)
+		}
+		// Патч не объект заменил бы курильщика целиком, а это не изменение полей
+		if !bytes.HasPrefix(bytes.TrimSpace(patch), []byte("{")) {
+			return apperr.BadRequest(apperr.CodeInvalidJSON)
+		}
+
+		fields := patchedFields(patch)
+
+		var passwordChanged bool
+		updated, ok, err := h.Storage.UpdateSmoker(r.PathValue("id"), func(smoker *models.Smoker) error {
+			if !canManageSmoker(username, smoker) {
+				return apperr.New(http.StatusForbidden, apperr.CodeForbidden)
+			}
+			if err := checkIfMatch(r, smokerETag(smoker), true); err != nil {
+				return err
+			}
+
+			doc, err := json.Marshal(smoker)
+			if err != nil {
+				return fmt.Errorf("%s.Marshal: %w", op, err)
+			}
+			patched, err := mergepatch.Apply(doc, patch)
+			if err != nil {
+				return apperr.BadRequest(apperr.CodeInvalidJSON).Wrap(err)
+			}
+
+			var next models.Smoker
+			if err := decodeStrict(bytes.NewReader(patched), &next); err != nil {
+				return err
+			}
+			rules := append(immutableSmokerRules(smoker), smokerProfileRules...)
+			if slices.Contains(fields, "password") {
+				rules = append(rules, smokerPasswordRule)
+			}
+			if !next.StoppedSmoking.Equal(smoker.StoppedSmoking) {
+				rules = append(rules, stoppedSmokingPastRule)
+			}
+			if err := rules.Validate(&next); err != nil {
+				return invalid(err)
+			}
+
+			passwordChanged = next.Password != smoker.Password
+			next.Version++
+			*smoker = next
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return apperr.NotFound(apperr.CodeSmokerNotFound)
+		}
+		if passwordChanged {
+			h.Storage.RevokeTokens(updated.Username)
+		}
+		h.audit(r, username, models.AuditSmokerUpdate, updated.Username, strings.Join(fields, ", "))
+
+		return writeSmoker(w, http.StatusOK, updated)
+	})
+}
+
+// patchedFields поля верхнего уровня, которые меняет патч. Они же идут в журнал аудита,
+// а сами значения в журнал не попадают: среди них может быть пароль
+func patchedFields(patch []byte) []string {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &fields); err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
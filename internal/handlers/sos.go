@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/apperr"
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/NarthurN/QuitSmoking/internal/notify"
+	"github.com/NarthurN/QuitSmoking/internal/sos"
+)
+
+// PostSOS начинает SOS-сессию, когда хочется закурить. Если сессия уже идёт,
+// курильщик возвращается в неё. По желанию напарникам уходит просьба о поддержке
+func (h *Handlers) PostSOS() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		smoker, ok := h.Storage.GetSmoker(username)
+		if !ok {
+			return apperr.NotFound(apperr.CodeSmokerNotFound)
+		}
+
+		now := time.Now().UTC()
+		if session, ok := h.Storage.GetOpenSOSSession(username, now); ok {
+			http.Redirect(w, r, `/sos/`+session.ID, http.StatusFound)
+			return nil
+		}
+
+		var reasons []string
+		for _, reason := range h.Storage.GetReasons(username) {
+			if reason.Kind == models.ReasonText {
+				reasons = append(reasons, reason.Text)
+			}
+		}
+		steps := sos.Plan(reasons, rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())))
+
+		session := &models.SOSSession{
+			Username:  username,
+			StartedAt: now,
+			EndsAt:    now.Add(sos.Duration(steps)),
+			Steps:     steps,
+		}
+		h.Storage.AddSOSSession(session)
+
+		if r.FormValue("notifyBuddies") == "on" {
+			asked := notify.CravingHelp(h.Storage, h.I18n, username, smoker.Name, "", now)
+			h.Storage.SetSOSBuddiesNotified(session.ID, asked)
+		}
+
+		http.Redirect(w, r, `/sos/`+session.ID, http.StatusFound)
+		return nil
+	})
+}
+
+// GetSOSPage отображает SOS-сессию. Шаги сменяет скрипт, спрашивая состояние у сервера
+func (h *Handlers) GetSOSPage() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		session, ok := h.Storage.GetSOSSession(r.PathValue("id"))
+		if !ok || session.Username != username {
+			return apperr.NotFound(apperr.CodeSessionNotFound)
+		}
+
+		data := struct {
+			Name   string
+			Unread int
+			State  models.SOSState
+		}{
+			Name:   h.smokerName(username),
+			Unread: h.Storage.CountUnread(username),
+			State:  sos.State(session, time.Now().UTC()),
+		}
+
+		h.render(w, r, http.StatusOK, "sos.html", data)
+		return nil
+	})
+}
+
+// GetSOSState отображает текущий шаг SOS-сессии в формате JSON
+func (h *Handlers) GetSOSState() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		session, ok := h.Storage.GetSOSSession(r.PathValue("id"))
+		if !ok || session.Username != username {
+			return apperr.NotFound(apperr.CodeSessionNotFound)
+		}
+
+		return writeJSON(w, http.StatusOK, sos.State(session, time.Now().UTC()))
+	})
+}
+
+// FinishSOS завершает SOS-сессию и записывает её итог в дневник тяги
+func (h *Handlers) FinishSOS() http.HandlerFunc {
+	return h.handle(func(w http.ResponseWriter, r *http.Request) error {
+		username, err := currentUser(r)
+		if err != nil {
+			return err
+		}
+
+		session, ok := h.Storage.GetSOSSession(r.PathValue("id"))
+		if !ok || session.Username != username {
+			return apperr.NotFound(apperr.CodeSessionNotFound)
+		}
+
+		outcome := r.FormValue("outcome")
+		if outcome != models.SOSOutcomeResisted && outcome != models.SOSOutcomeSmoked {
+			return apperr.BadRequest(apperr.CodeUnknownOutcome)
+		}
+
+		note := strings.TrimSpace(r.FormValue("note"))
+		if note == "" {
+			note = h.localizer(r).T("sos.craving_note")
+		}
+
+		craving := &models.Craving{
+			Username: username,
+			At:       time.Now().UTC(),
+			Resisted: outcome == models.SOSOutcomeResisted,
+			Note:     note,
+		}
+		if !h.Storage.FinishSOSSession(session.ID, outcome, craving) {
+			return apperr.New(http.StatusConflict, apperr.CodeSessionFinished)
+		}
+
+		http.Redirect(w, r, `/profile`, http.StatusFound)
+		return nil
+	})
+}
@@ -3,15 +3,23 @@ package helpers
 import (
 	"fmt"
 	"log/slog"
+	"math"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/NarthurN/QuitSmoking/internal/configs"
+	"github.com/NarthurN/QuitSmoking/internal/i18n"
 	"github.com/NarthurN/QuitSmoking/internal/models"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-type Tokener struct{}
+type Tokener struct {
+	// Generation если задан, возвращает текущее поколение токенов курильщика,
+	// которое записывается в каждый выданный токен
+	Generation func(username string) int
+}
 
 func NewTokener() *Tokener {
 	return &Tokener{}
@@ -19,18 +27,21 @@ func NewTokener() *Tokener {
 
 func (t *Tokener) GetJwtToken(username string) (string, error) {
 	op := "helpers.GetJwtToken"
-	expirationTime := time.Now().UTC().Add(5 * time.Minute)
 
-	claims := &models.Claims{
-		Username: username,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-		},
+	tokenString, err := signToken(&models.Claims{Username: username, Generation: t.generation(username)})
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return tokenString, nil
+}
+
+// GetImpersonationToken выдаёт администратору impersonator токен, с которым он видит
+// приложение глазами курильщика username. Кто вошёл на самом деле, хранится в токене
+func (t *Tokener) GetImpersonationToken(username, impersonator string) (string, error) {
+	op := "helpers.GetImpersonationToken"
 
-	tokenString, err := token.SignedString([]byte(configs.JwtKey))
+	tokenString, err := signToken(&models.Claims{Username: username, Impersonator: impersonator, Generation: t.generation(username)})
 	if err != nil {
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
@@ -38,6 +49,24 @@ func (t *Tokener) GetJwtToken(username string) (string, error) {
 	return tokenString, nil
 }
 
+func (t *Tokener) generation(username string) int {
+	if t.Generation == nil {
+		return 0
+	}
+	return t.Generation(username)
+}
+
+// signToken подписывает claims. Токен действует пять минут
+func signToken(claims *models.Claims) (string, error) {
+	expirationTime := time.Now().UTC().Add(5 * time.Minute)
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(expirationTime),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(configs.JwtKey))
+}
+
 func (t *Tokener) VerifyUser(token string) (*models.Claims, error) {
 	op := "helpers.VerifyUser"
 	claims := &models.Claims{}
@@ -53,8 +82,6 @@ func (t *Tokener) VerifyUser(token string) (*models.Claims, error) {
 		return claims, fmt.Errorf("%s: %w", op, err)
 	}
 
-
-
 	return claims, nil
 }
 
@@ -65,22 +92,80 @@ func (t *Tokener) AllowedPath(path string, m map[string]struct{}) bool {
 	return false
 }
 
+// CheckPermision проверяет, что у пользователя есть одна из ролей, нужных для пути.
+// Путь из configs.PathsRoles, который заканчивается на "/", закрывает и все вложенные пути
 func (t *Tokener) CheckPermision(username, path string) bool {
 	requiredRoles, ok := configs.PathsRoles[path]
-	if ok {
-		rolesOfUser, ok := configs.UserRoles[username]
-		if !ok {
-			return false
+	if !ok {
+		for prefix, roles := range configs.PathsRoles {
+			if strings.HasSuffix(prefix, "/") && strings.HasPrefix(path, prefix) {
+				requiredRoles, ok = roles, true
+				break
+			}
 		}
-		for _, requiredRole := range requiredRoles {
-			for _, roleOfUser := range rolesOfUser {
-				if requiredRole == roleOfUser {
-					return true
-				}
+	}
+	if !ok {
+		return true
+	}
+
+	for _, requiredRole := range requiredRoles {
+		if HasRole(username, requiredRole) {
+			return true
+		}
+	}
+	return false
+}
+
+// rolesMu защищает configs.UserRoles: администратор меняет роли, пока идут запросы
+var rolesMu sync.RWMutex
+
+// GetRoles возвращает роли пользователя. У пользователя без особых ролей есть только configs.UserRole
+func GetRoles(username string) []string {
+	rolesMu.RLock()
+	defer rolesMu.RUnlock()
+
+	if roles, ok := configs.UserRoles[username]; ok {
+		return slices.Clone(roles)
+	}
+	return []string{configs.UserRole}
+}
+
+// SetRoles заменяет особые роли пользователя. configs.UserRole есть у всех, поэтому не хранится
+func SetRoles(username string, roles []string) {
+	rolesMu.Lock()
+	defer rolesMu.Unlock()
+
+	var special []string
+	for _, role := range roles {
+		if role != configs.UserRole && !slices.Contains(special, role) {
+			special = append(special, role)
+		}
+	}
+	if len(special) == 0 {
+		delete(configs.UserRoles, username)
+		return
+	}
+	configs.UserRoles[username] = special
+}
+
+// HasRole проверяет, есть ли у пользователя роль
+func HasRole(username, role string) bool {
+	return slices.Contains(GetRoles(username), role)
+}
+
+// HasPermission проверяет, есть ли у пользователя привилегия через одну из его ролей
+func HasPermission(username, permission string) bool {
+	rolesMu.RLock()
+	defer rolesMu.RUnlock()
+
+	for _, role := range configs.UserRoles[username] {
+		for _, p := range configs.RolePermissions[role] {
+			if p == permission {
+				return true
 			}
 		}
 	}
-	return true
+	return false
 }
 
 func SlogErr(err error) slog.Attr {
@@ -98,10 +183,15 @@ func SlogDebug(str string) slog.Attr {
 }
 
 // GetSmokersDiffTime возвращает промежуток времени между StoppedSmoking и времени "сейчас"
-func GetSmokersDiffTime(smoker *models.Smoker) string {
+// на языке loc, например "1 год, 2 месяца, 5 дней, 21 час"
+func GetSmokersDiffTime(smoker *models.Smoker, loc *i18n.Localizer) string {
 	now := time.Now().UTC()
 
 	diff := now.Sub(smoker.StoppedSmoking)
+	// Дата отказа ещё не наступила (режим сокращения)
+	if diff < 0 {
+		diff = 0
+	}
 
 	// Преобразуем разницу в годы, месяцы, дни и часы
 	years := int(diff.Hours() / 24 / 365)
@@ -115,7 +205,126 @@ func GetSmokersDiffTime(smoker *models.Smoker) string {
 
 	hours := int(remaining.Hours())
 
-	timePassed := fmt.Sprintf("%d лет, %d месяцев, %d дней, %d часов", years, months, days, hours)
+	timePassed := strings.Join([]string{
+		loc.Plural("duration.years", years),
+		loc.Plural("duration.months", months),
+		loc.Plural("duration.days", days),
+		loc.Plural("duration.hours", hours),
+	}, ", ")
 
 	return timePassed
-}
\ No newline at end of file
+}
+
+// GetSmokeFreeDays возвращает количество полных дней без сигарет
+func GetSmokeFreeDays(smoker *models.Smoker) int {
+	return getSmokeFreeDaysAt(smoker, time.Now().UTC())
+}
+
+func getSmokeFreeDaysAt(smoker *models.Smoker, at time.Time) int {
+	diff := at.Sub(smoker.StoppedSmoking)
+	if diff < 0 {
+		return 0
+	}
+	return int(diff.Hours() / 24)
+}
+
+// GetDailyCost возвращает, сколько курильщик тратил на сигареты в день по цене пачки packPrice
+func GetDailyCost(smoker *models.Smoker, packPrice float64) float64 {
+	if smoker.PackSize <= 0 {
+		return 0
+	}
+	return float64(smoker.CigarettesPerDay) / float64(smoker.PackSize) * packPrice
+}
+
+// GetPackPrice возвращает цену пачки, действовавшую в момент at. До первого изменения
+// из истории prices (по возрастанию From) действует цена, указанная при регистрации
+func GetPackPrice(smoker *models.Smoker, prices []models.PriceChange, at time.Time) float64 {
+	price := smoker.PackPrice
+	for _, change := range prices {
+		if change.From.After(at) {
+			break
+		}
+		price = change.Price
+	}
+	return price
+}
+
+// GetSavedBetween возвращает сумму, сэкономленную с from до to: каждый отрезок времени
+// считается по цене пачки, которая тогда действовала
+func GetSavedBetween(smoker *models.Smoker, prices []models.PriceChange, from, to time.Time) float64 {
+	if !to.After(from) {
+		return 0
+	}
+
+	var saved float64
+	start, price := from, GetPackPrice(smoker, prices, from)
+	for _, change := range prices {
+		if !change.From.After(start) {
+			continue
+		}
+		if !change.From.Before(to) {
+			break
+		}
+		saved += change.From.Sub(start).Hours() / 24 * GetDailyCost(smoker, price)
+		start, price = change.From, change.Price
+	}
+	saved += to.Sub(start).Hours() / 24 * GetDailyCost(smoker, price)
+
+	return math.Round(saved*100) / 100
+}
+
+// GetMoneySaved возвращает сумму, сэкономленную с момента отказа от курения
+func GetMoneySaved(smoker *models.Smoker, prices []models.PriceChange) float64 {
+	return GetSavedBetween(smoker, prices, smoker.StoppedSmoking, time.Now().UTC())
+}
+
+// GetJournalStreak возвращает количество дней подряд (до сегодняшнего или вчерашнего дня),
+// в которые курильщик делал записи в дневнике тяги
+func GetJournalStreak(cravings []*models.Craving) int {
+	days := make(map[time.Time]struct{}, len(cravings))
+	for _, c := range cravings {
+		days[c.At.UTC().Truncate(24*time.Hour)] = struct{}{}
+	}
+
+	day := time.Now().UTC().Truncate(24 * time.Hour)
+	if _, ok := days[day]; !ok {
+		day = day.Add(-24 * time.Hour)
+	}
+
+	streak := 0
+	for {
+		if _, ok := days[day]; !ok {
+			return streak
+		}
+		streak++
+		day = day.Add(-24 * time.Hour)
+	}
+}
+
+// GoalStats возвращает показатели, по которым считаются цели, на любой момент:
+// дни без сигарет и экономию
+func GoalStats(smoker *models.Smoker, prices []models.PriceChange) func(at time.Time) models.SmokerStats {
+	return func(at time.Time) models.SmokerStats {
+		return models.SmokerStats{
+			DaysSmokeFree: getSmokeFreeDaysAt(smoker, at),
+			MoneySaved:    GetSavedBetween(smoker, prices, smoker.StoppedSmoking, at),
+		}
+	}
+}
+
+// GetSmokerStats собирает показатели курильщика для проверки достижений
+func GetSmokerStats(smoker *models.Smoker, cravings []*models.Craving, prices []models.PriceChange) models.SmokerStats {
+	resisted := 0
+	for _, c := range cravings {
+		if c.Resisted {
+			resisted++
+		}
+	}
+
+	return models.SmokerStats{
+		DaysSmokeFree:    GetSmokeFreeDays(smoker),
+		MoneySaved:       GetMoneySaved(smoker, prices),
+		CravingsResisted: resisted,
+		JournalStreak:    GetJournalStreak(cravings),
+	}
+}
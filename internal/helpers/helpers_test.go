@@ -0,0 +1,82 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/configs"
+	"github.com/NarthurN/QuitSmoking/internal/i18n"
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSavedBetween(t *testing.T) {
+	day := 24 * time.Hour
+	start := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	// Пачка в день: экономия за день равна цене пачки
+	smoker := &models.Smoker{StoppedSmoking: start, CigarettesPerDay: 20, PackSize: 20, PackPrice: 100}
+
+	// Без истории всё считается по цене при регистрации
+	assert.Equal(t, 1000.0, GetSavedBetween(smoker, nil, start, start.Add(10*day)))
+
+	// 5 дней по 100, 3 дня по 150, 2 дня по 200
+	prices := []models.PriceChange{
+		{Price: 150, From: start.Add(5 * day)},
+		{Price: 200, From: start.Add(8 * day)},
+	}
+	assert.Equal(t, 1350.0, GetSavedBetween(smoker, prices, start, start.Add(10*day)))
+
+	// Отрезок внутри истории берёт цену, действовавшую в его начале
+	assert.Equal(t, 150.0+200.0, GetSavedBetween(smoker, prices, start.Add(7*day), start.Add(9*day)))
+
+	// Изменения после конца отрезка не влияют на результат
+	assert.Equal(t, 500.0, GetSavedBetween(smoker, prices, start, start.Add(5*day)))
+
+	assert.Equal(t, 0.0, GetSavedBetween(smoker, prices, start.Add(day), start))
+}
+
+func TestGetPackPrice(t *testing.T) {
+	start := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	smoker := &models.Smoker{PackPrice: 100}
+	prices := []models.PriceChange{{Price: 150, From: start}}
+
+	assert.Equal(t, 100.0, GetPackPrice(smoker, prices, start.Add(-time.Hour)))
+	assert.Equal(t, 150.0, GetPackPrice(smoker, prices, start))
+}
+
+func TestGetSmokersDiffTime(t *testing.T) {
+	smoker := &models.Smoker{StoppedSmoking: time.Now().UTC().Add(-(2*24 + 5) * time.Hour)}
+	bundle := i18n.Default()
+
+	assert.Equal(t, "0 лет, 0 месяцев, 2 дня, 5 часов", GetSmokersDiffTime(smoker, bundle.Localizer("ru")))
+	assert.Equal(t, "0 years, 0 months, 2 days, 5 hours", GetSmokersDiffTime(smoker, bundle.Localizer("en")))
+}
+
+func TestCheckPermision(t *testing.T) {
+	tokener := NewTokener()
+
+	// Пути без ролей открыты всем, кто вошёл
+	assert.True(t, tokener.CheckPermision("olga", "/profile"))
+
+	// Список курильщиков и админка только для администратора, включая вложенные пути
+	assert.True(t, tokener.CheckPermision("arthurCool", "/smokers"))
+	assert.False(t, tokener.CheckPermision("olga", "/smokers"))
+	assert.False(t, tokener.CheckPermision("victorCool", "/admin"))
+	assert.False(t, tokener.CheckPermision("victorCool", "/admin/smokers/1/roles"))
+	assert.True(t, tokener.CheckPermision("arthurCool", "/admin/smokers/1/roles"))
+}
+
+func TestSetRoles(t *testing.T) {
+	t.Cleanup(func() { SetRoles("olga", nil) })
+
+	assert.Equal(t, []string{configs.UserRole}, GetRoles("olga"))
+
+	// Повторы и роль по умолчанию не хранятся
+	SetRoles("olga", []string{configs.UserRole, configs.ModeratorRole, configs.ModeratorRole})
+	assert.Equal(t, []string{configs.ModeratorRole}, GetRoles("olga"))
+	assert.True(t, HasPermission("olga", configs.ModeratePermission))
+
+	SetRoles("olga", []string{configs.UserRole})
+	assert.Equal(t, []string{configs.UserRole}, GetRoles("olga"))
+	assert.False(t, HasRole("olga", configs.ModeratorRole))
+}
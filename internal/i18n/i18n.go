@@ -0,0 +1,212 @@
+// Package i18n каталог сообщений интерфейса на русском и английском, выбор языка
+// по настройке курильщика и заголовку Accept-Language и склонение по числам
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLocale язык, на котором написано приложение. Сообщения, которых нет в переводе,
+// берутся из него
+const DefaultLocale = "ru"
+
+// CookieName cookie, в которой браузер помнит выбранный язык
+const CookieName = "lang"
+
+//go:embed locales/*.json
+var embedded embed.FS
+
+// message сообщение каталога: строка или набор форм множественного числа
+type message struct {
+	text   string
+	plural map[string]string
+}
+
+func (m *message) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &m.text); err == nil {
+		return nil
+	}
+	return json.Unmarshal(data, &m.plural)
+}
+
+// Bundle каталоги сообщений всех поддерживаемых языков
+type Bundle struct {
+	catalogs map[string]map[string]message
+	locales  []string
+}
+
+// New загружает каталоги из файлов locales/<язык>.json. Каталог языка по умолчанию обязателен
+func New(fsys fs.FS) (*Bundle, error) {
+	op := "i18n.New"
+
+	files, err := fs.Glob(fsys, "locales/*.json")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	b := &Bundle{catalogs: make(map[string]map[string]message, len(files))}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		var catalog map[string]message
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", op, file, err)
+		}
+
+		locale := strings.TrimSuffix(path.Base(file), ".json")
+		b.catalogs[locale] = catalog
+		b.locales = append(b.locales, locale)
+	}
+
+	if _, ok := b.catalogs[DefaultLocale]; !ok {
+		return nil, fmt.Errorf("%s: catalog %q not found", op, DefaultLocale)
+	}
+	sort.Strings(b.locales)
+	return b, nil
+}
+
+// Default каталоги, встроенные в бинарник
+func Default() *Bundle {
+	b, err := New(embedded)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// Locales возвращает поддерживаемые языки
+func (b *Bundle) Locales() []string {
+	return b.locales
+}
+
+// Supported сообщает, есть ли каталог для языка
+func (b *Bundle) Supported(locale string) bool {
+	_, ok := b.catalogs[locale]
+	return ok
+}
+
+// Localizer возвращает переводчик для языка. Неизвестный язык заменяется языком по умолчанию
+func (b *Bundle) Localizer(locale string) *Localizer {
+	if !b.Supported(locale) {
+		locale = DefaultLocale
+	}
+	return &Localizer{bundle: b, locale: locale}
+}
+
+// FromRequest выбирает язык запроса: сохранённая настройка курильщика, затем cookie
+// с выбором браузера, затем Accept-Language. preferred может быть пустым
+func (b *Bundle) FromRequest(r *http.Request, preferred string) *Localizer {
+	if b.Supported(preferred) {
+		return b.Localizer(preferred)
+	}
+	if cookie, err := r.Cookie(CookieName); err == nil && b.Supported(cookie.Value) {
+		return b.Localizer(cookie.Value)
+	}
+	return b.Localizer(Negotiate(r.Header.Get("Accept-Language"), b.locales))
+}
+
+// Negotiate выбирает из supported язык с наибольшим весом в заголовке Accept-Language.
+// "en-US" подходит к "en". Если ничего не подошло, возвращает DefaultLocale
+func Negotiate(header string, supported []string) string {
+	best, bestQ := DefaultLocale, 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= bestQ {
+			continue
+		}
+
+		primary, _, _ := strings.Cut(tag, "-")
+		for _, locale := range supported {
+			if locale == primary || tag == "*" && locale == DefaultLocale {
+				best, bestQ = locale, q
+				break
+			}
+		}
+	}
+	return best
+}
+
+// Localizer переводит сообщения на один язык
+type Localizer struct {
+	bundle *Bundle
+	locale string
+}
+
+// Locale возвращает язык переводчика
+func (l *Localizer) Locale() string {
+	return l.locale
+}
+
+// lookup ищет сообщение сначала в своём каталоге, затем в каталоге по умолчанию
+// и возвращает язык, на котором оно нашлось: от него зависят формы множественного числа
+func (l *Localizer) lookup(key string) (message, string, bool) {
+	if m, ok := l.bundle.catalogs[l.locale][key]; ok {
+		return m, l.locale, true
+	}
+	m, ok := l.bundle.catalogs[DefaultLocale][key]
+	return m, DefaultLocale, ok
+}
+
+// Has сообщает, есть ли ключ в каталоге языка или в каталоге по умолчанию
+func (l *Localizer) Has(key string) bool {
+	_, _, ok := l.lookup(key)
+	return ok
+}
+
+// T переводит сообщение и подставляет в него args как fmt.Sprintf.
+// Для отсутствующего в каталогах ключа возвращает сам ключ, чтобы пропуск был заметен
+func (l *Localizer) T(key string, args ...any) string {
+	m, _, ok := l.lookup(key)
+	if !ok {
+		return key
+	}
+	text := m.text
+	if m.plural != nil {
+		text = m.plural[Other]
+	}
+	if len(args) == 0 {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+// Plural выбирает форму сообщения для n и подставляет n и args, например "%d дня"
+func (l *Localizer) Plural(key string, n int, args ...any) string {
+	m, locale, ok := l.lookup(key)
+	if !ok {
+		return key
+	}
+	if m.plural == nil {
+		return fmt.Sprintf(m.text, append([]any{n}, args...)...)
+	}
+
+	text, ok := m.plural[PluralForm(locale, n)]
+	if !ok {
+		text = m.plural[Other]
+	}
+	return fmt.Sprintf(text, append([]any{n}, args...)...)
+}
@@ -0,0 +1,127 @@
+package i18n
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRussianPlural(t *testing.T) {
+	// Проверяем формы на границах правила: 11–14 всегда many
+	cases := map[int]string{
+		0: Many, 1: One, 2: Few, 4: Few, 5: Many, 11: Many, 12: Many, 14: Many,
+		21: One, 22: Few, 25: Many, 101: One, 111: Many, 112: Many, 122: Few,
+	}
+	for n, form := range cases {
+		assert.Equal(t, form, PluralForm("ru", n), "n = %d", n)
+	}
+}
+
+func TestEnglishPlural(t *testing.T) {
+	assert.Equal(t, One, PluralForm("en", 1))
+	assert.Equal(t, Other, PluralForm("en", 0))
+	assert.Equal(t, Other, PluralForm("en", 2))
+	assert.Equal(t, Other, PluralForm("en", 21))
+}
+
+func TestLocalizer(t *testing.T) {
+	bundle := Default()
+
+	ru := bundle.Localizer("ru")
+	assert.Equal(t, "1 день", ru.Plural("duration.days", 1))
+	assert.Equal(t, "3 дня", ru.Plural("duration.days", 3))
+	assert.Equal(t, "5 дней", ru.Plural("duration.days", 5))
+	assert.Equal(t, "Профиль Arthur", ru.T("nav.profile", "Arthur"))
+
+	en := bundle.Localizer("en")
+	assert.Equal(t, "1 day", en.Plural("duration.days", 1))
+	assert.Equal(t, "5 days", en.Plural("duration.days", 5))
+
+	// Неизвестный ключ виден сразу, а неизвестный язык заменяется русским
+	assert.Equal(t, "no.such.key", en.T("no.such.key"))
+	assert.Equal(t, "ru", bundle.Localizer("de").Locale())
+}
+
+func TestFallbackToDefaultLocale(t *testing.T) {
+	bundle, err := New(fstest.MapFS{
+		"locales/ru.json": {Data: []byte(`{"hello": "Привет", "days": {"one": "%d день", "few": "%d дня", "many": "%d дней"}}`)},
+		"locales/en.json": {Data: []byte(`{}`)},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"en", "ru"}, bundle.Locales())
+
+	// Непереведённое сообщение берётся из русского каталога вместе с русскими формами
+	en := bundle.Localizer("en")
+	assert.Equal(t, "Привет", en.T("hello"))
+	assert.Equal(t, "2 дня", en.Plural("days", 2))
+}
+
+func TestNewWithoutDefaultLocale(t *testing.T) {
+	_, err := New(fstest.MapFS{"locales/en.json": {Data: []byte(`{}`)}})
+	assert.Error(t, err)
+}
+
+func TestCatalogsHaveSameKeys(t *testing.T) {
+	bundle := Default()
+	for _, locale := range bundle.Locales() {
+		for key := range bundle.catalogs[DefaultLocale] {
+			_, ok := bundle.catalogs[locale][key]
+			assert.True(t, ok, "%s: нет перевода %q", locale, key)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	supported := []string{"en", "ru"}
+
+	assert.Equal(t, "en", Negotiate("en-US,en;q=0.9", supported))
+	assert.Equal(t, "ru", Negotiate("ru-RU,ru;q=0.9,en;q=0.8", supported))
+	assert.Equal(t, "en", Negotiate("de-DE,de;q=0.9,en;q=0.5", supported))
+	assert.Equal(t, "ru", Negotiate("en;q=0.3,ru;q=0.7", supported))
+	assert.Equal(t, "ru", Negotiate("de,fr", supported))
+	assert.Equal(t, "ru", Negotiate("", supported))
+	assert.Equal(t, "ru", Negotiate("en;q=0", supported))
+	assert.Equal(t, "ru", Negotiate("*", supported))
+}
+
+func TestFromRequest(t *testing.T) {
+	bundle := Default()
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Language", "en-GB,en;q=0.9")
+	assert.Equal(t, "en", bundle.FromRequest(r, "").Locale())
+
+	// Cookie важнее заголовка, а настройка курильщика важнее cookie
+	r.AddCookie(&http.Cookie{Name: CookieName, Value: "ru"})
+	assert.Equal(t, "ru", bundle.FromRequest(r, "").Locale())
+	assert.Equal(t, "en", bundle.FromRequest(r, "en").Locale())
+}
+
+func TestNumber(t *testing.T) {
+	bundle := Default()
+	ru, en := bundle.Localizer("ru"), bundle.Localizer("en")
+
+	assert.Equal(t, "1\u00a0234\u00a0567,89", ru.Number(1234567.891, 2))
+	assert.Equal(t, "1,234,567.89", en.Number(1234567.891, 2))
+	assert.Equal(t, "999", en.Number(999, 0))
+	assert.Equal(t, "-1,000.50", en.Number(-1000.5, 2))
+	// Округление до нуля не оставляет минус
+	assert.Equal(t, "0.00", en.Number(-0.001, 2))
+}
+
+func TestMoney(t *testing.T) {
+	bundle := Default()
+	ru, en := bundle.Localizer("ru"), bundle.Localizer("en")
+
+	assert.Equal(t, "1\u00a0234,50\u00a0₽", ru.Money(1234.5, "RUB"))
+	assert.Equal(t, "12,50\u00a0$", ru.Money(12.5, "USD"))
+	assert.Equal(t, "$1,234.50", en.Money(1234.5, "USD"))
+	assert.Equal(t, "RUB\u00a01,234.50", en.Money(1234.5, "RUB"))
+	// В иенах нет копеек
+	assert.Equal(t, "¥1,235", en.Money(1234.5, "JPY"))
+	// Неизвестная валюта показывается кодом
+	assert.Equal(t, "XYZ\u00a010.00", en.Money(10, "XYZ"))
+}
@@ -0,0 +1,95 @@
+package i18n
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// currencyDigits поддерживаемые валюты ISO 4217 и число знаков после запятой в них
+var currencyDigits = map[string]int{
+	"RUB": 2,
+	"USD": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"KZT": 2,
+	"BYN": 2,
+	"UAH": 2,
+	"JPY": 0,
+}
+
+// Currencies возвращает коды поддерживаемых валют по алфавиту
+func Currencies() []string {
+	codes := make([]string, 0, len(currencyDigits))
+	for code := range currencyDigits {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// SupportedCurrency сообщает, умеем ли мы показывать суммы в валюте
+func SupportedCurrency(code string) bool {
+	_, ok := currencyDigits[code]
+	return ok
+}
+
+// Number форматирует число с decimals знаками после запятой и разделителями
+// разрядов и дробной части, принятыми в языке: "1 234,50" или "1,234.50"
+func (l *Localizer) Number(value float64, decimals int) string {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+
+	// Половину округляем от нуля, как в кассе, а не к чётному, как FormatFloat
+	scale := math.Pow10(decimals)
+	formatted := strconv.FormatFloat(math.Round(math.Abs(value)*scale)/scale, 'f', decimals, 64)
+	integer, fraction, _ := strings.Cut(formatted, ".")
+
+	group := l.T("number.group")
+	var b strings.Builder
+	if value < 0 && strings.Trim(formatted, "0.") != "" {
+		b.WriteString("-")
+	}
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			b.WriteString(group)
+		}
+		b.WriteRune(digit)
+	}
+	if fraction != "" {
+		b.WriteString(l.T("number.decimal"))
+		b.WriteString(fraction)
+	}
+	return b.String()
+}
+
+// Money форматирует сумму в валюте по правилам языка: "1 234,50 ₽" или "$1,234.50".
+// Для неподдерживаемой валюты вместо символа пишется её код. Буквенный символ
+// вроде "RUB" отделяется от числа пробелом: "RUB 1,234.50"
+func (l *Localizer) Money(amount float64, currency string) string {
+	digits, ok := currencyDigits[currency]
+	if !ok {
+		digits = 2
+	}
+
+	symbol := l.CurrencySymbol(currency)
+	pattern := l.T("money.pattern")
+	if last, _ := utf8.DecodeLastRuneInString(symbol); unicode.IsLetter(last) {
+		pattern = l.T("money.pattern_code")
+	}
+	return fmt.Sprintf(pattern, l.Number(amount, digits), symbol)
+}
+
+// CurrencySymbol возвращает символ валюты, принятый в языке, например "₽" или "RUB"
+func (l *Localizer) CurrencySymbol(currency string) string {
+	key := "currency." + currency
+	if symbol := l.T(key); symbol != key {
+		return symbol
+	}
+	return currency
+}
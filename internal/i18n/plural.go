@@ -0,0 +1,49 @@
+package i18n
+
+// Формы множественного числа по CLDR. Для целых чисел русскому языку нужны one, few и many,
+// английскому — one и other
+const (
+	One   = "one"
+	Few   = "few"
+	Many  = "many"
+	Other = "other"
+)
+
+// pluralRules правило выбора формы для каждой поддерживаемой локали
+var pluralRules = map[string]func(n int) string{
+	"ru": russianPlural,
+	"en": englishPlural,
+}
+
+// russianPlural 1 день, 2 дня, 5 дней, 11 дней, 21 день, 22 дня
+func russianPlural(n int) string {
+	if n < 0 {
+		n = -n
+	}
+	mod10, mod100 := n%10, n%100
+	switch {
+	case mod10 == 1 && mod100 != 11:
+		return One
+	case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+		return Few
+	default:
+		return Many
+	}
+}
+
+// englishPlural 1 day, 2 days, 0 days
+func englishPlural(n int) string {
+	if n == 1 || n == -1 {
+		return One
+	}
+	return Other
+}
+
+// PluralForm возвращает форму множественного числа для n в локали. Для неизвестной локали — other
+func PluralForm(locale string, n int) string {
+	rule, ok := pluralRules[locale]
+	if !ok {
+		return Other
+	}
+	return rule(n)
+}
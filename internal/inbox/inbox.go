@@ -0,0 +1,34 @@
+package inbox
+
+import (
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+type Store interface {
+	AddInboxItem(item *models.InboxItem)
+}
+
+// Inbox единая точка, через которую любые части приложения пишут во входящие курильщика
+type Inbox struct {
+	store Store
+}
+
+func New(store Store) *Inbox {
+	return &Inbox{store: store}
+}
+
+// Post кладёт запись во входящие курильщика
+func (i *Inbox) Post(username, kind, title, body, link string) *models.InboxItem {
+	item := &models.InboxItem{
+		Username:  username,
+		Kind:      kind,
+		Title:     title,
+		Body:      body,
+		Link:      link,
+		CreatedAt: time.Now().UTC(),
+	}
+	i.store.AddInboxItem(item)
+	return item
+}
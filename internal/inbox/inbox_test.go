@@ -0,0 +1,40 @@
+package inbox
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/NarthurN/QuitSmoking/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostAndRead(t *testing.T) {
+	store := storage.New()
+	box := New(store)
+
+	for range 25 {
+		box.Post("arthurCool", models.InboxBuddyMessage, "Сообщение", "привет", "/buddies")
+	}
+	last := box.Post("arthurCool", models.InboxPostReply, "Ответ", "держись", "/feed")
+	box.Post("victorCool", models.InboxBuddyInvite, "Приглашение", "", "/buddies")
+
+	// Новые записи первыми, страница ограничена limit
+	items, total := store.GetInbox("arthurCool", 0, 20)
+	assert.Equal(t, 26, total)
+	assert.Len(t, items, 20)
+	assert.Equal(t, last.ID, items[0].ID)
+
+	items, _ = store.GetInbox("arthurCool", 20, 20)
+	assert.Len(t, items, 6)
+	assert.Equal(t, 26, store.CountUnread("arthurCool"))
+
+	// Чужую запись прочитать нельзя
+	assert.False(t, store.MarkInboxItemRead("victorCool", last.ID, time.Now()))
+	assert.True(t, store.MarkInboxItemRead("arthurCool", last.ID, time.Now()))
+	assert.Equal(t, 25, store.CountUnread("arthurCool"))
+
+	assert.Equal(t, 25, store.MarkAllInboxRead("arthurCool", time.Now()))
+	assert.Equal(t, 0, store.CountUnread("arthurCool"))
+	assert.Equal(t, 1, store.CountUnread("victorCool"))
+}
@@ -0,0 +1,184 @@
+// Package media хранение загруженных курильщиками файлов и их проверка
+package media
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+)
+
+var (
+	ErrNotFound        = errors.New("media not found")
+	ErrTooLarge        = errors.New("file too large")
+	ErrUnsupportedType = errors.New("unsupported file type")
+	ErrInvalidImage    = errors.New("invalid image")
+)
+
+// Допустимые изображения и расширения файлов для них
+var imageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// Store хранилище файлов. Ключ выдаёт NewKey, содержимое проверяется до сохранения
+type Store interface {
+	Save(ctx context.Context, key string, data []byte) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Limits ограничения на загружаемые изображения. MaxPixels защищает
+// от маленьких файлов, которые при декодировании занимают гигабайты
+type Limits struct {
+	MaxSize   int64
+	MaxPixels int
+}
+
+// ReadImage читает изображение не больше limits.MaxSize байт и проверяет, что это
+// действительно JPEG, PNG или GIF разумного размера. Тип определяется по содержимому,
+// а не по имени файла или заголовку от клиента
+func ReadImage(r io.Reader, limits Limits) ([]byte, string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limits.MaxSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(data)) > limits.MaxSize {
+		return nil, "", ErrTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	if _, ok := imageTypes[contentType]; !ok {
+		return nil, "", ErrUnsupportedType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %w", ErrInvalidImage, err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > limits.MaxPixels {
+		return nil, "", ErrInvalidImage
+	}
+	return data, contentType, nil
+}
+
+// NewKey создаёт случайный ключ файла с расширением по типу содержимого
+func NewKey(contentType string) (string, error) {
+	ext, ok := imageTypes[contentType]
+	if !ok {
+		return "", ErrUnsupportedType
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b) + ext, nil
+}
+
+// Ключи только такого вида, чтобы из них нельзя было собрать путь за пределами каталога
+var keyPattern = regexp.MustCompile(`^[0-9a-f]{32}\.[a-z]{3}$`)
+
+// Disk хранит файлы в каталоге на диске
+type Disk struct {
+	dir string
+}
+
+func NewDisk(dir string) (*Disk, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("media.NewDisk: %w", err)
+	}
+	return &Disk{dir: dir}, nil
+}
+
+func (d *Disk) Save(_ context.Context, key string, data []byte) error {
+	op := "media.Disk.Save"
+
+	if !keyPattern.MatchString(key) {
+		return fmt.Errorf("%s: invalid key", op)
+	}
+	if err := os.WriteFile(filepath.Join(d.dir, key), data, 0o640); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (d *Disk) Open(_ context.Context, key string) (io.ReadCloser, error) {
+	if !keyPattern.MatchString(key) {
+		return nil, ErrNotFound
+	}
+	f, err := os.Open(filepath.Join(d.dir, key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("media.Disk.Open: %w", err)
+	}
+	return f, nil
+}
+
+func (d *Disk) Delete(_ context.Context, key string) error {
+	if !keyPattern.MatchString(key) {
+		return ErrNotFound
+	}
+	err := os.Remove(filepath.Join(d.dir, key))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("media.Disk.Delete: %w", err)
+	}
+	return nil
+}
+
+// Memory хранит файлы в памяти. Подходит для тестов и запуска без диска
+type Memory struct {
+	mu    sync.RWMutex
+	files map[string][]byte
+}
+
+func NewMemory() *Memory {
+	return &Memory{files: make(map[string][]byte)}
+}
+
+func (m *Memory) Save(_ context.Context, key string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.files[key] = bytes.Clone(data)
+	return nil
+}
+
+func (m *Memory) Open(_ context.Context, key string) (io.ReadCloser, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	data, ok := m.files[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m *Memory) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.files[key]; !ok {
+		return ErrNotFound
+	}
+	delete(m.files, key)
+	return nil
+}
@@ -0,0 +1,67 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func pngBytes(t *testing.T, w, h int) []byte {
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h))))
+	return buf.Bytes()
+}
+
+func TestReadImage(t *testing.T) {
+	limits := Limits{MaxSize: 10 << 10, MaxPixels: 100 * 100}
+
+	data, contentType, err := ReadImage(bytes.NewReader(pngBytes(t, 10, 10)), limits)
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", contentType)
+	assert.NotEmpty(t, data)
+
+	// Маленький файл с огромными размерами
+	_, _, err = ReadImage(bytes.NewReader(pngBytes(t, 200, 200)), limits)
+	assert.ErrorIs(t, err, ErrInvalidImage)
+
+	_, _, err = ReadImage(bytes.NewReader(make([]byte, 11<<10)), limits)
+	assert.ErrorIs(t, err, ErrTooLarge)
+
+	_, _, err = ReadImage(bytes.NewReader([]byte("<html><script>alert(1)</script></html>")), limits)
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+
+	// Заголовок PNG без самого изображения
+	_, _, err = ReadImage(bytes.NewReader(pngBytes(t, 10, 10)[:20]), limits)
+	assert.ErrorIs(t, err, ErrInvalidImage)
+}
+
+func TestDisk(t *testing.T) {
+	disk, err := NewDisk(t.TempDir())
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	key, err := NewKey("image/png")
+	assert.NoError(t, err)
+	assert.NoError(t, disk.Save(ctx, key, []byte("png")))
+
+	f, err := disk.Open(ctx, key)
+	if assert.NoError(t, err) {
+		data, _ := io.ReadAll(f)
+		f.Close()
+		assert.Equal(t, "png", string(data))
+	}
+
+	// Ключ с путём не принимается
+	assert.Error(t, disk.Save(ctx, "../evil.png", []byte("x")))
+	_, err = disk.Open(ctx, "../../etc/passwd")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.NoError(t, disk.Delete(ctx, key))
+	_, err = disk.Open(ctx, key)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
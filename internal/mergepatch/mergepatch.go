@@ -0,0 +1,55 @@
+// Package mergepatch применяет JSON Merge Patch по RFC 7396: поля из патча заменяют
+// поля документа, вложенные объекты сливаются рекурсивно, а null удаляет поле
+package mergepatch
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ContentType тип тела запроса PATCH с merge patch
+const ContentType = "application/merge-patch+json"
+
+// Apply применяет patch к документу doc и возвращает новый документ
+func Apply(doc, patch []byte) ([]byte, error) {
+	const op = "mergepatch.Apply"
+
+	var target any
+	if len(doc) > 0 {
+		if err := json.Unmarshal(doc, &target); err != nil {
+			return nil, fmt.Errorf("%s: document: %w", op, err)
+		}
+	}
+
+	var p any
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, fmt.Errorf("%s: patch: %w", op, err)
+	}
+
+	result, err := json.Marshal(merge(target, p))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return result, nil
+}
+
+// merge повторяет алгоритм MergePatch из раздела 2 RFC 7396
+func merge(target, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = make(map[string]any)
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+		targetObject[name] = merge(targetObject[name], value)
+	}
+	return targetObject
+}
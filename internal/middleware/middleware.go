@@ -2,13 +2,20 @@ package middleware
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"regexp"
 	"strings"
 	"time"
 
+	"github.com/NarthurN/QuitSmoking/internal/apperr"
+	"github.com/NarthurN/QuitSmoking/internal/audit"
 	"github.com/NarthurN/QuitSmoking/internal/helpers"
+	"github.com/NarthurN/QuitSmoking/internal/i18n"
 	"github.com/NarthurN/QuitSmoking/internal/models"
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -17,7 +24,7 @@ var allowedPaths = map[string]struct{}{
 	"/":        {},
 	"/signin":  {},
 	"/form":    {},
-	"/logout":    {},
+	"/logout":  {},
 	"/static/": {},
 }
 
@@ -32,22 +39,94 @@ func (rw *customResponseWriter) WriteHeader(statusCode int) {
 	rw.ResponseWriter.WriteHeader(statusCode)
 }
 
+// Unwrap нужен http.ResponseController, чтобы потоковые ответы могли сбрасывать буфер
+func (rw *customResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 type Tokener interface {
 	VerifyUser(token string) (*models.Claims, error)
 	GetJwtToken(username string) (string, error)
+	GetImpersonationToken(username, impersonator string) (string, error)
 	AllowedPath(path string, m map[string]struct{}) bool
 	CheckPermision(username, path string) bool
 }
 
+// Accounts сообщает, заблокирована ли учётная запись и какое поколение её токенов действует
+type Accounts interface {
+	IsLocked(username string) bool
+	TokenGeneration(username string) int
+}
+
 type Middleware struct {
 	logger  *slog.Logger
 	Tokener Tokener
+	// Accounts если задан, JwtAuth не пускает заблокированных курильщиков и отозванные токены
+	Accounts Accounts
+	// Audit если задан, JwtAuth записывает в него отказы в доступе
+	Audit audit.Recorder
+	// Fail отвечает на ошибку доступа. По умолчанию пишет application/problem+json,
+	// а обработчики подставляют свой ответ, чтобы браузер увидел страницу с ошибкой
+	Fail func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// RefreshWindow за столько до истечения JwtAuth выдаёт новый токен
+const RefreshWindow = 30 * time.Second
+
+// Ошибки Session: поток, открытый с этим токеном, пора закрыть
+var (
+	ErrAccountLocked = errors.New("account is locked")
+	ErrTokenRevoked  = errors.New("token is revoked")
+	// ErrTokenExpiring токен вот-вот истечёт: клиент может переподключиться и получить новый
+	ErrTokenExpiring = errors.New("token is expiring")
+)
+
+// Заголовок с id запроса. Id от клиента или прокси сохраняется, если он похож на id
+const RequestIDHeader = "X-Request-ID"
+
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestID даёт каждому запросу id: кладёт его в контекст и в заголовок ответа,
+// чтобы запись в логе и в журнале аудита можно было найти по жалобе клиента
+func (m *Middleware) RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		ctx := context.WithValue(r.Context(), models.ContextString("request.id"), id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// newRequestID случайный id запроса
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// denied записывает отказ в доступе в журнал аудита, если он задан
+func (m *Middleware) denied(r *http.Request, actor, details string) {
+	if m.Audit == nil {
+		return
+	}
+	event := audit.Event(r, actor, models.AuditAccessDenied, "")
+	event.Details = details
+	m.Audit.AddAuditEvent(event)
 }
 
 func New(logger *slog.Logger, tokener Tokener) *Middleware {
+	bundle := i18n.Default()
 	return &Middleware{
 		logger:  logger,
 		Tokener: tokener,
+		Fail: func(w http.ResponseWriter, r *http.Request, err error) {
+			loc := bundle.FromRequest(r, "")
+			apperr.WriteProblem(w, loc, apperr.From(err).Problem(loc, r.URL.Path))
+		},
 	}
 }
 
@@ -66,7 +145,9 @@ func (m *Middleware) Log(next http.Handler) http.Handler {
 
 		duration := time.Since(start)
 
+		requestID, _ := r.Context().Value(models.ContextString("request.id")).(string)
 		attrs := []any{
+			"request_id", requestID,
 			"method", r.Method,
 			"status", rw.status,
 			"duration", duration.String(),
@@ -94,54 +175,83 @@ func (m *Middleware) JwtAuth(next http.Handler) http.Handler {
 			return
 		}
 
-		var authHeaderValue string
+		// Браузер передаёт токен в cookie, а остальные клиенты (например, WebSocket
+		// вне браузера) могут передать тот же токен в заголовке Authorization
+		authHeaderValue := r.Header.Get("Authorization")
 
-		cookie, err := r.Cookie("token")
-		if err != nil {
-			if err == http.ErrNoCookie {
-				m.logger.Debug("middleware.jwtAuth.r.Cookie(token)", helpers.SlogDebug("no cookie"))
-				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		if authHeaderValue == "" {
+			cookie, err := r.Cookie("token")
+			if err != nil {
+				if errors.Is(err, http.ErrNoCookie) {
+					m.logger.Debug("middleware.jwtAuth.r.Cookie(token)", helpers.SlogDebug("no cookie"))
+					m.Fail(w, r, apperr.New(http.StatusUnauthorized, apperr.CodeUnauthorized))
+					return
+				}
+				m.logger.Error("middleware.jwtAuth.r.Cookie(token)", helpers.SlogErr(err))
+				m.Fail(w, r, apperr.Internal(err))
 				return
 			}
-			m.logger.Error("middleware.jwtAuth.r.Cookie(token)", helpers.SlogErr(err))
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-			return
+			authHeaderValue = cookie.Value
 		}
-		authHeaderValue = cookie.Value
 
 		if authHeaderValue == "" {
 			m.logger.Debug("middleware.jwtAuth.authHeaderValue", helpers.SlogDebug("authHeaderValue is empty"))
-			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			m.Fail(w, r, apperr.New(http.StatusUnauthorized, apperr.CodeUnauthorized))
 			return
 		}
 
 		bearerToken := strings.Split(authHeaderValue, " ")
 		if len(bearerToken) != 2 {
 			m.logger.Debug("middleware.jwtAuth.bearerToken", helpers.SlogDebug("format bearerToken is not Bearer {jwt}"))
-			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			m.Fail(w, r, apperr.New(http.StatusUnauthorized, apperr.CodeTokenInvalid))
 			return
 		}
 
 		claims, err := m.Tokener.VerifyUser(bearerToken[1])
 		if err != nil {
-			m.logger.Error("middleware.jwtAuth.VerifyUser", helpers.SlogErr(err))
-			if err == jwt.ErrSignatureInvalid {
-				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
-				return
+			// Истёкший, поддельный и испорченный токен одинаково требуют войти заново
+			reason := "invalid_token"
+			if errors.Is(err, jwt.ErrTokenExpired) {
+				reason = "token_expired"
 			}
-			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			m.logger.Debug("middleware.jwtAuth.VerifyUser", helpers.SlogDebug(err.Error()))
+			m.denied(r, "", reason)
+			m.Fail(w, r, apperr.New(http.StatusUnauthorized, apperr.CodeTokenInvalid).Wrap(err))
+			return
+		}
+
+		// После сброса пароля или смены ролей старые токены не действуют
+		if m.Accounts != nil && claims.Generation != m.Accounts.TokenGeneration(claims.Username) {
+			m.logger.Debug("middleware.jwtAuth.TokenGeneration", helpers.SlogDebug("token is revoked"))
+			m.denied(r, claims.Username, "token_revoked")
+			m.Fail(w, r, apperr.New(http.StatusUnauthorized, apperr.CodeTokenRevoked))
 			return
 		}
 
 		if !m.Tokener.CheckPermision(claims.Username, r.URL.Path) {
 			m.logger.Debug("middleware.jwtAuth.CheckPermision", helpers.SlogDebug("permition denied"))
-			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			m.denied(r, claims.Username, r.Method+" "+r.URL.Path)
+			m.Fail(w, r, apperr.New(http.StatusForbidden, apperr.CodeForbidden))
+			return
+		}
+
+		if m.Accounts != nil && m.Accounts.IsLocked(claims.Username) {
+			m.logger.Debug("middleware.jwtAuth.IsLocked", helpers.SlogDebug("account is locked"))
+			m.denied(r, claims.Username, "account_locked")
+			m.Fail(w, r, apperr.New(http.StatusForbidden, apperr.CodeAccountLocked))
 			return
 		}
 
-		if time.Until(claims.ExpiresAt.Time) < 30*time.Second && time.Until(claims.ExpiresAt.Time) > 0 {
+		if time.Until(claims.ExpiresAt.Time) < RefreshWindow && time.Until(claims.ExpiresAt.Time) > 0 {
 			m.logger.Debug("refreesh")
-			newToken, err := m.Tokener.GetJwtToken(claims.Username)
+			var newToken string
+			var err error
+			// Обновлённый токен администратора, вошедшего от имени курильщика, остаётся таким же
+			if claims.Impersonator != "" {
+				newToken, err = m.Tokener.GetImpersonationToken(claims.Username, claims.Impersonator)
+			} else {
+				newToken, err = m.Tokener.GetJwtToken(claims.Username)
+			}
 			if err != nil {
 				m.logger.Error("middleware.jwtAuth.GetJwtToken", helpers.SlogErr(err))
 			}
@@ -154,8 +264,36 @@ func (m *Middleware) JwtAuth(next http.Handler) http.Handler {
 
 		ctx := r.Context()
 		ctx = context.WithValue(ctx, models.ContextString("smoker.name"), claims.Username)
+		if claims.Impersonator != "" {
+			ctx = context.WithValue(ctx, models.ContextString("smoker.impersonator"), claims.Impersonator)
+		}
+		ctx = context.WithValue(ctx, models.ContextString("smoker.claims"), claims)
 		r = r.WithContext(ctx)
 
 		next.ServeHTTP(w, r)
 	})
 }
+
+// Session возвращает проверку для долгих потоков (SSE, WebSocket): JwtAuth пропускает
+// их один раз, а блокировка, отзыв токенов и срок действия должны действовать и потом.
+// Без токена в контексте (например, в тестах) проверяется только блокировка
+func (m *Middleware) Session(r *http.Request) func(now time.Time) error {
+	username, _ := r.Context().Value(models.ContextString("smoker.name")).(string)
+	claims, _ := r.Context().Value(models.ContextString("smoker.claims")).(*models.Claims)
+	return func(now time.Time) error {
+		if m.Accounts != nil && m.Accounts.IsLocked(username) {
+			return ErrAccountLocked
+		}
+		if claims == nil {
+			return nil
+		}
+		if m.Accounts != nil && claims.Generation != m.Accounts.TokenGeneration(username) {
+			return ErrTokenRevoked
+		}
+		// Закрываем поток заранее, пока переподключение ещё застаёт окно обновления токена
+		if claims.ExpiresAt != nil && claims.ExpiresAt.Time.Sub(now) < RefreshWindow {
+			return ErrTokenExpiring
+		}
+		return nil
+	}
+}
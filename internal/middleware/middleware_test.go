@@ -1,12 +1,15 @@
 package middleware
 
 import (
+	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/NarthurN/QuitSmoking/internal/apperr"
 	"github.com/NarthurN/QuitSmoking/internal/models"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
@@ -27,6 +30,11 @@ func (m *MockVerifier) GetJwtToken(username string) (string, error) {
 	return args.Get(0).(string), args.Error(1)
 }
 
+func (m *MockVerifier) GetImpersonationToken(username, impersonator string) (string, error) {
+	args := m.Called(username, impersonator)
+	return args.Get(0).(string), args.Error(1)
+}
+
 func (m *MockVerifier) AllowedPath(path string, allowedPaths map[string]struct{}) bool {
 	args := m.Called(path, allowedPaths)
 	return args.Get(0).(bool)
@@ -98,6 +106,7 @@ func TestJwtAuthWhenPathIsAllowed(t *testing.T) {
 		},
 	}, nil)
 	mockVerifier.On("AllowedPath", allowedPath, mock.Anything).Return(false)
+	mockVerifier.On("CheckPermision", user, allowedPath).Return(true)
 	// Создаем middleware с моком Verifier
 	middleware := New(slog.Default(), mockVerifier)
 
@@ -160,3 +169,314 @@ func TestJwtAuthWhenPathIsNotAllowed(t *testing.T) {
 	// Проверяем, что VerifyUser был вызван с правильным аргументом
 	mockVerifier.AssertCalled(t, "AllowedPath", notAllowedPath, mock.Anything)
 }
+
+func TestJwtAuthWithCookie(t *testing.T) {
+	expectedToken := "2222"
+	path := "/chat"
+	user := "arthur"
+
+	mockVerifier := new(MockVerifier)
+	mockVerifier.On("AllowedPath", path, mock.Anything).Return(false)
+	mockVerifier.On("VerifyUser", expectedToken).Return(&models.Claims{
+		Username: user,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(5 * time.Minute)),
+		},
+	}, nil)
+	mockVerifier.On("CheckPermision", user, path).Return(true)
+
+	middleware := New(slog.Default(), mockVerifier)
+
+	// Обработчик получает имя курильщика из контекста
+	var got string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = r.Context().Value(models.ContextString("smoker.name")).(string)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	// Без заголовка Authorization токен берётся из cookie, как у браузера
+	req := httptest.NewRequest("GET", path, nil)
+	req.AddCookie(&http.Cookie{Name: "token", Value: "Bearer " + expectedToken})
+	rr := httptest.NewRecorder()
+
+	middleware.JwtAuth(handler).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, user, got)
+	mockVerifier.AssertCalled(t, "VerifyUser", expectedToken)
+}
+
+// lockedAccounts заблокированные учётные записи для теста
+type lockedAccounts map[string]bool
+
+func (l lockedAccounts) IsLocked(username string) bool {
+	return l[username]
+}
+
+func (l lockedAccounts) TokenGeneration(username string) int {
+	return 0
+}
+
+func TestJwtAuthLockedAccount(t *testing.T) {
+	expectedToken := "3333"
+	path := "/profile"
+	user := "olga"
+
+	mockVerifier := new(MockVerifier)
+	mockVerifier.On("AllowedPath", path, mock.Anything).Return(false)
+	mockVerifier.On("VerifyUser", expectedToken).Return(&models.Claims{
+		Username: user,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(5 * time.Minute)),
+		},
+	}, nil)
+	mockVerifier.On("CheckPermision", user, path).Return(true)
+
+	middleware := New(slog.Default(), mockVerifier)
+	middleware.Accounts = lockedAccounts{user: true}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	// Токен ещё действует, но учётная запись заблокирована
+	req := httptest.NewRequest("GET", path, nil)
+	req.Header.Set("Authorization", "Bearer "+expectedToken)
+	rr := httptest.NewRecorder()
+
+	middleware.JwtAuth(handler).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+// tokenGenerations поколения токенов для теста
+type tokenGenerations map[string]int
+
+func (g tokenGenerations) IsLocked(username string) bool {
+	return false
+}
+
+func (g tokenGenerations) TokenGeneration(username string) int {
+	return g[username]
+}
+
+func TestJwtAuthRevokedToken(t *testing.T) {
+	expectedToken := "6666"
+	path := "/profile"
+	user := "olga"
+
+	mockVerifier := new(MockVerifier)
+	mockVerifier.On("AllowedPath", path, mock.Anything).Return(false)
+	mockVerifier.On("VerifyUser", expectedToken).Return(&models.Claims{
+		Username:   user,
+		Generation: 1,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(5 * time.Minute)),
+		},
+	}, nil)
+	mockVerifier.On("CheckPermision", user, path).Return(true)
+
+	middleware := New(slog.Default(), mockVerifier)
+	// Пароль сбросили, и поколение токенов выросло
+	middleware.Accounts = tokenGenerations{user: 2}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", path, nil)
+	req.Header.Set("Authorization", "Bearer "+expectedToken)
+	rr := httptest.NewRecorder()
+
+	middleware.JwtAuth(handler).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+func TestJwtAuthImpersonation(t *testing.T) {
+	expectedToken := "4444"
+	refreshedToken := "5555"
+	path := "/profile"
+	user := "olga"
+	admin := "arthurCool"
+
+	mockVerifier := new(MockVerifier)
+	mockVerifier.On("AllowedPath", path, mock.Anything).Return(false)
+	mockVerifier.On("VerifyUser", expectedToken).Return(&models.Claims{
+		Username:     user,
+		Impersonator: admin,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(20 * time.Second)),
+		},
+	}, nil)
+	mockVerifier.On("CheckPermision", user, path).Return(true)
+	mockVerifier.On("GetImpersonationToken", user, admin).Return(refreshedToken, nil)
+
+	middleware := New(slog.Default(), mockVerifier)
+
+	// Обработчик видит и курильщика, и администратора, который вошёл от его имени
+	var gotUser, gotAdmin string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUser, _ = r.Context().Value(models.ContextString("smoker.name")).(string)
+		gotAdmin, _ = r.Context().Value(models.ContextString("smoker.impersonator")).(string)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", path, nil)
+	req.Header.Set("Authorization", "Bearer "+expectedToken)
+	rr := httptest.NewRecorder()
+
+	middleware.JwtAuth(handler).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, user, gotUser)
+	assert.Equal(t, admin, gotAdmin)
+	// Токен обновился без потери администратора
+	mockVerifier.AssertCalled(t, "GetImpersonationToken", user, admin)
+	mockVerifier.AssertNotCalled(t, "GetJwtToken", user)
+}
+
+// auditLog журнал аудита для теста
+type auditLog []*models.AuditEvent
+
+func (l *auditLog) AddAuditEvent(event *models.AuditEvent) {
+	*l = append(*l, event)
+}
+
+func TestRequestID(t *testing.T) {
+	middleware := New(slog.Default(), new(MockVerifier))
+
+	var got string
+	handler := middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = r.Context().Value(models.ContextString("request.id")).(string)
+	}))
+
+	// Без заголовка id создаётся и возвращается клиенту
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+	assert.Len(t, got, 16)
+	assert.Equal(t, got, rr.Header().Get(RequestIDHeader))
+
+	// id от клиента сохраняется
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(RequestIDHeader, "proxy-42")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, "proxy-42", got)
+
+	// а непохожий на id заменяется
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(RequestIDHeader, "<script>")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.NotEqual(t, "<script>", got)
+	assert.Len(t, got, 16)
+}
+
+func TestJwtAuthForbiddenIsAudited(t *testing.T) {
+	expectedToken := "6666"
+	path := "/admin"
+	user := "olga"
+
+	mockVerifier := new(MockVerifier)
+	mockVerifier.On("AllowedPath", path, mock.Anything).Return(false)
+	mockVerifier.On("VerifyUser", expectedToken).Return(&models.Claims{
+		Username: user,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(5 * time.Minute)),
+		},
+	}, nil)
+	mockVerifier.On("CheckPermision", user, path).Return(false)
+
+	middleware := New(slog.Default(), mockVerifier)
+	var log auditLog
+	middleware.Audit = &log
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", path, nil)
+	req.Header.Set("Authorization", "Bearer "+expectedToken)
+	rr := httptest.NewRecorder()
+
+	middleware.RequestID(middleware.JwtAuth(handler)).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	if assert.Len(t, log, 1) {
+		assert.Equal(t, models.AuditAccessDenied, log[0].Action)
+		assert.Equal(t, user, log[0].Actor)
+		assert.Equal(t, "GET /admin", log[0].Details)
+		assert.Equal(t, rr.Header().Get(RequestIDHeader), log[0].RequestID)
+	}
+}
+
+func TestJwtAuthExpiredToken(t *testing.T) {
+	expectedToken := "7777"
+	path := "/profile"
+
+	mockVerifier := new(MockVerifier)
+	mockVerifier.On("AllowedPath", path, mock.Anything).Return(false)
+	// Tokener оборачивает ошибку jwt, поэтому сравнивать её через == нельзя
+	mockVerifier.On("VerifyUser", expectedToken).
+		Return(&models.Claims{}, fmt.Errorf("helpers.VerifyUser: %w", jwt.ErrTokenExpired))
+
+	middleware := New(slog.Default(), mockVerifier)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", path, nil)
+	req.Header.Set("Authorization", "Bearer "+expectedToken)
+	rr := httptest.NewRecorder()
+
+	middleware.JwtAuth(handler).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Contains(t, rr.Header().Get("Content-Type"), apperr.ProblemContentType)
+	var problem apperr.Problem
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+	assert.Equal(t, apperr.CodeTokenInvalid, problem.Code)
+}
+
+func TestSessionRechecksToken(t *testing.T) {
+	expectedToken := "7777"
+	path := "/profile/live"
+	user := "olga"
+	expires := time.Now().UTC().Add(5 * time.Minute)
+
+	mockVerifier := new(MockVerifier)
+	mockVerifier.On("AllowedPath", path, mock.Anything).Return(false)
+	mockVerifier.On("VerifyUser", expectedToken).Return(&models.Claims{
+		Username:   user,
+		Generation: 1,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expires),
+		},
+	}, nil)
+	mockVerifier.On("CheckPermision", user, path).Return(true)
+
+	middleware := New(slog.Default(), mockVerifier)
+	generations := tokenGenerations{user: 1}
+	middleware.Accounts = generations
+
+	var session func(now time.Time) error
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session = middleware.Session(r)
+	})
+
+	req := httptest.NewRequest("GET", path, nil)
+	req.Header.Set("Authorization", "Bearer "+expectedToken)
+	middleware.JwtAuth(handler).ServeHTTP(httptest.NewRecorder(), req)
+	if !assert.NotNil(t, session) {
+		return
+	}
+
+	assert.NoError(t, session(time.Now()))
+	// Поток закрывается заранее, пока токен ещё можно обновить
+	assert.ErrorIs(t, session(expires.Add(-RefreshWindow/2)), ErrTokenExpiring)
+	// Пароль сбросили, пока поток был открыт
+	generations[user] = 2
+	assert.ErrorIs(t, session(time.Now()), ErrTokenRevoked)
+}
@@ -8,17 +8,27 @@ import (
 
 var Smokers = map[string]*models.Smoker{
 	"arthurCool": {
-		ID:             "1",
-		Name:           "Arthur",
-		Username:       "arthurCool",
-		Password:       "123qwe",
-		StoppedSmoking: time.Date(2025, time.February, 24, 0, 0, 0, 0, time.UTC),
+		ID:               "1",
+		Name:             "Arthur",
+		Username:         "arthurCool",
+		Password:         "123qwe",
+		StoppedSmoking:   time.Date(2025, time.February, 24, 0, 0, 0, 0, time.UTC),
+		CigarettesPerDay: 20,
+		PackSize:         20,
+		PackPrice:        250,
+		CreatedAt:        time.Date(2025, time.February, 20, 9, 30, 0, 0, time.UTC),
+		Version:          1,
 	},
 	"victorCool": {
-		ID:             "2",
-		Name:           "Victor",
-		Username:       "victorCool",
-		Password:       "qasw",
-		StoppedSmoking: time.Date(2024, time.January, 15, 0, 0, 0, 0, time.UTC),
+		ID:               "2",
+		Name:             "Victor",
+		Username:         "victorCool",
+		Password:         "qasw",
+		StoppedSmoking:   time.Date(2024, time.January, 15, 0, 0, 0, 0, time.UTC),
+		CigarettesPerDay: 10,
+		PackSize:         20,
+		PackPrice:        200,
+		CreatedAt:        time.Date(2024, time.January, 10, 18, 0, 0, 0, time.UTC),
+		Version:          1,
 	},
 }
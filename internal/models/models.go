@@ -7,11 +7,27 @@ import (
 )
 
 type Smoker struct {
-	ID             string    `json:"id"`
-	Name           string    `json:"name"`
-	Username       string    `json:"username"`
-	Password       string    `json:"password"`
-	StoppedSmoking time.Time `json:"stoppedSmoking"`
+	ID               string    `json:"id"`
+	Name             string    `json:"name"`
+	Username         string    `json:"username"`
+	Password         string    `json:"password,omitempty"`
+	StoppedSmoking   time.Time `json:"stoppedSmoking"`
+	CigarettesPerDay int       `json:"cigarettesPerDay"`
+	PackSize         int       `json:"packSize"`
+	PackPrice        float64   `json:"packPrice"`
+	CreatedAt        time.Time `json:"createdAt"`
+	// Version растёт при каждом изменении данных курильщика и служит его ETag
+	Version int `json:"version"`
+}
+
+// Валюта, в которой считаются деньги курильщика, пока он не выбрал свою
+const DefaultCurrency = "RUB"
+
+// PriceChange новая цена пачки, действующая с даты From (полночь UTC) до следующего изменения
+type PriceChange struct {
+	Price     float64   `json:"price"`
+	From      time.Time `json:"from"`
+	CreatedAt time.Time `json:"createdAt"`
 }
 
 type Credentials struct {
@@ -21,6 +37,10 @@ type Credentials struct {
 
 type Claims struct {
 	Username string `json:"username"`
+	// Impersonator администратор, который вошёл от имени Username
+	Impersonator string `json:"imp,omitempty"`
+	// Generation поколение токенов курильщика на момент выдачи
+	Generation int `json:"gen,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -31,3 +51,559 @@ type PageData struct {
 
 // Чтобы в контекст передавать не тип string
 type ContextString string
+
+// Craving запись в дневнике тяги
+type Craving struct {
+	ID       string    `json:"id"`
+	Username string    `json:"username"`
+	At       time.Time `json:"at"`
+	Resisted bool      `json:"resisted"`
+	Note     string    `json:"note"`
+}
+
+// SmokerStats показатели курильщика, по которым выдаются достижения
+type SmokerStats struct {
+	DaysSmokeFree    int
+	MoneySaved       float64
+	CravingsResisted int
+	JournalStreak    int
+}
+
+// AchievementRule правило выдачи достижения из конфигурационного файла
+type AchievementRule struct {
+	ID          string  `json:"id"`
+	Title       string  `json:"title"`
+	Description string  `json:"description"`
+	Metric      string  `json:"metric"`
+	Threshold   float64 `json:"threshold"`
+}
+
+// Achievement выданное курильщику достижение
+type Achievement struct {
+	RuleID      string    `json:"ruleId"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	AwardedAt   time.Time `json:"awardedAt"`
+}
+
+// Виды целей
+const (
+	GoalKindMoney = "money"
+	GoalKindDays  = "days"
+)
+
+// Состояния цели
+const (
+	GoalStatusInProgress = "in_progress"
+	GoalStatusCompleted  = "completed"
+	GoalStatusFailed     = "failed"
+)
+
+// Goal личная цель курильщика: накопить сумму или продержаться заданное число дней
+type Goal struct {
+	ID          string     `json:"id"`
+	Username    string     `json:"username"`
+	Title       string     `json:"title"`
+	Kind        string     `json:"kind"`
+	Target      float64    `json:"target"`
+	Deadline    *time.Time `json:"deadline,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+// GoalProgress прогресс курильщика по цели
+type GoalProgress struct {
+	*Goal
+	Current float64 `json:"current"`
+	Percent float64 `json:"percent"`
+	Status  string  `json:"status"`
+}
+
+// Способы снижения количества сигарет
+const (
+	TaperLinear      = "linear"
+	TaperExponential = "exponential"
+)
+
+// ReductionPlan план постепенного сокращения курения до даты отказа
+type ReductionPlan struct {
+	Username       string    `json:"username"`
+	StartDate      time.Time `json:"startDate"`
+	QuitDate       time.Time `json:"quitDate"`
+	StartAllowance int       `json:"startAllowance"`
+	Taper          string    `json:"taper"`
+	// PreviousStoppedSmoking дата отказа курильщика до того, как её заменил план
+	PreviousStoppedSmoking time.Time `json:"previousStoppedSmoking"`
+}
+
+// DailyAllowance допустимое количество сигарет на день плана и фактически выкуренное
+type DailyAllowance struct {
+	Date      time.Time `json:"date"`
+	Allowance int       `json:"allowance"`
+	Smoked    int       `json:"smoked"`
+}
+
+// Виды никотинозаместительной терапии (НЗТ)
+const (
+	NRTPatch   = "patch"
+	NRTGum     = "gum"
+	NRTLozenge = "lozenge"
+	NRTVape    = "vape"
+)
+
+// NRTProduct средство НЗТ, которое использует курильщик.
+// Strength — мг на единицу (пластырь, жвачка, леденец) или мг/мл для жидкости вейпа
+type NRTProduct struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	Type      string    `json:"type"`
+	Strength  float64   `json:"strength"`
+	StartDate time.Time `json:"startDate"`
+	StepDays  int       `json:"stepDays"`
+}
+
+// NRTStep ступень снижения дозировки
+type NRTStep struct {
+	StartDate time.Time `json:"startDate"`
+	Strength  float64   `json:"strength"`
+}
+
+// NRTUsage использование средства НЗТ за день
+type NRTUsage struct {
+	ProductID string    `json:"productId"`
+	Date      time.Time `json:"date"`
+	Units     float64   `json:"units"`
+	// Milliliters объём жидкости для вейпа: его дозировка указана в мг/мл, а не в мг на штуку
+	Milliliters float64 `json:"milliliters,omitempty"`
+	Strength    float64 `json:"strength"`
+}
+
+// NicotineIntake суммарное потребление никотина за день, мг
+type NicotineIntake struct {
+	Date       time.Time `json:"date"`
+	Milligrams float64   `json:"milligrams"`
+}
+
+// NRTStatus текущее состояние средства НЗТ с напоминанием о следующем снижении
+type NRTStatus struct {
+	*NRTProduct
+	Current    NRTStep   `json:"current"`
+	Next       *NRTStep  `json:"next,omitempty"`
+	DaysToNext int       `json:"daysToNext"`
+	Schedule   []NRTStep `json:"schedule"`
+}
+
+// Симптомы отмены, которые можно отметить при ежедневной отметке самочувствия
+var WithdrawalSymptoms = []string{
+	"irritability",
+	"anxiety",
+	"insomnia",
+	"appetite",
+	"concentration",
+	"headache",
+}
+
+// CheckIn ежедневная отметка самочувствия. Mood и Stress — по шкале от 1 до 5
+type CheckIn struct {
+	Username   string    `json:"username"`
+	Date       time.Time `json:"date"`
+	Mood       int       `json:"mood"`
+	SleepHours float64   `json:"sleepHours"`
+	Stress     int       `json:"stress"`
+	Symptoms   []string  `json:"symptoms"`
+	Note       string    `json:"note"`
+}
+
+// WeeklySummary сводка самочувствия за последние 7 дней.
+// Корреляции — коэффициент Пирсона, nil если данных недостаточно
+type WeeklySummary struct {
+	From                time.Time      `json:"from"`
+	To                  time.Time      `json:"to"`
+	CheckIns            int            `json:"checkIns"`
+	AverageMood         float64        `json:"averageMood"`
+	AverageSleep        float64        `json:"averageSleep"`
+	AverageStress       float64        `json:"averageStress"`
+	Cravings            int            `json:"cravings"`
+	Symptoms            map[string]int `json:"symptoms"`
+	MoodVsCravings      *float64       `json:"moodVsCravings"`
+	MoodVsDaysSmokeFree *float64       `json:"moodVsDaysSmokeFree"`
+}
+
+// Состояния приглашения в напарники
+const (
+	InviteStatusPending  = "pending"
+	InviteStatusAccepted = "accepted"
+	InviteStatusDeclined = "declined"
+)
+
+// BuddyInvite приглашение стать напарниками по отказу от курения
+type BuddyInvite struct {
+	Token     string    `json:"token"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// SharingSettings что курильщик показывает напарнику
+type SharingSettings struct {
+	Streak     bool `json:"streak"`
+	Milestones bool `json:"milestones"`
+	Cravings   bool `json:"cravings"`
+}
+
+// Buddyship связь двух напарников с настройками приватности каждого из них
+type Buddyship struct {
+	Users   [2]string                  `json:"users"`
+	Sharing map[string]SharingSettings `json:"sharing"`
+	Since   time.Time                  `json:"since"`
+}
+
+// BuddyMessage сообщение поддержки от напарника
+type BuddyMessage struct {
+	ID     string    `json:"id"`
+	From   string    `json:"from"`
+	To     string    `json:"to"`
+	Text   string    `json:"text"`
+	SentAt time.Time `json:"sentAt"`
+}
+
+// BuddyView данные напарника, которые он разрешил показывать. Скрытые поля не заполняются
+type BuddyView struct {
+	Username       string          `json:"username"`
+	Name           string          `json:"name"`
+	Since          time.Time       `json:"since"`
+	MySharing      SharingSettings `json:"mySharing"`
+	DaysSmokeFree  *int            `json:"daysSmokeFree,omitempty"`
+	Milestones     []*Achievement  `json:"milestones,omitempty"`
+	RecentCravings []*Craving      `json:"recentCravings,omitempty"`
+}
+
+// Состояния группового челленджа
+const (
+	ChallengeUpcoming = "upcoming"
+	ChallengeActive   = "active"
+	ChallengeFinished = "finished"
+)
+
+// Показатели, по которым строится таблица лидеров
+const (
+	RankBySmokeFree  = "smoke_free"
+	RankByMoneySaved = "money_saved"
+)
+
+// Challenge групповой челлендж: участники вместе бросают курить с общей даты
+type Challenge struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	CreatedBy string    `json:"-"`
+	StartDate time.Time `json:"startDate"`
+	EndDate   time.Time `json:"endDate"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ChallengeMember участник челленджа. Anonymous — показывать в таблице лидеров без имени
+type ChallengeMember struct {
+	Username  string    `json:"username"`
+	JoinedAt  time.Time `json:"joinedAt"`
+	Anonymous bool      `json:"anonymous"`
+	// Number порядковый номер вступления в челлендж, под ним показывается анонимный участник.
+	// Не меняется, когда другие участники выходят
+	Number int `json:"number"`
+}
+
+// LeaderboardEntry строка таблицы лидеров. Username не попадает в JSON,
+// чтобы не раскрывать анонимных участников
+type LeaderboardEntry struct {
+	Rank          int     `json:"rank"`
+	Username      string  `json:"-"`
+	DisplayName   string  `json:"displayName"`
+	Anonymous     bool    `json:"-"`
+	Number        int     `json:"-"`
+	SmokeFreeDays float64 `json:"smokeFreeDays"`
+	MoneySaved    float64 `json:"moneySaved"`
+	IsMe          bool    `json:"isMe"`
+}
+
+// Виды реакций на посты в сообществе
+var Reactions = []string{"like", "support", "strong"}
+
+// Post пост или ответ (ParentID не пустой) в ленте сообщества
+type Post struct {
+	ID        string              `json:"id"`
+	ParentID  string              `json:"parentId,omitempty"`
+	Author    string              `json:"author"`
+	Text      string              `json:"text"`
+	CreatedAt time.Time           `json:"createdAt"`
+	Hidden    bool                `json:"hidden"`
+	HiddenBy  string              `json:"hiddenBy,omitempty"`
+	Reactions map[string][]string `json:"reactions"`
+}
+
+// Thread пост сообщества с ответами
+type Thread struct {
+	*Post
+	Replies []*Post `json:"replies"`
+}
+
+// PostReport жалоба на пост
+type PostReport struct {
+	PostID    string    `json:"postId"`
+	Reporter  string    `json:"reporter"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Ban блокировка пользователя в сообществе
+type Ban struct {
+	Username  string    `json:"username"`
+	By        string    `json:"by"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Виды уведомлений
+const (
+	NotificationMilestone     = "milestone"
+	NotificationCheckIn       = "checkin_reminder"
+	NotificationGoalCompleted = "goal_completed"
+	NotificationCravingHelp   = "craving_help"
+)
+
+// Каналы доставки уведомлений
+const (
+	ChannelInApp   = "in_app"
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+	ChannelPush    = "push"
+)
+
+// Состояния доставки уведомления
+const (
+	DeliveryPending = "pending"
+	DeliverySent    = "sent"
+	DeliveryFailed  = "failed"
+)
+
+// NotificationSettings настройки уведомлений курильщика.
+// Тихие часы задаются в часах по местному времени, QuietStart > QuietEnd означает интервал через полночь
+type NotificationSettings struct {
+	Username    string   `json:"username"`
+	Timezone    string   `json:"timezone"`
+	QuietStart  int      `json:"quietStart"`
+	QuietEnd    int      `json:"quietEnd"`
+	CheckInHour int      `json:"checkInHour"`
+	Channels    []string `json:"channels"`
+	Email       string   `json:"email"`
+	WebhookURL  string   `json:"webhookUrl"`
+}
+
+// Notification уведомление курильщику. Key не даёт отправить одно и то же уведомление дважды
+type Notification struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	Kind      string    `json:"kind"`
+	Key       string    `json:"-"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Delivery доставка уведомления по одному каналу
+type Delivery struct {
+	ID             string     `json:"id"`
+	NotificationID string     `json:"notificationId"`
+	Username       string     `json:"username"`
+	Channel        string     `json:"channel"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	LastError      string     `json:"lastError,omitempty"`
+	NextAttempt    time.Time  `json:"nextAttempt"`
+	SentAt         *time.Time `json:"sentAt,omitempty"`
+}
+
+// Виды мотивирующих записей курильщика
+const (
+	ReasonText   = "reason"
+	ReasonPhoto  = "photo"
+	ReasonLetter = "letter"
+)
+
+// Reason мотивирующая запись, которую курильщик сделал для себя: причина бросить курить,
+// фотография с подписью или письмо себе. Письмо с OpenAfterDays откроется только
+// после стольких дней без сигарет
+type Reason struct {
+	ID            string    `json:"id"`
+	Username      string    `json:"-"`
+	Kind          string    `json:"kind"`
+	Title         string    `json:"title,omitempty"`
+	Text          string    `json:"text"`
+	MediaKey      string    `json:"-"`
+	MediaType     string    `json:"mediaType,omitempty"`
+	OpenAfterDays int       `json:"openAfterDays,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// MotivationPick мотивирующая запись для профиля и почему выбрана именно она
+type MotivationPick struct {
+	Reason *Reason `json:"reason"`
+	Why    string  `json:"why"`
+}
+
+// Виды шагов SOS-сессии
+const (
+	SOSStepBreathing   = "breathing"
+	SOSStepDistraction = "distraction"
+	SOSStepReason      = "reason"
+)
+
+// Итоги SOS-сессии
+const (
+	SOSOutcomeResisted = "resisted"
+	SOSOutcomeSmoked   = "smoked"
+)
+
+// SOSStep шаг SOS-сессии. Offset — сколько секунд прошло от начала сессии до шага
+type SOSStep struct {
+	Kind     string `json:"kind"`
+	Text     string `json:"text"`
+	Offset   int    `json:"offset"`
+	Duration int    `json:"duration"`
+}
+
+// SOSSession сессия помощи при тяге. Пока Outcome пустой, сессия не завершена
+type SOSSession struct {
+	ID              string     `json:"id"`
+	Username        string     `json:"-"`
+	StartedAt       time.Time  `json:"startedAt"`
+	EndsAt          time.Time  `json:"endsAt"`
+	Steps           []SOSStep  `json:"steps"`
+	BuddiesNotified int        `json:"buddiesNotified"`
+	Outcome         string     `json:"outcome,omitempty"`
+	CravingID       string     `json:"cravingId,omitempty"`
+	FinishedAt      *time.Time `json:"finishedAt,omitempty"`
+}
+
+// SOSState состояние сессии на текущий момент
+type SOSState struct {
+	*SOSSession
+	Current   *SOSStep `json:"current,omitempty"`
+	Step      int      `json:"step"`
+	Remaining int      `json:"remaining"`
+	Done      bool     `json:"done"`
+}
+
+// ChatMessage сообщение в чате напарников или участников челленджа
+type ChatMessage struct {
+	ID     string    `json:"id"`
+	Room   string    `json:"room"`
+	From   string    `json:"-"`
+	Text   string    `json:"text"`
+	SentAt time.Time `json:"sentAt"`
+}
+
+// PushSubscription подписка браузера на Web Push в том виде, в каком её отдаёт PushManager.subscribe
+type PushSubscription struct {
+	Username  string    `json:"-"`
+	Endpoint  string    `json:"endpoint"`
+	Keys      PushKeys  `json:"keys"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// PushKeys ключи подписки в base64url: публичный ключ браузера P-256 и секрет аутентификации
+type PushKeys struct {
+	P256dh string `json:"p256dh"`
+	Auth   string `json:"auth"`
+}
+
+// Виды записей во входящих, кроме уведомлений планировщика
+const (
+	InboxBuddyInvite  = "buddy_invite"
+	InboxBuddyMessage = "buddy_message"
+	InboxPostReply    = "post_reply"
+)
+
+// InboxItem запись во входящих курильщика. Link — страница, к которой относится запись
+type InboxItem struct {
+	ID        string     `json:"id"`
+	Username  string     `json:"username"`
+	Kind      string     `json:"kind"`
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+	Link      string     `json:"link,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ReadAt    *time.Time `json:"readAt,omitempty"`
+}
+
+// AccountLock блокировка учётной записи: заблокированный курильщик не может войти,
+// а его выданные токены перестают действовать
+type AccountLock struct {
+	Username string    `json:"username"`
+	By       string    `json:"by"`
+	Reason   string    `json:"reason"`
+	At       time.Time `json:"at"`
+}
+
+// Действия, которые попадают в журнал аудита
+const (
+	AuditSigninSuccess    = "signin.success"
+	AuditSigninFailure    = "signin.failure"
+	AuditLogout           = "logout"
+	AuditAccessDenied     = "access.denied"
+	AuditSmokerCreate     = "smoker.create"
+	AuditSmokerUpdate     = "smoker.update"
+	AuditSmokerDelete     = "smoker.delete"
+	AuditPasswordReset    = "password.reset"
+	AuditAccountLock      = "account.lock"
+	AuditAccountUnlock    = "account.unlock"
+	AuditRoleChange       = "role.change"
+	AuditImpersonateStart = "impersonate.start"
+	AuditImpersonateStop  = "impersonate.stop"
+)
+
+// AuditActions все действия журнала аудита
+func AuditActions() []string {
+	return []string{
+		AuditSigninSuccess, AuditSigninFailure, AuditLogout, AuditAccessDenied,
+		AuditSmokerCreate, AuditSmokerUpdate, AuditSmokerDelete,
+		AuditPasswordReset, AuditAccountLock, AuditAccountUnlock, AuditRoleChange,
+		AuditImpersonateStart, AuditImpersonateStop,
+	}
+}
+
+// AuditEvent запись журнала аудита: кто (Actor) что сделал (Action) с кем (Target).
+// Actor пуст, если действие совершил не вошедший пользователь, например при неудачном входе
+type AuditEvent struct {
+	ID     string    `json:"id"`
+	At     time.Time `json:"at"`
+	Actor  string    `json:"actor,omitempty"`
+	Action string    `json:"action"`
+	Target string    `json:"target,omitempty"`
+	// Impersonator администратор, который действовал от имени Actor
+	Impersonator string `json:"impersonator,omitempty"`
+	Details      string `json:"details,omitempty"`
+	IP           string `json:"ip"`
+	UserAgent    string `json:"userAgent,omitempty"`
+	RequestID    string `json:"requestId,omitempty"`
+	// Suppressed сколько таких же событий с этого адреса не записано перед этим
+	Suppressed int `json:"suppressed,omitempty"`
+}
+
+// AdminStats сводка по всем курильщикам для администратора
+type AdminStats struct {
+	Smokers int
+	Locked  int
+	// AverageStreak и MedianStreak — дни без сигарет
+	AverageStreak float64
+	MedianStreak  int
+	Cravings      int
+	// Relapses эпизоды тяги, когда курильщик закурил
+	Relapses int
+	// RelapsePercent доля срывов среди всех эпизодов тяги, в процентах
+	RelapsePercent float64
+	// RecentlyRelapsed курильщики, которые закурили за последние 30 дней
+	RecentlyRelapsed int
+	// RecentRelapsePercent доля таких курильщиков среди всех, в процентах
+	RecentRelapsePercent float64
+	ByRole               map[string]int
+}
@@ -0,0 +1,124 @@
+// Package motivation выбирает, какую из мотивирующих записей курильщика показать
+// на профиле, с учётом времени суток, недавней тяги и длины серии без сигарет
+package motivation
+
+import (
+	"math/rand/v2"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+// Сколько дней после открытия письмо считается новым и показывается первым
+const freshLetterDays = 3
+
+// Сколько эпизодов тяги за сутки делают день трудным
+const hardDayCravings = 3
+
+// Context обстоятельства, в которых курильщик открыл профиль
+type Context struct {
+	// Now местное время курильщика
+	Now            time.Time
+	StreakDays     int
+	RecentCravings int
+}
+
+// RecentCravings считает эпизоды тяги за последние сутки
+func RecentCravings(cravings []*models.Craving, now time.Time) int {
+	count := 0
+	for _, craving := range cravings {
+		if now.Sub(craving.At) < 24*time.Hour && !craving.At.After(now) {
+			count++
+		}
+	}
+	return count
+}
+
+// Unlocked сообщает, можно ли уже показывать запись: письма ждут своего дня
+func Unlocked(reason *models.Reason, streakDays int) bool {
+	return reason.Kind != models.ReasonLetter || streakDays >= reason.OpenAfterDays
+}
+
+// Pick выбирает запись случайно, но с весами: в трудный день чаще причины и письма,
+// утром фотографии, вечером письма, в первую неделю — причины. Только что открывшееся
+// письмо показывается в первую очередь. Если показывать нечего, возвращает nil
+func Pick(reasons []*models.Reason, ctx Context, rnd *rand.Rand) *models.MotivationPick {
+	var fresh *models.Reason
+	for _, reason := range reasons {
+		if reason.Kind == models.ReasonLetter && reason.OpenAfterDays > 0 && Unlocked(reason, ctx.StreakDays) &&
+			ctx.StreakDays-reason.OpenAfterDays < freshLetterDays &&
+			(fresh == nil || reason.OpenAfterDays > fresh.OpenAfterDays) {
+			fresh = reason
+		}
+	}
+	if fresh != nil {
+		return &models.MotivationPick{Reason: fresh, Why: "Открылось письмо, которое вы написали себе заранее"}
+	}
+
+	type candidate struct {
+		reason *models.Reason
+		weight float64
+		why    string
+	}
+
+	var (
+		candidates []candidate
+		total      float64
+	)
+	hour := ctx.Now.Hour()
+	for _, reason := range reasons {
+		if !Unlocked(reason, ctx.StreakDays) {
+			continue
+		}
+
+		c := candidate{reason: reason, weight: 1, why: "Вы записали это для себя"}
+		best := 1.0
+		boost := func(factor float64, why string) {
+			c.weight *= factor
+			if factor > best {
+				best = factor
+				c.why = why
+			}
+		}
+
+		switch reason.Kind {
+		case models.ReasonText:
+			if ctx.RecentCravings >= hardDayCravings {
+				boost(4, "Сегодня непростой день — вспомните, ради чего вы бросаете")
+			}
+			if ctx.StreakDays < 7 {
+				boost(2, "Первая неделя самая трудная — держитесь за свои причины")
+			}
+			if hour < 5 {
+				boost(2, "Ночью тянет сильнее — вспомните, ради чего вы бросаете")
+			}
+		case models.ReasonLetter:
+			if ctx.RecentCravings >= hardDayCravings {
+				boost(3, "Сегодня непростой день — вот что вы написали себе")
+			}
+			if hour >= 18 {
+				boost(2, "Вечером — письмо от себя")
+			}
+		case models.ReasonPhoto:
+			if hour >= 5 && hour < 12 {
+				boost(3, "Начните утро с того, что для вас важно")
+			}
+		}
+
+		candidates = append(candidates, c)
+		total += c.weight
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	x := rnd.Float64() * total
+	for _, c := range candidates {
+		x -= c.weight
+		if x < 0 {
+			return &models.MotivationPick{Reason: c.reason, Why: c.why}
+		}
+	}
+	last := candidates[len(candidates)-1]
+	return &models.MotivationPick{Reason: last.reason, Why: last.why}
+}
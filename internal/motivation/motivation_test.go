@@ -0,0 +1,56 @@
+package motivation
+
+import (
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPick(t *testing.T) {
+	reasons := []*models.Reason{
+		{ID: "1", Kind: models.ReasonText, Text: "Ради здоровья"},
+		{ID: "2", Kind: models.ReasonPhoto, Text: "Семья"},
+		{ID: "3", Kind: models.ReasonLetter, Text: "Ты справился с первым месяцем!", OpenAfterDays: 30},
+	}
+	rnd := rand.New(rand.NewPCG(1, 2))
+	morning := time.Date(2025, time.March, 1, 8, 0, 0, 0, time.UTC)
+
+	// Письмо на 30 дней ещё закрыто
+	for range 50 {
+		pick := Pick(reasons, Context{Now: morning, StreakDays: 10}, rnd)
+		assert.NotEqual(t, "3", pick.Reason.ID)
+	}
+
+	// Только что открывшееся письмо показывается первым
+	pick := Pick(reasons, Context{Now: morning, StreakDays: 31}, rnd)
+	assert.Equal(t, "3", pick.Reason.ID)
+
+	// Утром чаще фотографии, а в трудный день — причины
+	counts := map[string]int{}
+	for range 1000 {
+		counts[Pick(reasons[:2], Context{Now: morning, StreakDays: 10}, rnd).Reason.ID]++
+	}
+	assert.Greater(t, counts["2"], counts["1"])
+
+	counts = map[string]int{}
+	evening := time.Date(2025, time.March, 1, 20, 0, 0, 0, time.UTC)
+	for range 1000 {
+		counts[Pick(reasons[:2], Context{Now: evening, StreakDays: 10, RecentCravings: 3}, rnd).Reason.ID]++
+	}
+	assert.Greater(t, counts["1"], counts["2"])
+
+	assert.Nil(t, Pick(nil, Context{Now: morning}, rnd))
+}
+
+func TestRecentCravings(t *testing.T) {
+	now := time.Now()
+	cravings := []*models.Craving{
+		{At: now.Add(-time.Hour)},
+		{At: now.Add(-23 * time.Hour)},
+		{At: now.Add(-25 * time.Hour)},
+	}
+	assert.Equal(t, 2, RecentCravings(cravings, now))
+}
@@ -0,0 +1,64 @@
+// Package netguard исходящие запросы на адреса, которые задают пользователи: вебхуки и
+// endpoint push-подписок. Такие запросы не должны попадать во внутреннюю сеть
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateAddress адрес ведёт во внутреннюю сеть: на loopback, в частную или link-local подсеть
+var ErrPrivateAddress = errors.New("address is not public")
+
+// Transport создаёт транспорт, который соединяется только с публичными адресами.
+// Проверка стоит в самом dialer, поэтому её не обойти ни редиректом, ни DNS rebinding
+func Transport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip, err := netip.ParseAddr(host)
+			if err != nil || !Public(ip) {
+				return ErrPrivateAddress
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// Через прокси dialer увидел бы только адрес прокси
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+// CheckHost разрешает имя хоста и возвращает ErrPrivateAddress, если хотя бы один
+// из его адресов не публичный. Нужна, чтобы отклонить адрес ещё при сохранении
+func CheckHost(ctx context.Context, host string) error {
+	op := "netguard.CheckHost"
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	for _, addr := range addrs {
+		if !Public(addr) {
+			return fmt.Errorf("%s: %s: %w", op, addr, ErrPrivateAddress)
+		}
+	}
+	return nil
+}
+
+// Public сообщает, что адрес не loopback, не частный, не link-local и не multicast
+func Public(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate()
+}
@@ -0,0 +1,44 @@
+package notify
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/i18n"
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+type CravingHelpStore interface {
+	LanguageStore
+	GetBuddies(username string) []string
+	GetNotificationSettings(username string) (models.NotificationSettings, bool)
+	AddNotification(notification *models.Notification, channels []string) bool
+}
+
+// CravingHelp просит напарников поддержать курильщика, которого накрыла тяга.
+// Уведомления уходят по каналам, выбранным каждым напарником, на его языке и доставляются планировщиком
+func CravingHelp(store CravingHelpStore, bundle *i18n.Bundle, username, name, note string, at time.Time) int {
+	asked := 0
+	for _, buddy := range store.GetBuddies(username) {
+		settings, ok := store.GetNotificationSettings(buddy)
+		if !ok {
+			settings = DefaultSettings(buddy)
+		}
+		loc := localizer(store, bundle, buddy)
+		body := loc.T("notify.craving_help.body")
+		if note != "" {
+			body = loc.T("notify.craving_help.body_note", note)
+		}
+		if store.AddNotification(&models.Notification{
+			Username:  buddy,
+			Kind:      models.NotificationCravingHelp,
+			Key:       fmt.Sprintf("craving_help:%s:%s:%d", username, buddy, at.Unix()),
+			Title:     loc.T("notify.craving_help.title", name),
+			Body:      body,
+			CreatedAt: at,
+		}, settings.Channels) {
+			asked++
+		}
+	}
+	return asked
+}
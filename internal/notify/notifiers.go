@@ -0,0 +1,229 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/inbox"
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/NarthurN/QuitSmoking/internal/netguard"
+	"github.com/NarthurN/QuitSmoking/internal/webpush"
+)
+
+// Страницы, на которые ведут уведомления во входящих
+var links = map[string]string{
+	models.NotificationMilestone:     "/profile",
+	models.NotificationCheckIn:       "/checkin",
+	models.NotificationGoalCompleted: "/profile",
+	models.NotificationCravingHelp:   "/buddies",
+}
+
+// Notifier доставляет уведомление по одному каналу
+type Notifier interface {
+	Channel() string
+	Notify(ctx context.Context, settings models.NotificationSettings, notification *models.Notification) error
+}
+
+// InApp кладёт уведомления во входящие внутри приложения
+type InApp struct {
+	inbox *inbox.Inbox
+}
+
+func NewInApp(inbox *inbox.Inbox) *InApp {
+	return &InApp{inbox: inbox}
+}
+
+func (n *InApp) Channel() string {
+	return models.ChannelInApp
+}
+
+func (n *InApp) Notify(_ context.Context, _ models.NotificationSettings, notification *models.Notification) error {
+	n.inbox.Post(notification.Username, notification.Kind, notification.Title, notification.Body, links[notification.Kind])
+	return nil
+}
+
+// Email отправляет уведомления письмом через SMTP-сервер.
+// Для разработки подойдёт локальная заглушка вроде MailHog на localhost:1025
+type Email struct {
+	addr     string
+	from     string
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+func NewEmail(addr, from string) *Email {
+	return &Email{
+		addr:     addr,
+		from:     from,
+		sendMail: smtp.SendMail,
+	}
+}
+
+func (n *Email) Channel() string {
+	return models.ChannelEmail
+}
+
+func (n *Email) Notify(_ context.Context, settings models.NotificationSettings, notification *models.Notification) error {
+	op := "notify.Email.Notify"
+
+	if settings.Email == "" {
+		return fmt.Errorf("%s: email is not set", op)
+	}
+	if strings.ContainsAny(settings.Email, "\r\n") {
+		return fmt.Errorf("%s: invalid email", op)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", n.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", settings.Email)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", notification.Title))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(notification.Body)
+	msg.WriteString("\r\n")
+
+	if err := n.sendMail(n.addr, nil, n.from, []string{settings.Email}, msg.Bytes()); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// ErrPrivateAddress адрес вебхука ведёт во внутреннюю сеть
+var ErrPrivateAddress = netguard.ErrPrivateAddress
+
+// Webhook отправляет уведомления POST-запросом с JSON на адрес, указанный курильщиком
+type Webhook struct {
+	client *http.Client
+}
+
+// NewWebhook создаёт отправителя, который соединяется только с публичными адресами
+func NewWebhook() *Webhook {
+	return &Webhook{client: &http.Client{Timeout: 10 * time.Second, Transport: netguard.Transport()}}
+}
+
+// CheckWebhookURL проверяет адрес вебхука при сохранении настроек: схема http или https
+// и имя, которое разрешается только в публичные адреса. Иначе возвращает ErrPrivateAddress
+func CheckWebhookURL(ctx context.Context, raw string) error {
+	op := "notify.CheckWebhookURL"
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("%s: unsupported url %q", op, raw)
+	}
+	if err := netguard.CheckHost(ctx, u.Hostname()); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (n *Webhook) Channel() string {
+	return models.ChannelWebhook
+}
+
+func (n *Webhook) Notify(ctx context.Context, settings models.NotificationSettings, notification *models.Notification) error {
+	op := "notify.Webhook.Notify"
+
+	if settings.WebhookURL == "" {
+		return fmt.Errorf("%s: webhook url is not set", op)
+	}
+
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, settings.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	req.Header.Set("Content-Type", "application/json;charset=utf-8")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: unexpected status %d", op, resp.StatusCode)
+	}
+	return nil
+}
+
+// Сколько push-сервис хранит уведомление, пока браузер не в сети
+const pushTTL = 24 * time.Hour
+
+type PushStore interface {
+	GetPushSubscriptions(username string) []models.PushSubscription
+	DeletePushSubscription(username, endpoint string) bool
+}
+
+// Push отправляет уведомления через Web Push во все браузеры, где курильщик на них подписался.
+// Подписки, которые push-сервис считает удалёнными, удаляются и у нас
+type Push struct {
+	client *webpush.Client
+	store  PushStore
+}
+
+func NewPush(client *webpush.Client, store PushStore) *Push {
+	return &Push{client: client, store: store}
+}
+
+func (n *Push) Channel() string {
+	return models.ChannelPush
+}
+
+func (n *Push) Notify(ctx context.Context, _ models.NotificationSettings, notification *models.Notification) error {
+	op := "notify.Push.Notify"
+
+	payload, err := json.Marshal(struct {
+		Title string `json:"title"`
+		Body  string `json:"body"`
+		URL   string `json:"url"`
+	}{
+		Title: notification.Title,
+		Body:  notification.Body,
+		URL:   links[notification.Kind],
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var (
+		sent int
+		errs []error
+	)
+	for _, subscription := range n.store.GetPushSubscriptions(notification.Username) {
+		err := n.client.Send(ctx, subscription, payload, pushTTL)
+		switch {
+		case errors.Is(err, webpush.ErrGone):
+			n.store.DeletePushSubscription(notification.Username, subscription.Endpoint)
+		case err != nil:
+			errs = append(errs, err)
+		default:
+			sent++
+		}
+	}
+
+	// Достаточно доставить хотя бы в один браузер, иначе при повторе
+	// уведомление придёт дважды туда, где оно уже есть
+	if sent > 0 {
+		return nil
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s: %w", op, errors.Join(errs...))
+	}
+	return fmt.Errorf("%s: no push subscriptions", op)
+}
@@ -0,0 +1,119 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/i18n"
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/NarthurN/QuitSmoking/internal/storage"
+	"github.com/NarthurN/QuitSmoking/internal/webpush"
+	"github.com/NarthurN/QuitSmoking/internal/webpush/webpushtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPushNotifier(t *testing.T) {
+	vapid, err := webpush.GenerateVAPID("mailto:admin@example.com")
+	assert.NoError(t, err)
+	service := webpushtest.NewServer(vapid.PublicKey())
+	defer service.Close()
+
+	store := storage.New()
+	push := NewPush(webpush.NewClient(vapid, true), store)
+	notification := &models.Notification{Username: "arthur", Kind: models.NotificationMilestone, Title: "Неделя без сигарет", Body: "Так держать"}
+
+	// Без подписок доставлять некуда
+	assert.Error(t, push.Notify(context.Background(), models.NotificationSettings{}, notification))
+
+	active := service.Subscribe()
+	active.Username = "arthur"
+	gone := service.Subscribe()
+	gone.Username = "arthur"
+	store.SavePushSubscription(&active)
+	store.SavePushSubscription(&gone)
+	service.Unsubscribe(gone.Endpoint)
+
+	assert.NoError(t, push.Notify(context.Background(), models.NotificationSettings{}, notification))
+
+	messages := service.Messages()
+	if assert.Len(t, messages, 1) {
+		var payload struct {
+			Title string `json:"title"`
+			URL   string `json:"url"`
+		}
+		assert.NoError(t, json.Unmarshal(messages[0].Payload, &payload))
+		assert.Equal(t, "Неделя без сигарет", payload.Title)
+		assert.Equal(t, "/profile", payload.URL)
+	}
+
+	// Отменённая подписка удалена
+	subscriptions := store.GetPushSubscriptions("arthur")
+	if assert.Len(t, subscriptions, 1) {
+		assert.Equal(t, active.Endpoint, subscriptions[0].Endpoint)
+	}
+}
+
+func TestCravingHelp(t *testing.T) {
+	store := storage.New()
+	now := time.Now().UTC()
+	for _, buddy := range []string{"victor", "olga"} {
+		store.AddBuddyInvite(&models.BuddyInvite{Token: buddy, From: "arthur", To: buddy, Status: models.InviteStatusPending, CreatedAt: now})
+		store.RespondBuddyInvite(buddy, true, now)
+	}
+	store.SaveNotificationSettings(&models.NotificationSettings{Username: "olga", Timezone: "UTC", Channels: []string{models.ChannelPush}})
+
+	assert.Equal(t, 2, CravingHelp(store, i18n.Default(), "arthur", "Артур", "", now))
+	// Повторный запрос в ту же секунду не дублирует уведомления
+	assert.Equal(t, 0, CravingHelp(store, i18n.Default(), "arthur", "Артур", "", now))
+
+	deliveries := store.GetDeliveries("olga")
+	if assert.Len(t, deliveries, 1) {
+		assert.Equal(t, models.ChannelPush, deliveries[0].Channel)
+	}
+	deliveries = store.GetDeliveries("victor")
+	if assert.Len(t, deliveries, 1) {
+		assert.Equal(t, models.ChannelInApp, deliveries[0].Channel)
+	}
+}
+
+func TestCheckWebhookURL(t *testing.T) {
+	ctx := context.Background()
+
+	assert.NoError(t, CheckWebhookURL(ctx, "https://93.184.216.34/hook"))
+	assert.Error(t, CheckWebhookURL(ctx, "ftp://93.184.216.34/hook"))
+	assert.Error(t, CheckWebhookURL(ctx, "https:///hook"))
+
+	// Внутренние адреса отклоняются ещё при сохранении настроек
+	for _, raw := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://10.0.0.5/hook",
+		"http://192.168.1.1/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::1]/hook",
+		"http://[fe80::1]/hook",
+		"http://[::ffff:127.0.0.1]/hook",
+		"http://0.0.0.0/hook",
+	} {
+		assert.ErrorIs(t, CheckWebhookURL(ctx, raw), ErrPrivateAddress, raw)
+	}
+}
+
+func TestWebhookRefusesPrivateAddress(t *testing.T) {
+	var called bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	// Даже если адрес прошёл проверку при сохранении, а потом стал указывать
+	// во внутреннюю сеть, dialer не соединяется с ним
+	settings := models.NotificationSettings{WebhookURL: server.URL}
+	err := NewWebhook().Notify(context.Background(), settings, &models.Notification{Username: "arthur"})
+	assert.ErrorIs(t, err, ErrPrivateAddress)
+	assert.False(t, called)
+}
@@ -0,0 +1,273 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/achievements"
+	"github.com/NarthurN/QuitSmoking/internal/goals"
+	"github.com/NarthurN/QuitSmoking/internal/helpers"
+	"github.com/NarthurN/QuitSmoking/internal/i18n"
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+// Сколько раз пытаться доставить уведомление и через сколько повторять первую попытку.
+// Каждая следующая пауза вдвое длиннее предыдущей
+const (
+	MaxAttempts  = 5
+	RetryBackoff = time.Minute
+)
+
+// LanguageStore знает язык, который выбрал курильщик
+type LanguageStore interface {
+	GetLanguage(username string) (string, bool)
+}
+
+type Store interface {
+	goals.Store
+	LanguageStore
+	GetNotificationSettings(username string) (models.NotificationSettings, bool)
+	AddNotification(notification *models.Notification, channels []string) bool
+	GetNotification(id string) (*models.Notification, bool)
+	GetDueDeliveries(now time.Time) []models.Delivery
+	UpdateDelivery(delivery models.Delivery)
+	GetCravings(username string) []*models.Craving
+	AllSmokers() []*models.Smoker
+	GetCheckIns(username string) []*models.CheckIn
+	GetPriceHistory(username string) []models.PriceChange
+}
+
+// Scheduler в фоне создаёт уведомления о приближающихся достижениях, напоминания
+// об отметке самочувствия и уведомления о выполненных целях, а затем доставляет их
+// с учётом часового пояса и тихих часов курильщика
+type Scheduler struct {
+	store        Store
+	bundle       *i18n.Bundle
+	achievements *achievements.Engine
+	notifiers    map[string]Notifier
+	logger       *slog.Logger
+	interval     time.Duration
+}
+
+func NewScheduler(
+	store Store,
+	bundle *i18n.Bundle,
+	engine *achievements.Engine,
+	logger *slog.Logger,
+	interval time.Duration,
+	notifiers ...Notifier,
+) *Scheduler {
+	byChannel := make(map[string]Notifier, len(notifiers))
+	for _, n := range notifiers {
+		byChannel[n.Channel()] = n
+	}
+
+	return &Scheduler{
+		store:        store,
+		bundle:       bundle,
+		achievements: engine,
+		notifiers:    byChannel,
+		logger:       logger,
+		interval:     interval,
+	}
+}
+
+// DefaultSettings настройки уведомлений для курильщика, который их не менял
+func DefaultSettings(username string) models.NotificationSettings {
+	return models.NotificationSettings{
+		Username:    username,
+		Timezone:    "Europe/Moscow",
+		QuietStart:  22,
+		QuietEnd:    8,
+		CheckInHour: 20,
+		Channels:    []string{models.ChannelInApp},
+	}
+}
+
+// Run запускает планировщик и блокируется до отмены ctx
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.Tick(ctx, time.Now().UTC())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick создаёт новые уведомления и доставляет те, время которых наступило
+func (s *Scheduler) Tick(ctx context.Context, now time.Time) {
+	// Снимок курильщиков: пока планировщик работает, их могут изменить или удалить
+	for _, smoker := range s.store.AllSmokers() {
+		s.schedule(smoker.Username, smoker, now)
+	}
+	s.deliver(ctx, now)
+}
+
+func (s *Scheduler) schedule(username string, smoker *models.Smoker, now time.Time) {
+	settings := s.settings(username)
+	loc := location(settings.Timezone)
+	localNow := now.In(loc)
+	localDay := localNow.Format(time.DateOnly)
+
+	cravings := s.store.GetCravings(username)
+	prices := s.store.GetPriceHistory(username)
+	stats := helpers.GetSmokerStats(smoker, cravings, prices)
+	texts := localizer(s.store, s.bundle, username)
+
+	for _, rule := range s.achievements.Upcoming(stats) {
+		title, description := achievements.Text(texts, rule.ID, rule.Title, rule.Description)
+		s.store.AddNotification(&models.Notification{
+			Username:  username,
+			Kind:      models.NotificationMilestone,
+			Key:       fmt.Sprintf("milestone:%s:%s", username, rule.ID),
+			Title:     texts.T("notify.milestone.title", title),
+			Body:      texts.T("notify.milestone.body", title, description),
+			CreatedAt: now,
+		}, settings.Channels)
+	}
+
+	if localNow.Hour() >= settings.CheckInHour && !checkedIn(s.store.GetCheckIns(username), now, localNow) {
+		s.store.AddNotification(&models.Notification{
+			Username:  username,
+			Kind:      models.NotificationCheckIn,
+			Key:       fmt.Sprintf("checkin:%s:%s", username, localDay),
+			Title:     texts.T("notify.checkin.title"),
+			Body:      texts.T("notify.checkin.body"),
+			CreatedAt: now,
+		}, settings.Channels)
+	}
+
+	for _, p := range goals.Track(s.store, username, helpers.GoalStats(smoker, prices)) {
+		if p.Status != models.GoalStatusCompleted {
+			continue
+		}
+		s.store.AddNotification(&models.Notification{
+			Username:  username,
+			Kind:      models.NotificationGoalCompleted,
+			Key:       fmt.Sprintf("goal:%s:%s", username, p.ID),
+			Title:     texts.T("notify.goal.title", p.Title),
+			Body:      texts.T("notify.goal.body"),
+			CreatedAt: now,
+		}, settings.Channels)
+	}
+}
+
+func (s *Scheduler) deliver(ctx context.Context, now time.Time) {
+	for _, delivery := range s.store.GetDueDeliveries(now) {
+		notification, ok := s.store.GetNotification(delivery.NotificationID)
+		if !ok {
+			continue
+		}
+		settings := s.settings(delivery.Username)
+
+		// Уведомления во входящих беззвучны, поэтому тихие часы их не задерживают
+		if delivery.Channel != models.ChannelInApp {
+			if quiet, end := QuietUntil(settings, now); quiet {
+				delivery.NextAttempt = end
+				s.store.UpdateDelivery(delivery)
+				continue
+			}
+		}
+
+		notifier, ok := s.notifiers[delivery.Channel]
+		if !ok {
+			delivery.Status = models.DeliveryFailed
+			delivery.LastError = "unknown channel"
+			s.store.UpdateDelivery(delivery)
+			continue
+		}
+
+		delivery.Attempts++
+		if err := notifier.Notify(ctx, settings, notification); err != nil {
+			s.logger.Error("notify.Scheduler.deliver", helpers.SlogErr(err), "channel", delivery.Channel, "attempt", delivery.Attempts)
+			delivery.LastError = err.Error()
+			if delivery.Attempts >= MaxAttempts {
+				delivery.Status = models.DeliveryFailed
+			} else {
+				delivery.NextAttempt = now.Add(RetryBackoff << (delivery.Attempts - 1))
+			}
+			s.store.UpdateDelivery(delivery)
+			continue
+		}
+
+		delivery.Status = models.DeliverySent
+		delivery.LastError = ""
+		delivery.SentAt = &now
+		s.store.UpdateDelivery(delivery)
+	}
+}
+
+func (s *Scheduler) settings(username string) models.NotificationSettings {
+	if settings, ok := s.store.GetNotificationSettings(username); ok {
+		return settings
+	}
+	return DefaultSettings(username)
+}
+
+// QuietUntil сообщает, идут ли у курильщика тихие часы, и когда они закончатся
+func QuietUntil(settings models.NotificationSettings, now time.Time) (bool, time.Time) {
+	if settings.QuietStart == settings.QuietEnd {
+		return false, time.Time{}
+	}
+
+	local := now.In(location(settings.Timezone))
+	hour := local.Hour()
+
+	var quiet bool
+	if settings.QuietStart < settings.QuietEnd {
+		quiet = hour >= settings.QuietStart && hour < settings.QuietEnd
+	} else {
+		quiet = hour >= settings.QuietStart || hour < settings.QuietEnd
+	}
+	if !quiet {
+		return false, time.Time{}
+	}
+
+	end := time.Date(local.Year(), local.Month(), local.Day(), settings.QuietEnd, 0, 0, 0, local.Location())
+	if !end.After(local) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return true, end.UTC()
+}
+
+// ValidChannel сообщает, поддерживается ли канал доставки
+func ValidChannel(channel string) bool {
+	return slices.Contains([]string{models.ChannelInApp, models.ChannelEmail, models.ChannelWebhook, models.ChannelPush}, channel)
+}
+
+// checkedIn сообщает, отмечался ли курильщик сегодня. Отметки хранятся по дню в UTC,
+// поэтому сегодняшней считается отметка и за местную дату, и за текущую дату в UTC
+func checkedIn(checkIns []*models.CheckIn, now, localNow time.Time) bool {
+	utcToday := now.UTC().Truncate(24 * time.Hour)
+	localToday := time.Date(localNow.Year(), localNow.Month(), localNow.Day(), 0, 0, 0, 0, time.UTC)
+	for _, checkIn := range checkIns {
+		if checkIn.Date.Equal(utcToday) || checkIn.Date.Equal(localToday) {
+			return true
+		}
+	}
+	return false
+}
+
+func location(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// localizer переводчик на язык получателя уведомления. Текст уведомления
+// складывается сразу, а доставляется позже, поэтому язык берётся из настроек, а не из запроса
+func localizer(store LanguageStore, bundle *i18n.Bundle, username string) *i18n.Localizer {
+	locale, _ := store.GetLanguage(username)
+	return bundle.Localizer(locale)
+}
@@ -0,0 +1,90 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/achievements"
+	"github.com/NarthurN/QuitSmoking/internal/i18n"
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/NarthurN/QuitSmoking/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeNotifier struct {
+	channel string
+	err     error
+	sent    []*models.Notification
+}
+
+func (n *fakeNotifier) Channel() string {
+	return n.channel
+}
+
+func (n *fakeNotifier) Notify(_ context.Context, _ models.NotificationSettings, notification *models.Notification) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.sent = append(n.sent, notification)
+	return nil
+}
+
+func TestQuietUntil(t *testing.T) {
+	settings := models.NotificationSettings{Timezone: "Europe/Moscow", QuietStart: 22, QuietEnd: 8}
+
+	// 20:00 UTC = 23:00 по Москве
+	quiet, end := QuietUntil(settings, time.Date(2025, time.March, 1, 20, 0, 0, 0, time.UTC))
+	assert.True(t, quiet)
+	assert.Equal(t, time.Date(2025, time.March, 2, 5, 0, 0, 0, time.UTC), end)
+
+	// 09:00 UTC = 12:00 по Москве
+	quiet, _ = QuietUntil(settings, time.Date(2025, time.March, 1, 9, 0, 0, 0, time.UTC))
+	assert.False(t, quiet)
+}
+
+func TestSchedulerCheckInReminderAndRetries(t *testing.T) {
+	store := storage.New()
+	store.SaveNotificationSettings(&models.NotificationSettings{
+		Username:    "arthur",
+		Timezone:    "UTC",
+		CheckInHour: 20,
+		Channels:    []string{models.ChannelInApp, models.ChannelWebhook},
+	})
+	store.AddSmoker(&models.Smoker{ID: "1", Username: "arthur", StoppedSmoking: time.Now()})
+	inApp := &fakeNotifier{channel: models.ChannelInApp}
+	webhook := &fakeNotifier{channel: models.ChannelWebhook, err: errors.New("connection refused")}
+
+	scheduler := NewScheduler(store, i18n.Default(), achievements.New(nil), slog.Default(), time.Minute, inApp, webhook)
+
+	now := time.Date(2025, time.March, 1, 20, 30, 0, 0, time.UTC)
+	scheduler.Tick(context.Background(), now)
+	// Напоминание за день создаётся один раз
+	scheduler.Tick(context.Background(), now.Add(time.Second))
+
+	assert.Len(t, inApp.sent, 1)
+
+	var webhookDelivery models.Delivery
+	for _, d := range store.GetDeliveries("arthur") {
+		if d.Channel == models.ChannelWebhook {
+			webhookDelivery = d
+		}
+	}
+	assert.Equal(t, models.DeliveryPending, webhookDelivery.Status)
+	assert.Equal(t, 1, webhookDelivery.Attempts)
+	assert.Equal(t, now.Add(RetryBackoff), webhookDelivery.NextAttempt)
+
+	// После исчерпания попыток доставка считается неудачной
+	for i := 0; i < MaxAttempts; i++ {
+		now = now.Add(time.Hour)
+		scheduler.deliver(context.Background(), now)
+	}
+	for _, d := range store.GetDeliveries("arthur") {
+		if d.Channel == models.ChannelWebhook {
+			assert.Equal(t, models.DeliveryFailed, d.Status)
+			assert.Equal(t, MaxAttempts, d.Attempts)
+		}
+	}
+}
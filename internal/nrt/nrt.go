@@ -0,0 +1,148 @@
+package nrt
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+const day = 24 * time.Hour
+
+// Стандартные дозировки, по которым снижается каждый вид НЗТ
+var ladders = map[string][]float64{
+	models.NRTPatch:   {21, 14, 7},
+	models.NRTGum:     {4, 2},
+	models.NRTLozenge: {4, 2},
+	models.NRTVape:    {20, 12, 6, 3},
+}
+
+// Длительность ступени по умолчанию, дней
+var defaultStepDays = map[string]int{
+	models.NRTPatch:   28,
+	models.NRTGum:     21,
+	models.NRTLozenge: 21,
+	models.NRTVape:    30,
+}
+
+// KnownType сообщает, поддерживается ли вид НЗТ
+func KnownType(productType string) bool {
+	_, ok := ladders[productType]
+	return ok
+}
+
+// DefaultStepDays возвращает рекомендуемую длительность ступени для вида НЗТ
+func DefaultStepDays(productType string) int {
+	return defaultStepDays[productType]
+}
+
+// Schedule строит график снижения дозировки: начальная дозировка, затем все стандартные
+// дозировки ниже неё и, в конце, полный отказ (дозировка 0)
+func Schedule(product *models.NRTProduct) []models.NRTStep {
+	strengths := []float64{product.Strength}
+	for _, s := range ladders[product.Type] {
+		if s < product.Strength {
+			strengths = append(strengths, s)
+		}
+	}
+	strengths = append(strengths, 0)
+
+	start := product.StartDate.UTC().Truncate(day)
+	steps := make([]models.NRTStep, 0, len(strengths))
+	for i, s := range strengths {
+		steps = append(steps, models.NRTStep{
+			StartDate: start.Add(time.Duration(i*product.StepDays) * day),
+			Strength:  s,
+		})
+	}
+	return steps
+}
+
+// CurrentStep возвращает ступень, действующую в момент now
+func CurrentStep(product *models.NRTProduct, now time.Time) models.NRTStep {
+	steps := Schedule(product)
+	current := steps[0]
+	for _, step := range steps {
+		if step.StartDate.After(now) {
+			break
+		}
+		current = step
+	}
+	return current
+}
+
+// NextStepDown возвращает следующую ступень снижения, если она ещё не наступила
+func NextStepDown(product *models.NRTProduct, now time.Time) (models.NRTStep, bool) {
+	for _, step := range Schedule(product) {
+		if step.StartDate.After(now) {
+			return step, true
+		}
+	}
+	return models.NRTStep{}, false
+}
+
+// Intake считает потребление никотина по дням в порядке возрастания даты
+func Intake(usages []*models.NRTUsage) []models.NicotineIntake {
+	byDay := make(map[time.Time]float64)
+	for _, u := range usages {
+		byDay[u.Date.UTC().Truncate(day)] += Milligrams(u)
+	}
+
+	intake := make([]models.NicotineIntake, 0, len(byDay))
+	for date, mg := range byDay {
+		intake = append(intake, models.NicotineIntake{Date: date, Milligrams: math.Round(mg*100) / 100})
+	}
+	sort.Slice(intake, func(i, j int) bool {
+		return intake[i].Date.Before(intake[j].Date)
+	})
+	return intake
+}
+
+// Milligrams возвращает количество никотина в одном использовании. Для вейпа дозировка
+// указана в мг/мл, поэтому умножается на объём жидкости, а не на число штук
+func Milligrams(usage *models.NRTUsage) float64 {
+	if usage.Milliliters > 0 {
+		return usage.Milliliters * usage.Strength
+	}
+	return usage.Units * usage.Strength
+}
+
+// Trend возвращает наклон линии тренда потребления никотина (мг в день за день)
+// по методу наименьших квадратов. Отрицательное значение — потребление снижается
+func Trend(intake []models.NicotineIntake) float64 {
+	if len(intake) < 2 {
+		return 0
+	}
+
+	first := intake[0].Date
+	var sumX, sumY, sumXY, sumXX float64
+	for _, in := range intake {
+		x := in.Date.Sub(first).Hours() / 24
+		sumX += x
+		sumY += in.Milligrams
+		sumXY += x * in.Milligrams
+		sumXX += x * x
+	}
+
+	n := float64(len(intake))
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0
+	}
+	return math.Round((n*sumXY-sumX*sumY)/denominator*100) / 100
+}
+
+// Status собирает текущее состояние средства НЗТ и напоминание о следующем снижении дозировки
+func Status(product *models.NRTProduct, now time.Time) models.NRTStatus {
+	status := models.NRTStatus{
+		NRTProduct: product,
+		Current:    CurrentStep(product, now),
+		Schedule:   Schedule(product),
+	}
+	if next, ok := NextStepDown(product, now); ok {
+		status.Next = &next
+		status.DaysToNext = int(math.Ceil(next.StartDate.Sub(now).Hours() / 24))
+	}
+	return status
+}
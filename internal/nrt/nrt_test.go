@@ -0,0 +1,57 @@
+package nrt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedule(t *testing.T) {
+	start := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	patch := &models.NRTProduct{Type: models.NRTPatch, Strength: 21, StartDate: start, StepDays: 28}
+
+	steps := Schedule(patch)
+
+	assert.Len(t, steps, 4)
+	assert.Equal(t, []float64{21, 14, 7, 0}, []float64{steps[0].Strength, steps[1].Strength, steps[2].Strength, steps[3].Strength})
+	assert.Equal(t, start.Add(56*day), steps[2].StartDate)
+
+	now := start.Add(30 * day)
+	assert.Equal(t, 14.0, CurrentStep(patch, now).Strength)
+
+	next, ok := NextStepDown(patch, now)
+	assert.True(t, ok)
+	assert.Equal(t, 7.0, next.Strength)
+}
+
+func TestIntakeAndTrend(t *testing.T) {
+	start := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	usages := []*models.NRTUsage{
+		{Date: start, Units: 10, Strength: 4},
+		{Date: start.Add(time.Hour), Units: 1, Strength: 21},
+		{Date: start.Add(day), Units: 8, Strength: 4},
+		{Date: start.Add(2 * day), Units: 6, Strength: 4},
+	}
+
+	intake := Intake(usages)
+
+	assert.Len(t, intake, 3)
+	assert.Equal(t, 61.0, intake[0].Milligrams)
+	assert.Less(t, Trend(intake), 0.0)
+}
+
+func TestIntakeVape(t *testing.T) {
+	start := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	usages := []*models.NRTUsage{
+		// 2,5 мл жидкости крепостью 12 мг/мл — 30 мг, а не 12
+		{Date: start, Milliliters: 2.5, Strength: 12},
+		{Date: start.Add(time.Hour), Units: 2, Strength: 4},
+	}
+
+	intake := Intake(usages)
+
+	assert.Len(t, intake, 1)
+	assert.Equal(t, 38.0, intake[0].Milligrams)
+}
@@ -0,0 +1,72 @@
+package reduction
+
+import (
+	"math"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+const day = 24 * time.Hour
+
+// Schedule строит расписание допустимого количества сигарет на каждый день
+// от StartDate (включительно) до QuitDate (не включительно). В день отказа допустимо 0.
+func Schedule(plan *models.ReductionPlan, smoked map[time.Time]int) []models.DailyAllowance {
+	if plan == nil {
+		return nil
+	}
+
+	start := truncateDay(plan.StartDate)
+	quit := truncateDay(plan.QuitDate)
+	days := int(quit.Sub(start) / day)
+	if days <= 0 {
+		return nil
+	}
+
+	// Для экспоненциального снижения подбираем множитель так,
+	// чтобы на следующий день после последнего дня плана осталось пол-сигареты
+	ratio := 1.0
+	if plan.Taper == models.TaperExponential && plan.StartAllowance > 0 {
+		ratio = math.Pow(0.5/float64(plan.StartAllowance), 1/float64(days))
+	}
+
+	schedule := make([]models.DailyAllowance, 0, days)
+	for i := 0; i < days; i++ {
+		var allowance float64
+		switch plan.Taper {
+		case models.TaperExponential:
+			allowance = float64(plan.StartAllowance) * math.Pow(ratio, float64(i))
+		default:
+			allowance = float64(plan.StartAllowance) * float64(days-i) / float64(days)
+		}
+
+		date := start.Add(time.Duration(i) * day)
+		schedule = append(schedule, models.DailyAllowance{
+			Date:      date,
+			Allowance: max(int(math.Round(allowance)), 1),
+			Smoked:    smoked[date],
+		})
+	}
+	return schedule
+}
+
+// Active сообщает, действует ли план в момент now. После даты отказа курильщик переходит
+// к обычному отслеживанию времени без сигарет
+func Active(plan *models.ReductionPlan, now time.Time) bool {
+	return plan != nil && now.Before(truncateDay(plan.QuitDate))
+}
+
+// Today возвращает день плана, соответствующий now
+func Today(plan *models.ReductionPlan, smoked map[time.Time]int, now time.Time) (models.DailyAllowance, bool) {
+	today := truncateDay(now)
+	for _, d := range Schedule(plan, smoked) {
+		if d.Date.Equal(today) {
+			return d, true
+		}
+	}
+	return models.DailyAllowance{}, false
+}
+
+func truncateDay(t time.Time) time.Time {
+	return t.UTC().Truncate(day)
+}
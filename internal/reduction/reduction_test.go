@@ -0,0 +1,61 @@
+package reduction
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduleLinear(t *testing.T) {
+	start := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	plan := &models.ReductionPlan{
+		StartDate:      start,
+		QuitDate:       start.Add(4 * day),
+		StartAllowance: 20,
+		Taper:          models.TaperLinear,
+	}
+
+	schedule := Schedule(plan, map[time.Time]int{start: 18})
+
+	assert.Len(t, schedule, 4)
+	assert.Equal(t, []int{20, 15, 10, 5}, allowances(schedule))
+	assert.Equal(t, 18, schedule[0].Smoked)
+}
+
+func TestScheduleExponential(t *testing.T) {
+	start := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	plan := &models.ReductionPlan{
+		StartDate:      start,
+		QuitDate:       start.Add(10 * day),
+		StartAllowance: 20,
+		Taper:          models.TaperExponential,
+	}
+
+	got := allowances(Schedule(plan, nil))
+
+	assert.Len(t, got, 10)
+	assert.Equal(t, 20, got[0])
+	assert.Equal(t, 1, got[9])
+	for i := 1; i < len(got); i++ {
+		assert.LessOrEqual(t, got[i], got[i-1])
+	}
+}
+
+func TestActive(t *testing.T) {
+	quit := time.Date(2025, time.March, 10, 0, 0, 0, 0, time.UTC)
+	plan := &models.ReductionPlan{QuitDate: quit}
+
+	assert.True(t, Active(plan, quit.Add(-time.Hour)))
+	assert.False(t, Active(plan, quit))
+	assert.False(t, Active(nil, quit))
+}
+
+func allowances(schedule []models.DailyAllowance) []int {
+	result := make([]int, 0, len(schedule))
+	for _, d := range schedule {
+		result = append(result, d.Allowance)
+	}
+	return result
+}
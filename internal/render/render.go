@@ -0,0 +1,156 @@
+// Package render разбирает HTML-шаблоны страниц один раз при старте и отрисовывает их.
+// Каждая страница собирается из общего макета templates/layout и своего файла
+// в templates, который переопределяет блоки макета. Шаблоны разбираются отдельно
+// для каждого языка, чтобы функции перевода t, plural и date знали свой язык
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/i18n"
+)
+
+const (
+	layoutPattern = "templates/layout/*.html"
+	pagesPattern  = "templates/*.html"
+
+	// Шаблон из макета, с которого начинается отрисовка любой страницы
+	baseTemplate = "base"
+)
+
+// Templates разобранные шаблоны страниц по языку и имени файла, например "profile.html"
+type Templates struct {
+	fsys   fs.FS
+	dev    bool
+	bundle *i18n.Bundle
+	pages  map[string]map[string]*template.Template
+
+	buffers sync.Pool
+}
+
+// New разбирает все страницы из fsys. В режиме разработки шаблоны разбираются заново
+// при каждой отрисовке, чтобы правки были видны без перезапуска
+func New(fsys fs.FS, dev bool, bundle *i18n.Bundle) (*Templates, error) {
+	t := &Templates{
+		fsys:   fsys,
+		dev:    dev,
+		bundle: bundle,
+		buffers: sync.Pool{
+			New: func() any { return new(bytes.Buffer) },
+		},
+	}
+
+	pages, err := t.parse()
+	if err != nil {
+		return nil, err
+	}
+	t.pages = pages
+	return t, nil
+}
+
+// Must как New, но паникует при ошибке. Подходит для шаблонов, встроенных в бинарник
+func Must(t *Templates, err error) *Templates {
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Funcs функции шаблонов для языка:
+//
+//	{{t "nav.profile" .Name}}        перевод с подстановкой
+//	{{plural "duration.days" .Days}} перевод с формой множественного числа
+//	{{date .CreatedAt}}              дата в принятом для языка формате
+//	{{money .Saved .Currency}}       сумма в валюте: "1 234,50 ₽" или "$1,234.50"
+//	{{number .Value 1}}              число с разделителями разрядов
+//	{{currency .Currency}}           символ валюты
+//	{{lang}}                         код языка страницы
+//	{{locales}}                      все поддерживаемые языки
+func Funcs(bundle *i18n.Bundle, locale string) template.FuncMap {
+	loc := bundle.Localizer(locale)
+	return template.FuncMap{
+		"t":      loc.T,
+		"plural": loc.Plural,
+		"date": func(t time.Time) string {
+			return t.Format(loc.T("date.format"))
+		},
+		"money":    loc.Money,
+		"number":   loc.Number,
+		"currency": loc.CurrencySymbol,
+		"lang":     loc.Locale,
+		"locales":  bundle.Locales,
+	}
+}
+
+func (t *Templates) parse() (map[string]map[string]*template.Template, error) {
+	op := "render.Templates.parse"
+
+	files, err := fs.Glob(t.fsys, pagesPattern)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	pages := make(map[string]map[string]*template.Template, len(t.bundle.Locales()))
+	for _, locale := range t.bundle.Locales() {
+		layout, err := template.New("").Funcs(Funcs(t.bundle, locale)).ParseFS(t.fsys, layoutPattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		pages[locale] = make(map[string]*template.Template, len(files))
+		for _, file := range files {
+			page, err := template.Must(layout.Clone()).ParseFS(t.fsys, file)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+			if page.Lookup(baseTemplate) == nil {
+				return nil, fmt.Errorf("%s: %s: template %q is not defined", op, file, baseTemplate)
+			}
+			pages[locale][path.Base(file)] = page
+		}
+	}
+	return pages, nil
+}
+
+// Render отрисовывает страницу на языке locale сначала в буфер и только потом пишет
+// статус и тело, поэтому при ошибке шаблона в ответ ничего не уходит и можно ответить 500
+func (t *Templates) Render(w http.ResponseWriter, status int, locale, name string, data any) error {
+	op := "render.Templates.Render"
+
+	pages := t.pages
+	if t.dev {
+		var err error
+		if pages, err = t.parse(); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	if _, ok := pages[locale]; !ok {
+		locale = i18n.DefaultLocale
+	}
+
+	page, ok := pages[locale][name]
+	if !ok {
+		return fmt.Errorf("%s: page %q not found", op, name)
+	}
+
+	buf := t.buffers.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer t.buffers.Put(buf)
+
+	if err := page.ExecuteTemplate(buf, baseTemplate, data); err != nil {
+		return fmt.Errorf("%s: %s: %w", op, name, err)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Language", locale)
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+	return nil
+}
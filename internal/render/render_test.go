@@ -0,0 +1,106 @@
+package render
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/i18n"
+	"github.com/stretchr/testify/assert"
+)
+
+var testFS = fstest.MapFS{
+	"templates/layout/base.html":   {Data: []byte(`{{define "base"}}<title>{{block "title" .}}QuitSmoking{{end}}</title>{{template "nav" .}}{{block "content" .}}{{end}}{{end}}`)},
+	"templates/layout/header.html": {Data: []byte(`{{define "nav"}}<nav>{{.Name}}</nav>{{end}}`)},
+	"templates/page.html":          {Data: []byte(`{{define "title"}}Страница{{end}}{{define "content"}}<p>{{.Text}}</p>{{end}}`)},
+	"templates/plain.html":         {Data: []byte(`{{define "content"}}{{.Missing}}{{end}}`)},
+}
+
+func TestRender(t *testing.T) {
+	templates, err := New(testFS, false, i18n.Default())
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	err = templates.Render(w, http.StatusCreated, "ru", "page.html", struct{ Name, Text string }{
+		Name: "Arthur",
+		Text: "<script>alert(1)</script>",
+	})
+	assert.NoError(t, err)
+
+	// Статус и тип проставлены, макет подхватил блоки страницы, данные экранированы
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "ru", w.Header().Get("Content-Language"))
+	assert.Equal(t, "<title>Страница</title><nav>Arthur</nav><p>&lt;script&gt;alert(1)&lt;/script&gt;</p>", w.Body.String())
+}
+
+func TestRenderErrorWritesNothing(t *testing.T) {
+	templates, err := New(testFS, false, i18n.Default())
+	assert.NoError(t, err)
+
+	// Ошибка в середине страницы не должна оставить в ответе половину HTML
+	w := httptest.NewRecorder()
+	err = templates.Render(w, http.StatusOK, "ru", "plain.html", struct{ Name string }{Name: "Arthur"})
+	assert.Error(t, err)
+	assert.False(t, w.Flushed)
+	assert.Empty(t, w.Body.String())
+	assert.Empty(t, w.Header().Get("Content-Type"))
+
+	err = templates.Render(w, http.StatusOK, "ru", "unknown.html", nil)
+	assert.Error(t, err)
+}
+
+func TestNewWithBrokenTemplate(t *testing.T) {
+	fsys := fstest.MapFS{
+		"templates/layout/base.html": {Data: []byte(`{{define "base"}}{{block "content" .}}{{end}}{{end}}`)},
+		"templates/broken.html":      {Data: []byte(`{{define "content"}}{{if}}{{end}}`)},
+	}
+
+	_, err := New(fsys, false, i18n.Default())
+	assert.Error(t, err)
+}
+
+func TestDevModeRereads(t *testing.T) {
+	fsys := fstest.MapFS{
+		"templates/layout/base.html": {Data: []byte(`{{define "base"}}{{block "content" .}}{{end}}{{end}}`)},
+		"templates/page.html":        {Data: []byte(`{{define "content"}}старый{{end}}`)},
+	}
+	templates, err := New(fsys, true, i18n.Default())
+	assert.NoError(t, err)
+
+	fsys["templates/page.html"] = &fstest.MapFile{Data: []byte(`{{define "content"}}новый{{end}}`)}
+
+	w := httptest.NewRecorder()
+	assert.NoError(t, templates.Render(w, http.StatusOK, "ru", "page.html", nil))
+	assert.Equal(t, "новый", w.Body.String())
+}
+
+func TestRenderLocales(t *testing.T) {
+	fsys := fstest.MapFS{
+		"templates/layout/base.html": {Data: []byte(`{{define "base"}}<html lang="{{lang}}">{{block "content" .}}{{end}}</html>{{end}}`)},
+		"templates/page.html":        {Data: []byte(`{{define "content"}}{{t "nav.profile" .Name}}; {{plural "duration.days" .Days}}; {{date .At}}{{end}}`)},
+	}
+	templates, err := New(fsys, false, i18n.Default())
+	assert.NoError(t, err)
+
+	data := struct {
+		Name string
+		Days int
+		At   time.Time
+	}{Name: "Arthur", Days: 22, At: time.Date(2025, time.March, 8, 0, 0, 0, 0, time.UTC)}
+
+	w := httptest.NewRecorder()
+	assert.NoError(t, templates.Render(w, http.StatusOK, "ru", "page.html", data))
+	assert.Equal(t, `<html lang="ru">Профиль Arthur; 22 дня; 08.03.2025</html>`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	assert.NoError(t, templates.Render(w, http.StatusOK, "en", "page.html", data))
+	assert.Equal(t, `<html lang="en">Arthur&#39;s profile; 22 days; Mar 8, 2025</html>`, w.Body.String())
+
+	// Неизвестный язык заменяется языком по умолчанию
+	w = httptest.NewRecorder()
+	assert.NoError(t, templates.Render(w, http.StatusOK, "de", "page.html", data))
+	assert.Equal(t, "ru", w.Header().Get("Content-Language"))
+}
@@ -26,18 +26,96 @@ func SetupRoutes(h *handlers.Handlers) http.Handler {
 	mux.Handle(`POST /signin`, h.Signin())
 	mux.Handle("GET /logout", h.Logout())
 	mux.Handle(`GET /smokers`, h.GetSmokers())
+	mux.Handle(`POST /smokers`, h.PostSmoker())
+	mux.Handle(`GET /smokers/{id}`, h.GetSmoker())
+	mux.Handle(`PUT /smokers/{id}`, h.PutSmoker())
+	mux.Handle(`PATCH /smokers/{id}`, h.PatchSmoker())
+	mux.Handle(`DELETE /smokers/{id}`, h.DeleteSmoker())
+	mux.Handle(`GET /admin`, h.GetAdminPage())
+	mux.Handle(`GET /admin/audit`, h.GetAuditPage())
+	mux.Handle(`GET /admin/audit/export`, h.ExportAudit())
+	mux.Handle(`GET /admin/smokers/{id}`, h.GetAdminSmoker())
+	mux.Handle(`POST /admin/smokers/{id}/password`, h.PostAdminPassword())
+	mux.Handle(`POST /admin/smokers/{id}/lock`, h.PostAdminLock())
+	mux.Handle(`POST /admin/smokers/{id}/unlock`, h.PostAdminUnlock())
+	mux.Handle(`POST /admin/smokers/{id}/roles`, h.PostAdminRoles())
+	mux.Handle(`POST /admin/smokers/{id}/impersonate`, h.PostAdminImpersonate())
+	mux.Handle(`POST /impersonation/stop`, h.StopImpersonation())
 	mux.Handle(`GET /profile`, h.GetSmokerProfile())
+	mux.Handle(`GET /profile/live`, h.GetProfileLive())
+	mux.Handle(`POST /cravings`, h.PostCraving())
+	mux.Handle(`GET /cravings`, h.GetCravings())
+	mux.Handle(`POST /sos`, h.PostSOS())
+	mux.Handle(`GET /sos/{id}`, h.GetSOSPage())
+	mux.Handle(`GET /sos/{id}/state`, h.GetSOSState())
+	mux.Handle(`POST /sos/{id}/finish`, h.FinishSOS())
+	mux.Handle(`POST /reasons`, h.PostReason())
+	mux.Handle(`GET /reasons`, h.GetReasons())
+	mux.Handle(`POST /reasons/letters`, h.PostReasonLetter())
+	mux.Handle(`POST /reasons/photos`, h.PostReasonPhoto())
+	mux.Handle(`GET /reasons/{id}/photo`, h.GetReasonPhoto())
+	mux.Handle(`DELETE /reasons/{id}`, h.DeleteReason())
+	mux.Handle(`GET /motivation`, h.GetMotivation())
+	mux.Handle(`GET /achievements`, h.GetAchievements())
+	mux.Handle(`POST /goals`, h.PostGoal())
+	mux.Handle(`GET /goals`, h.GetGoals())
+	mux.Handle(`DELETE /goals/{id}`, h.DeleteGoal())
+	mux.Handle(`POST /reduction`, h.PostReductionPlan())
+	mux.Handle(`GET /reduction`, h.GetReductionPlan())
+	mux.Handle(`POST /reduction/cigarettes`, h.PostCigarette())
+	mux.Handle(`POST /nrt`, h.PostNRTProduct())
+	mux.Handle(`GET /nrt`, h.GetNRT())
+	mux.Handle(`POST /nrt/{id}/usage`, h.PostNRTUsage())
+	mux.Handle(`GET /checkin`, h.GetCheckInForm())
+	mux.Handle(`POST /checkin`, h.PostCheckIn())
+	mux.Handle(`GET /checkins`, h.GetCheckIns())
+	mux.Handle(`GET /checkins/summary`, h.GetWeeklySummary())
+	mux.Handle(`GET /buddies`, h.GetBuddiesPage())
+	mux.Handle(`GET /buddies/shared`, h.GetBuddies())
+	mux.Handle(`POST /buddies/invite`, h.PostBuddyInvite())
+	mux.Handle(`POST /buddies/invites/{token}/accept`, h.AcceptBuddyInvite())
+	mux.Handle(`POST /buddies/invites/{token}/decline`, h.DeclineBuddyInvite())
+	mux.Handle(`POST /buddies/{username}/sharing`, h.PostBuddySharing())
+	mux.Handle(`GET /buddies/{username}/messages`, h.GetBuddyMessages())
+	mux.Handle(`POST /buddies/{username}/messages`, h.PostBuddyMessage())
+	mux.Handle(`DELETE /buddies/{username}`, h.DeleteBuddy())
+	mux.Handle(`GET /challenges`, h.GetChallengesPage())
+	mux.Handle(`POST /challenges`, h.PostChallenge())
+	mux.Handle(`GET /challenges/{id}`, h.GetChallengePage())
+	mux.Handle(`GET /challenges/{id}/leaderboard`, h.GetChallengeLeaderboard())
+	mux.Handle(`POST /challenges/{id}/join`, h.JoinChallenge())
+	mux.Handle(`POST /challenges/{id}/leave`, h.LeaveChallenge())
+	mux.Handle(`GET /feed`, h.GetFeedPage())
+	mux.Handle(`GET /feed/posts`, h.GetFeed())
+	mux.Handle(`POST /feed/posts`, h.PostFeedPost())
+	mux.Handle(`POST /feed/posts/{id}/reactions`, h.PostReaction())
+	mux.Handle(`POST /feed/posts/{id}/report`, h.PostReport())
+	mux.Handle(`POST /feed/posts/{id}/hide`, h.HidePost())
+	mux.Handle(`POST /feed/posts/{id}/unhide`, h.UnhidePost())
+	mux.Handle(`POST /feed/bans`, h.PostBan())
+	mux.Handle(`DELETE /feed/bans/{username}`, h.DeleteBan())
+	mux.Handle(`GET /notifications/settings`, h.GetNotificationSettings())
+	mux.Handle(`POST /notifications/settings`, h.PostNotificationSettings())
+	mux.Handle(`GET /notifications/deliveries`, h.GetDeliveries())
+	mux.Handle(`GET /inbox`, h.GetInboxPage())
+	mux.Handle(`GET /inbox/items`, h.GetInbox())
+	mux.Handle(`POST /inbox/{id}/read`, h.MarkInboxItemRead())
+	mux.Handle(`POST /inbox/read-all`, h.MarkAllInboxRead())
+	mux.Handle(`GET /chat`, h.GetChat())
+	mux.Handle(`POST /settings/language`, h.PostLanguage())
+	mux.Handle(`POST /settings/currency`, h.PostCurrency())
+	mux.Handle(`POST /prices`, h.PostPrice())
+	mux.Handle(`GET /prices`, h.GetPrices())
+	mux.Handle(`DELETE /prices/{from}`, h.DeletePrice())
+	mux.Handle(`GET /push/key`, h.GetPushKey())
+	mux.Handle(`POST /push/subscriptions`, h.PostPushSubscription())
+	mux.Handle(`DELETE /push/subscriptions`, h.DeletePushSubscription())
 
-	mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServer(http.Dir("static"))))
+	mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServerFS(h.Assets)))
 	// mux.Handle("GET /static/", http.FileServer(http.Dir("static")))
-	return h.Mw.Log(h.Mw.JwtAuth(mux))
+	return h.Mw.RequestID(h.Mw.Log(h.Mw.JwtAuth(mux)))
 }
 
-// r.Post("/smokers", handlers.PostSmoker)
-// r.Delete("/smokers/{id}", handlers.DeleteSmoker)
-// r.Put("/smokers/{id}", handlers.PutSmoker)
-// r.Get("/smokers/{id}", handlers.GetSmokersDiffTime)
-
 func SetupLogger(level string) *slog.Logger {
 	var slogLevel slog.Level
 
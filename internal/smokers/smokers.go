@@ -0,0 +1,192 @@
+// Package smokers выбирает курильщиков для списка администратора: фильтрует,
+// сортирует и делит на страницы по курсору. Курсор помнит ключ сортировки и id
+// последнего курильщика на странице, поэтому страницы не съезжают, когда
+// курильщиков добавляют или удаляют между запросами
+package smokers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+// Поля сортировки. С "-" впереди список идёт по убыванию:
+// "-smokeFree" — сначала те, кто не курит дольше всех, "-createdAt" — сначала новые
+const (
+	SortSmokeFree = "smokeFree"
+	SortCreatedAt = "createdAt"
+
+	DefaultSort = "-" + SortCreatedAt
+)
+
+// Размер страницы по умолчанию и наибольший
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// ErrInvalidCursor курсор повреждён или выдан для другой сортировки
+var ErrInvalidCursor = errors.New("smokers: invalid cursor")
+
+// Filter условия отбора. Пустые поля не ограничивают список
+type Filter struct {
+	// QuitFrom и QuitTo границы даты отказа от курения включительно
+	QuitFrom time.Time
+	QuitTo   time.Time
+	Role     string
+	// Search ищет без учёта регистра в имени и username
+	Search string
+}
+
+// Query запрос страницы списка
+type Query struct {
+	Filter
+	Sort   string
+	Cursor string
+	Limit  int
+}
+
+// Page страница списка
+type Page struct {
+	Smokers []*models.Smoker
+	// Total сколько всего курильщиков подходит под фильтр
+	Total int
+	// NextCursor курсор следующей страницы, пустой на последней
+	NextCursor string
+}
+
+// ValidSort сообщает, что по такому полю можно сортировать
+func ValidSort(sort string) bool {
+	field := strings.TrimPrefix(sort, "-")
+	return field == SortSmokeFree || field == SortCreatedAt
+}
+
+// List отбирает курильщиков из all по фильтру и возвращает страницу после курсора.
+// roles возвращает роли курильщика по username
+func List(all []*models.Smoker, roles func(username string) []string, q Query) (Page, error) {
+	if q.Sort == "" {
+		q.Sort = DefaultSort
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+	o := newOrder(q.Sort)
+
+	var matched []*models.Smoker
+	for _, smoker := range all {
+		if q.Filter.match(smoker, roles) {
+			matched = append(matched, smoker)
+		}
+	}
+	slices.SortFunc(matched, o.compare)
+
+	page := Page{Total: len(matched)}
+	start := 0
+	if q.Cursor != "" {
+		after, err := o.decode(q.Cursor)
+		if err != nil {
+			return Page{}, err
+		}
+		start, _ = slices.BinarySearchFunc(matched, after, o.compare)
+		if start < len(matched) && o.compare(matched[start], after) == 0 {
+			start++
+		}
+	}
+
+	end := min(start+q.Limit, len(matched))
+	page.Smokers = matched[start:end]
+	if end < len(matched) {
+		page.NextCursor = o.encode(matched[end-1])
+	}
+	return page, nil
+}
+
+func (f Filter) match(smoker *models.Smoker, roles func(string) []string) bool {
+	if !f.QuitFrom.IsZero() && smoker.StoppedSmoking.Before(f.QuitFrom) {
+		return false
+	}
+	if !f.QuitTo.IsZero() && smoker.StoppedSmoking.After(f.QuitTo) {
+		return false
+	}
+	if f.Role != "" && !slices.Contains(roles(smoker.Username), f.Role) {
+		return false
+	}
+	if f.Search != "" {
+		search := strings.ToLower(f.Search)
+		if !strings.Contains(strings.ToLower(smoker.Name), search) &&
+			!strings.Contains(strings.ToLower(smoker.Username), search) {
+			return false
+		}
+	}
+	return true
+}
+
+// order порядок списка. При равных ключах курильщики идут по id, чтобы порядок был полным
+type order struct {
+	sort  string
+	field string
+	desc  bool
+}
+
+func newOrder(sort string) order {
+	return order{sort: sort, field: strings.TrimPrefix(sort, "-"), desc: strings.HasPrefix(sort, "-")}
+}
+
+func (o order) key(smoker *models.Smoker) time.Time {
+	if o.field == SortSmokeFree {
+		return smoker.StoppedSmoking
+	}
+	return smoker.CreatedAt
+}
+
+func (o order) compare(a, b *models.Smoker) int {
+	c := o.key(a).Compare(o.key(b))
+	// Чем раньше бросил, тем дольше не курит
+	if o.field == SortSmokeFree {
+		c = -c
+	}
+	if o.desc {
+		c = -c
+	}
+	if c == 0 {
+		c = strings.Compare(a.ID, b.ID)
+	}
+	return c
+}
+
+// cursor последний курильщик страницы: его ключ сортировки и id
+type cursor struct {
+	Sort string    `json:"s"`
+	Key  time.Time `json:"k"`
+	ID   string    `json:"i"`
+}
+
+func (o order) encode(last *models.Smoker) string {
+	data, _ := json.Marshal(cursor{Sort: o.sort, Key: o.key(last), ID: last.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decode превращает курсор в курильщика, с которым можно сравнивать остальных
+func (o order) decode(raw string) (*models.Smoker, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Sort != o.sort || c.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	after := &models.Smoker{ID: c.ID}
+	if o.field == SortSmokeFree {
+		after.StoppedSmoking = c.Key
+	} else {
+		after.CreatedAt = c.Key
+	}
+	return after, nil
+}
@@ -0,0 +1,140 @@
+package smokers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func day(d int) time.Time {
+	return time.Date(2025, time.January, d, 0, 0, 0, 0, time.UTC)
+}
+
+func roles(username string) []string {
+	if username == "boss" {
+		return []string{"admin"}
+	}
+	return []string{"user"}
+}
+
+func ids(smokers []*models.Smoker) []string {
+	var result []string
+	for _, smoker := range smokers {
+		result = append(result, smoker.ID)
+	}
+	return result
+}
+
+var all = []*models.Smoker{
+	{ID: "1", Name: "Arthur", Username: "arthur", StoppedSmoking: day(5), CreatedAt: day(1)},
+	{ID: "2", Name: "Victor", Username: "boss", StoppedSmoking: day(1), CreatedAt: day(3)},
+	{ID: "3", Name: "Olga", Username: "olga", StoppedSmoking: day(20), CreatedAt: day(2)},
+	{ID: "4", Name: "Ivan", Username: "ivan", StoppedSmoking: day(5), CreatedAt: day(3)},
+}
+
+func TestListSort(t *testing.T) {
+	tests := map[string][]string{
+		"":           {"2", "4", "3", "1"},
+		"createdAt":  {"1", "3", "2", "4"},
+		"-createdAt": {"2", "4", "3", "1"},
+		// Дольше всех не курит тот, кто бросил раньше; при равенстве — по id
+		"-smokeFree": {"2", "1", "4", "3"},
+		"smokeFree":  {"3", "1", "4", "2"},
+	}
+	for sort, want := range tests {
+		page, err := List(all, roles, Query{Sort: sort})
+		assert.NoError(t, err)
+		assert.Equal(t, want, ids(page.Smokers), sort)
+	}
+}
+
+func TestListFilter(t *testing.T) {
+	page, err := List(all, roles, Query{Filter: Filter{QuitFrom: day(2), QuitTo: day(5)}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"4", "1"}, ids(page.Smokers))
+	assert.Equal(t, 2, page.Total)
+
+	page, _ = List(all, roles, Query{Filter: Filter{Role: "admin"}})
+	assert.Equal(t, []string{"2"}, ids(page.Smokers))
+
+	// Поиск и по имени, и по username без учёта регистра
+	page, _ = List(all, roles, Query{Filter: Filter{Search: "BOS"}})
+	assert.Equal(t, []string{"2"}, ids(page.Smokers))
+	page, _ = List(all, roles, Query{Filter: Filter{Search: "ol"}})
+	assert.Equal(t, []string{"3"}, ids(page.Smokers))
+}
+
+func TestListPages(t *testing.T) {
+	var got []string
+	q := Query{Sort: "-smokeFree", Limit: 3}
+	for i := 0; i < 10; i++ {
+		page, err := List(all, roles, q)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, page.Total)
+		got = append(got, ids(page.Smokers)...)
+		if page.NextCursor == "" {
+			break
+		}
+		q.Cursor = page.NextCursor
+	}
+	assert.Equal(t, []string{"2", "1", "4", "3"}, got)
+}
+
+func TestListCursorSurvivesChanges(t *testing.T) {
+	page, err := List(all, roles, Query{Sort: "createdAt", Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "3"}, ids(page.Smokers))
+
+	// Курильщик со второй страницы удалён, а в начало списка добавлен новый:
+	// следующая страница продолжается с того же места
+	changed := []*models.Smoker{
+		all[0], all[2], all[3],
+		{ID: "0", Username: "new", CreatedAt: day(1).Add(-time.Hour)},
+	}
+	page, err = List(changed, roles, Query{Sort: "createdAt", Limit: 2, Cursor: page.NextCursor})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"4"}, ids(page.Smokers))
+	assert.Empty(t, page.NextCursor)
+}
+
+func TestListInvalidCursor(t *testing.T) {
+	page, _ := List(all, roles, Query{Sort: "createdAt", Limit: 1})
+
+	_, err := List(all, roles, Query{Sort: "-smokeFree", Cursor: page.NextCursor})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	_, err = List(all, roles, Query{Cursor: "not a cursor"})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestListManySmokers(t *testing.T) {
+	var many []*models.Smoker
+	for i := 0; i < 250; i++ {
+		many = append(many, &models.Smoker{ID: fmt.Sprintf("%03d", i), CreatedAt: day(1 + i%7)})
+	}
+
+	seen := map[string]bool{}
+	q := Query{Limit: MaxLimit}
+	for {
+		page, err := List(many, roles, q)
+		assert.NoError(t, err)
+		for _, smoker := range page.Smokers {
+			assert.False(t, seen[smoker.ID], smoker.ID)
+			seen[smoker.ID] = true
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		q.Cursor = page.NextCursor
+	}
+	assert.Len(t, seen, 250)
+}
+
+func TestValidSort(t *testing.T) {
+	assert.True(t, ValidSort("-smokeFree"))
+	assert.True(t, ValidSort("createdAt"))
+	assert.False(t, ValidSort("name"))
+}
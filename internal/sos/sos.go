@@ -0,0 +1,106 @@
+// Package sos сессия помощи, когда хочется закурить прямо сейчас. Приступ тяги обычно
+// проходит за 3–5 минут, и сессия помогает их пережить: дыхательное упражнение,
+// задание, чтобы отвлечься, и собственные причины курильщика бросить курить
+package sos
+
+import (
+	"math/rand/v2"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+// Квадратное дыхание: вдох, задержка, выдох, задержка по breathPhase секунд
+const (
+	breathPhase  = 4
+	breathCycles = 4
+
+	distractionDuration = 60
+	reasonDuration      = 30
+)
+
+var breathing = []string{
+	"Вдох через нос",
+	"Задержите дыхание",
+	"Медленный выдох через рот",
+	"Пауза",
+}
+
+var distractions = []string{
+	"Выпейте стакан холодной воды маленькими глотками",
+	"Назовите пять предметов вокруг себя синего цвета",
+	"Сделайте 20 приседаний или пройдитесь по лестнице",
+	"Напишите напарнику или другу пару строк о том, как прошёл день",
+	"Почистите зубы или съешьте что-нибудь хрустящее: морковь, яблоко",
+	"Посчитайте от 100 до 0 через семь",
+	"Умойтесь прохладной водой",
+	"Вспомните, на что потратите сэкономленные деньги",
+}
+
+// Когда курильщик ещё не записал свои причины
+var defaultReasons = []string{
+	"Через 20 минут без сигареты пульс и давление возвращаются к норме",
+	"Каждая пережитая тяга делает следующую слабее",
+	"Тяга пройдёт через пару минут, а решение останется с вами",
+}
+
+// Plan составляет шаги сессии: два круга из дыхания, отвлечения и причины
+func Plan(reasons []string, rnd *rand.Rand) []models.SOSStep {
+	if len(reasons) == 0 {
+		reasons = defaultReasons
+	}
+
+	var (
+		steps  []models.SOSStep
+		offset int
+	)
+	add := func(kind, text string, duration int) {
+		steps = append(steps, models.SOSStep{Kind: kind, Text: text, Offset: offset, Duration: duration})
+		offset += duration
+	}
+
+	distraction := rnd.Perm(len(distractions))
+	reason := rnd.Perm(len(reasons))
+	for round := range 2 {
+		for range breathCycles {
+			for _, phase := range breathing {
+				add(models.SOSStepBreathing, phase, breathPhase)
+			}
+		}
+		add(models.SOSStepDistraction, distractions[distraction[round%len(distraction)]], distractionDuration)
+		add(models.SOSStepReason, reasons[reason[round%len(reason)]], reasonDuration)
+	}
+	return steps
+}
+
+// Duration длительность сессии по её шагам
+func Duration(steps []models.SOSStep) time.Duration {
+	if len(steps) == 0 {
+		return 0
+	}
+	last := steps[len(steps)-1]
+	return time.Duration(last.Offset+last.Duration) * time.Second
+}
+
+// State возвращает текущий шаг сессии и сколько секунд он ещё продлится
+func State(session *models.SOSSession, now time.Time) models.SOSState {
+	state := models.SOSState{SOSSession: session}
+
+	elapsed := int(now.Sub(session.StartedAt).Seconds())
+	if session.Outcome != "" || !now.Before(session.EndsAt) {
+		state.Done = true
+		state.Step = len(session.Steps)
+		return state
+	}
+
+	for i := range session.Steps {
+		step := &session.Steps[i]
+		if elapsed < step.Offset+step.Duration {
+			state.Current = step
+			state.Step = i
+			state.Remaining = step.Offset + step.Duration - max(elapsed, 0)
+			break
+		}
+	}
+	return state
+}
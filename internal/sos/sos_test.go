@@ -0,0 +1,51 @@
+package sos
+
+import (
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlan(t *testing.T) {
+	steps := Plan([]string{"Ради дочки"}, rand.New(rand.NewPCG(1, 2)))
+
+	// Два круга: 16 фаз дыхания, отвлечение и причина
+	assert.Len(t, steps, 36)
+	assert.Equal(t, 5*time.Minute+8*time.Second, Duration(steps))
+	assert.Equal(t, models.SOSStepReason, steps[17].Kind)
+	assert.Equal(t, "Ради дочки", steps[17].Text)
+
+	// Шаги идут друг за другом без пропусков
+	for i := 1; i < len(steps); i++ {
+		assert.Equal(t, steps[i-1].Offset+steps[i-1].Duration, steps[i].Offset)
+	}
+
+	// Без своих причин подставляются общие
+	steps = Plan(nil, rand.New(rand.NewPCG(1, 2)))
+	assert.Contains(t, defaultReasons, steps[17].Text)
+}
+
+func TestState(t *testing.T) {
+	start := time.Date(2025, time.March, 1, 12, 0, 0, 0, time.UTC)
+	steps := Plan(nil, rand.New(rand.NewPCG(1, 2)))
+	session := &models.SOSSession{StartedAt: start, EndsAt: start.Add(Duration(steps)), Steps: steps}
+
+	state := State(session, start.Add(5*time.Second))
+	assert.False(t, state.Done)
+	assert.Equal(t, 1, state.Step)
+	assert.Equal(t, "Задержите дыхание", state.Current.Text)
+	assert.Equal(t, 3, state.Remaining)
+
+	state = State(session, start.Add(70*time.Second))
+	assert.Equal(t, models.SOSStepDistraction, state.Current.Kind)
+	assert.Equal(t, 54, state.Remaining)
+
+	assert.True(t, State(session, session.EndsAt).Done)
+
+	// Завершённая досрочно сессия
+	session.Outcome = models.SOSOutcomeResisted
+	assert.True(t, State(session, start.Add(5*time.Second)).Done)
+}
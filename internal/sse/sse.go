@@ -0,0 +1,128 @@
+// Package sse отправка событий Server-Sent Events и ограничение числа подключений
+package sse
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrTooManyConnections = errors.New("too many connections")
+	ErrTooManyForUser     = errors.New("too many connections for user")
+)
+
+// Limiter ограничивает число одновременных подключений: всего и на одного пользователя
+type Limiter struct {
+	mu      sync.Mutex
+	max     int
+	perUser int
+	total   int
+	byUser  map[string]int
+}
+
+func NewLimiter(max, perUser int) *Limiter {
+	return &Limiter{
+		max:     max,
+		perUser: perUser,
+		byUser:  make(map[string]int),
+	}
+}
+
+// Acquire занимает подключение. После окончания потока его нужно вернуть через Release
+func (l *Limiter) Acquire(username string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.total >= l.max {
+		return ErrTooManyConnections
+	}
+	if l.byUser[username] >= l.perUser {
+		return ErrTooManyForUser
+	}
+	l.total++
+	l.byUser[username]++
+	return nil
+}
+
+// Release освобождает подключение, занятое Acquire
+func (l *Limiter) Release(username string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.total--
+	l.byUser[username]--
+	if l.byUser[username] <= 0 {
+		delete(l.byUser, username)
+	}
+}
+
+// Stream поток событий в ответе на запрос
+type Stream struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+// NewStream отправляет заголовки потока и снимает WriteTimeout сервера,
+// иначе сервер оборвёт долгое подключение. retry подсказывает браузеру,
+// через сколько переподключаться после обрыва
+func NewStream(w http.ResponseWriter, retry time.Duration) (*Stream, error) {
+	op := "sse.NewStream"
+
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	s := &Stream{w: w, rc: rc}
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", retry.Milliseconds()); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := s.rc.Flush(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return s, nil
+}
+
+// Event отправляет событие name с данными в JSON
+func (s *Stream) Event(name string, data any) error {
+	op := "sse.Stream.Event"
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if strings.ContainsAny(name, "\r\n") {
+		return fmt.Errorf("%s: invalid event name", op)
+	}
+
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", name, payload); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := s.rc.Flush(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Heartbeat отправляет комментарий, чтобы прокси и браузер не закрыли простаивающее подключение
+func (s *Stream) Heartbeat() error {
+	op := "sse.Stream.Heartbeat"
+
+	if _, err := fmt.Fprint(s.w, ": ping\n\n"); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := s.rc.Flush(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
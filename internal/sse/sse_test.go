@@ -0,0 +1,39 @@
+package sse
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter(t *testing.T) {
+	limiter := NewLimiter(3, 2)
+
+	assert.NoError(t, limiter.Acquire("arthur"))
+	assert.NoError(t, limiter.Acquire("arthur"))
+	assert.ErrorIs(t, limiter.Acquire("arthur"), ErrTooManyForUser)
+
+	assert.NoError(t, limiter.Acquire("victor"))
+	assert.ErrorIs(t, limiter.Acquire("olga"), ErrTooManyConnections)
+
+	// Освободившееся место снова доступно
+	limiter.Release("arthur")
+	assert.NoError(t, limiter.Acquire("olga"))
+	assert.ErrorIs(t, limiter.Acquire("arthur"), ErrTooManyConnections)
+}
+
+func TestStream(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	stream, err := NewStream(w, 5*time.Second)
+	assert.NoError(t, err)
+	assert.NoError(t, stream.Event("stats", map[string]int{"days": 3}))
+	assert.NoError(t, stream.Heartbeat())
+	assert.Error(t, stream.Event("stats\ndata: x", nil))
+
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	assert.Equal(t, "retry: 5000\n\nevent: stats\ndata: {\"days\":3}\n\n: ping\n\n", w.Body.String())
+	assert.True(t, w.Flushed)
+}
@@ -0,0 +1,62 @@
+package storage
+
+import "github.com/NarthurN/QuitSmoking/internal/models"
+
+// LockAccount блокирует учётную запись. Повторная блокировка заменяет прежнюю
+func (s *Storage) LockAccount(lock *models.AccountLock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.accountLocks[lock.Username] = lock
+}
+
+// UnlockAccount снимает блокировку и сообщает, была ли она
+func (s *Storage) UnlockAccount(username string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.accountLocks[username]; !ok {
+		return false
+	}
+	delete(s.accountLocks, username)
+	return true
+}
+
+// GetAccountLock возвращает блокировку учётной записи, если она есть
+func (s *Storage) GetAccountLock(username string) (*models.AccountLock, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	lock, ok := s.accountLocks[username]
+	return lock, ok
+}
+
+// IsLocked сообщает, заблокирована ли учётная запись
+func (s *Storage) IsLocked(username string) bool {
+	_, ok := s.GetAccountLock(username)
+	return ok
+}
+
+// CountLocked возвращает количество заблокированных учётных записей
+func (s *Storage) CountLocked() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.accountLocks)
+}
+
+// TokenGeneration возвращает текущее поколение токенов курильщика
+func (s *Storage) TokenGeneration(username string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.tokenGens[username]
+}
+
+// RevokeTokens отзывает все выданные курильщику токены, например после сброса пароля
+func (s *Storage) RevokeTokens(username string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokenGens[username]++
+}
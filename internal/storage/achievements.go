@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"sort"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+// AwardAchievement выдаёт достижение курильщику. Повторная выдача того же достижения
+// ничего не меняет и возвращает false
+func (s *Storage) AwardAchievement(username string, achievement *models.Achievement) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	awarded, ok := s.achievements[username]
+	if !ok {
+		awarded = make(map[string]*models.Achievement)
+		s.achievements[username] = awarded
+	}
+
+	if _, ok := awarded[achievement.RuleID]; ok {
+		return false
+	}
+	awarded[achievement.RuleID] = achievement
+	return true
+}
+
+// GetAchievements возвращает достижения курильщика в порядке их получения
+func (s *Storage) GetAchievements(username string) []*models.Achievement {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	achievements := make([]*models.Achievement, 0, len(s.achievements[username]))
+	for _, a := range s.achievements[username] {
+		achievements = append(achievements, a)
+	}
+	sort.Slice(achievements, func(i, j int) bool {
+		if achievements[i].AwardedAt.Equal(achievements[j].AwardedAt) {
+			return achievements[i].RuleID < achievements[j].RuleID
+		}
+		return achievements[i].AwardedAt.Before(achievements[j].AwardedAt)
+	})
+	return achievements
+}
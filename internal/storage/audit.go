@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"slices"
+	"strconv"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+// В памяти хранится не больше MaxAuditEvents событий. При переполнении самые старые
+// auditTrim событий уходят в архив, если он задан, и удаляются из памяти
+const (
+	MaxAuditEvents = 50_000
+	auditTrim      = MaxAuditEvents / 10
+)
+
+// SetAuditArchive задаёт, куда уходят старые события при переполнении журнала, например
+// в файл, который только дописывается. archive вызывается под блокировкой и не должен обращаться к хранилищу
+func (s *Storage) SetAuditArchive(archive func(events []models.AuditEvent)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.auditArchive = archive
+}
+
+// AddAuditEvent дописывает событие в журнал аудита. Записи журнала не меняются,
+// поэтому в журнал попадает копия события
+func (s *Storage) AddAuditEvent(event *models.AuditEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.auditSeq++
+	event.ID = strconv.Itoa(s.auditSeq)
+	s.audit = append(s.audit, *event)
+
+	if len(s.audit) > MaxAuditEvents {
+		if s.auditArchive != nil {
+			s.auditArchive(slices.Clone(s.audit[:auditTrim]))
+		}
+		s.audit = slices.Clone(s.audit[auditTrim:])
+	}
+}
+
+// ListAuditEvents возвращает события, для которых match вернул true, от новых к старым.
+// limit ограничивает количество, 0 — без ограничения
+func (s *Storage) ListAuditEvents(match func(models.AuditEvent) bool, limit int) []models.AuditEvent {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var events []models.AuditEvent
+	for i := len(s.audit) - 1; i >= 0; i-- {
+		if limit > 0 && len(events) == limit {
+			break
+		}
+		if match(s.audit[i]) {
+			events = append(events, s.audit[i])
+		}
+	}
+	return events
+}
+
+// GetAuditEvents возвращает события, где курильщик был исполнителем или целью, от новых к старым
+func (s *Storage) GetAuditEvents(username string) []models.AuditEvent {
+	return s.ListAuditEvents(func(event models.AuditEvent) bool {
+		return event.Actor == username || event.Target == username || event.Impersonator == username
+	}, 0)
+}
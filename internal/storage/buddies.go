@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"maps"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+// AddBuddyInvite сохраняет приглашение в напарники
+func (s *Storage) AddBuddyInvite(invite *models.BuddyInvite) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.buddyInvites[invite.Token] = invite
+}
+
+// GetBuddyInvite возвращает копию приглашения по токену: RespondBuddyInvite меняет
+// статус хранимого приглашения под блокировкой, а копию можно читать без неё
+func (s *Storage) GetBuddyInvite(token string) (*models.BuddyInvite, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	invite, ok := s.buddyInvites[token]
+	if !ok {
+		return nil, false
+	}
+	cp := *invite
+	return &cp, true
+}
+
+// GetPendingInvites возвращает ожидающие ответа приглашения, отправленные курильщику или им самим
+func (s *Storage) GetPendingInvites(username string) []*models.BuddyInvite {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var invites []*models.BuddyInvite
+	for _, invite := range s.buddyInvites {
+		if invite.Status == models.InviteStatusPending && (invite.To == username || invite.From == username) {
+			cp := *invite
+			invites = append(invites, &cp)
+		}
+	}
+	sort.Slice(invites, func(i, j int) bool {
+		return invites[i].CreatedAt.Before(invites[j].CreatedAt)
+	})
+	return invites
+}
+
+// RespondBuddyInvite принимает или отклоняет приглашение. При принятии создаётся связь напарников
+// с настройками приватности по умолчанию: дневник тяги скрыт
+func (s *Storage) RespondBuddyInvite(token string, accept bool, at time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	invite, ok := s.buddyInvites[token]
+	if !ok || invite.Status != models.InviteStatusPending {
+		return false
+	}
+
+	if !accept {
+		invite.Status = models.InviteStatusDeclined
+		return true
+	}
+
+	invite.Status = models.InviteStatusAccepted
+	buddyship := &models.Buddyship{
+		Users: [2]string{invite.From, invite.To},
+		Sharing: map[string]models.SharingSettings{
+			invite.From: {Streak: true, Milestones: true},
+			invite.To:   {Streak: true, Milestones: true},
+		},
+		Since: at,
+	}
+	s.buddyships[buddyKey(invite.From, invite.To)] = buddyship
+	return true
+}
+
+// GetBuddyship возвращает копию связи двух напарников. Настройки приватности
+// копируются: SetSharing меняет их под блокировкой, а обработчики читают без неё
+func (s *Storage) GetBuddyship(a, b string) (*models.Buddyship, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	buddyship, ok := s.buddyships[buddyKey(a, b)]
+	if !ok {
+		return nil, false
+	}
+	cp := *buddyship
+	cp.Sharing = maps.Clone(buddyship.Sharing)
+	return &cp, true
+}
+
+// GetBuddies возвращает имена напарников курильщика
+func (s *Storage) GetBuddies(username string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var buddies []string
+	for _, buddyship := range s.buddyships {
+		switch username {
+		case buddyship.Users[0]:
+			buddies = append(buddies, buddyship.Users[1])
+		case buddyship.Users[1]:
+			buddies = append(buddies, buddyship.Users[0])
+		}
+	}
+	sort.Strings(buddies)
+	return buddies
+}
+
+// SetSharing меняет, что курильщик показывает напарнику
+func (s *Storage) SetSharing(username, buddy string, sharing models.SharingSettings) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	buddyship, ok := s.buddyships[buddyKey(username, buddy)]
+	if !ok {
+		return false
+	}
+	buddyship.Sharing[username] = sharing
+	return true
+}
+
+// DeleteBuddyship разрывает связь напарников
+func (s *Storage) DeleteBuddyship(a, b string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := buddyKey(a, b)
+	if _, ok := s.buddyships[key]; !ok {
+		return false
+	}
+	delete(s.buddyships, key)
+	return true
+}
+
+// AddBuddyMessage сохраняет сообщение поддержки
+func (s *Storage) AddBuddyMessage(message *models.BuddyMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.buddyMessageSeq++
+	message.ID = strconv.Itoa(s.buddyMessageSeq)
+	key := buddyKey(message.From, message.To)
+	s.buddyMessages[key] = append(s.buddyMessages[key], message)
+}
+
+// GetBuddyMessages возвращает переписку двух напарников в порядке отправки
+func (s *Storage) GetBuddyMessages(a, b string) []*models.BuddyMessage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key := buddyKey(a, b)
+	messages := make([]*models.BuddyMessage, len(s.buddyMessages[key]))
+	copy(messages, s.buddyMessages[key])
+	return messages
+}
+
+// buddyKey возвращает ключ пары, не зависящий от порядка имён
+func buddyKey(a, b string) string {
+	if a > b {
+		a, b = b, a
+	}
+	return a + "\x00" + b
+}
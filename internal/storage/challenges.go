@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+// AddChallenge сохраняет новый челлендж
+func (s *Storage) AddChallenge(challenge *models.Challenge) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.challengeSeq++
+	challenge.ID = strconv.Itoa(s.challengeSeq)
+	s.challenges[challenge.ID] = challenge
+}
+
+// GetChallenge возвращает челлендж по id
+func (s *Storage) GetChallenge(id string) (*models.Challenge, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	challenge, ok := s.challenges[id]
+	return challenge, ok
+}
+
+// GetChallenges возвращает все челленджи в порядке даты начала
+func (s *Storage) GetChallenges() []*models.Challenge {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	challenges := make([]*models.Challenge, 0, len(s.challenges))
+	for _, challenge := range s.challenges {
+		challenges = append(challenges, challenge)
+	}
+	sort.Slice(challenges, func(i, j int) bool {
+		return challenges[i].StartDate.Before(challenges[j].StartDate)
+	})
+	return challenges
+}
+
+// JoinChallenge добавляет участника в челлендж и присваивает ему порядковый номер вступления.
+// Повторное вступление ничего не меняет и возвращает false
+func (s *Storage) JoinChallenge(id string, member *models.ChallengeMember) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, m := range s.challengeMembers[id] {
+		if m.Username == member.Username {
+			return false
+		}
+	}
+	s.challengeJoins[id]++
+	member.Number = s.challengeJoins[id]
+	s.challengeMembers[id] = append(s.challengeMembers[id], member)
+	return true
+}
+
+// LeaveChallenge удаляет участника из челленджа и сообщает, был ли он участником
+func (s *Storage) LeaveChallenge(id, username string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	members := s.challengeMembers[id]
+	for i, m := range members {
+		if m.Username == username {
+			s.challengeMembers[id] = append(members[:i], members[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// GetChallengeMembers возвращает участников челленджа в порядке вступления
+func (s *Storage) GetChallengeMembers(id string) []*models.ChallengeMember {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	members := make([]*models.ChallengeMember, len(s.challengeMembers[id]))
+	copy(members, s.challengeMembers[id])
+	return members
+}
+
+// SetChallengeResults фиксирует итоговую таблицу завершённого челленджа.
+// Уже зафиксированные итоги не перезаписываются
+func (s *Storage) SetChallengeResults(id, rankBy string, results []models.LeaderboardEntry) []models.LeaderboardEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byMetric, ok := s.challengeResults[id]
+	if !ok {
+		byMetric = make(map[string][]models.LeaderboardEntry)
+		s.challengeResults[id] = byMetric
+	}
+	if existing, ok := byMetric[rankBy]; ok {
+		return existing
+	}
+	byMetric[rankBy] = results
+	return results
+}
+
+// GetChallengeResults возвращает итоговую таблицу челленджа, если она уже зафиксирована
+func (s *Storage) GetChallengeResults(id, rankBy string) ([]models.LeaderboardEntry, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results, ok := s.challengeResults[id][rankBy]
+	return results, ok
+}
@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"strconv"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+// AddChatMessage сохраняет сообщение чата
+func (s *Storage) AddChatMessage(message *models.ChatMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.chatMessageSeq++
+	message.ID = strconv.Itoa(s.chatMessageSeq)
+	s.chatMessages[message.Room] = append(s.chatMessages[message.Room], message)
+}
+
+// GetChatHistory возвращает до limit сообщений комнаты, отправленных раньше сообщения before
+// (или последние, если before пустой), в порядке отправки, и есть ли сообщения ещё раньше
+func (s *Storage) GetChatHistory(room, before string, limit int) ([]models.ChatMessage, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	messages := s.chatMessages[room]
+	end := len(messages)
+	if before != "" {
+		end = 0
+		for i, message := range messages {
+			if message.ID == before {
+				end = i
+				break
+			}
+		}
+	}
+	start := max(end-limit, 0)
+
+	page := make([]models.ChatMessage, 0, end-start)
+	for _, message := range messages[start:end] {
+		page = append(page, *message)
+	}
+	return page, start > 0
+}
@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"sort"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+// SaveCheckIn сохраняет отметку самочувствия. Повторная отметка за тот же день заменяет предыдущую
+func (s *Storage) SaveCheckIn(checkIn *models.CheckIn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	checkIn.Date = checkIn.Date.UTC().Truncate(24 * time.Hour)
+	days, ok := s.checkIns[checkIn.Username]
+	if !ok {
+		days = make(map[time.Time]*models.CheckIn)
+		s.checkIns[checkIn.Username] = days
+	}
+	days[checkIn.Date] = checkIn
+}
+
+// GetCheckIns возвращает отметки самочувствия курильщика в порядке убывания даты
+func (s *Storage) GetCheckIns(username string) []*models.CheckIn {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	checkIns := make([]*models.CheckIn, 0, len(s.checkIns[username]))
+	for _, checkIn := range s.checkIns[username] {
+		checkIns = append(checkIns, checkIn)
+	}
+	sort.Slice(checkIns, func(i, j int) bool {
+		return checkIns[i].Date.After(checkIns[j].Date)
+	})
+	return checkIns
+}
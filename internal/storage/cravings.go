@@ -0,0 +1,26 @@
+package storage
+
+import (
+	"strconv"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+// AddCraving записывает эпизод тяги в дневник курильщика
+func (s *Storage) AddCraving(craving *models.Craving) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	craving.ID = strconv.Itoa(len(s.cravings[craving.Username]) + 1)
+	s.cravings[craving.Username] = append(s.cravings[craving.Username], craving)
+}
+
+// GetCravings возвращает дневник тяги курильщика
+func (s *Storage) GetCravings(username string) []*models.Craving {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	cravings := make([]*models.Craving, len(s.cravings[username]))
+	copy(cravings, s.cravings[username])
+	return cravings
+}
@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+// AddPost сохраняет пост или ответ в ленте сообщества
+func (s *Storage) AddPost(post *models.Post) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.postSeq++
+	post.ID = strconv.Itoa(s.postSeq)
+	post.Reactions = make(map[string][]string)
+	s.posts[post.ID] = post
+}
+
+// GetPost возвращает копию поста по id
+func (s *Storage) GetPost(id string) (*models.Post, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	post, ok := s.posts[id]
+	if !ok {
+		return nil, false
+	}
+	return copyPost(post), true
+}
+
+// GetPosts возвращает копии всех постов и ответов в порядке публикации
+func (s *Storage) GetPosts() []*models.Post {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	posts := make([]*models.Post, 0, len(s.posts))
+	for _, post := range s.posts {
+		posts = append(posts, copyPost(post))
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		return posts[i].CreatedAt.Before(posts[j].CreatedAt)
+	})
+	return posts
+}
+
+// ToggleReaction ставит или снимает реакцию пользователя на пост
+func (s *Storage) ToggleReaction(id, kind, username string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	post, ok := s.posts[id]
+	if !ok {
+		return false
+	}
+
+	users := post.Reactions[kind]
+	if i := slices.Index(users, username); i >= 0 {
+		post.Reactions[kind] = slices.Delete(users, i, i+1)
+	} else {
+		post.Reactions[kind] = append(users, username)
+	}
+	return true
+}
+
+// SetPostHidden скрывает пост или возвращает его в ленту
+func (s *Storage) SetPostHidden(id string, hidden bool, by string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	post, ok := s.posts[id]
+	if !ok {
+		return false
+	}
+	post.Hidden = hidden
+	post.HiddenBy = ""
+	if hidden {
+		post.HiddenBy = by
+	}
+	return true
+}
+
+// AddReport сохраняет жалобу на пост. Повторная жалоба того же пользователя не учитывается
+func (s *Storage) AddReport(report *models.PostReport) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, r := range s.reports {
+		if r.PostID == report.PostID && r.Reporter == report.Reporter {
+			return false
+		}
+	}
+	s.reports = append(s.reports, report)
+	return true
+}
+
+// GetReports возвращает все жалобы в порядке поступления
+func (s *Storage) GetReports() []*models.PostReport {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	reports := make([]*models.PostReport, len(s.reports))
+	copy(reports, s.reports)
+	return reports
+}
+
+// BanUser блокирует пользователя в сообществе
+func (s *Storage) BanUser(ban *models.Ban) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.bans[ban.Username] = ban
+}
+
+// UnbanUser снимает блокировку и сообщает, была ли она
+func (s *Storage) UnbanUser(username string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.bans[username]; !ok {
+		return false
+	}
+	delete(s.bans, username)
+	return true
+}
+
+// GetBan возвращает блокировку пользователя, если она есть
+func (s *Storage) GetBan(username string) (*models.Ban, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ban, ok := s.bans[username]
+	return ban, ok
+}
+
+func copyPost(post *models.Post) *models.Post {
+	c := *post
+	c.Reactions = make(map[string][]string, len(post.Reactions))
+	for kind, users := range post.Reactions {
+		c.Reactions[kind] = slices.Clone(users)
+	}
+	return &c
+}
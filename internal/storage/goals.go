@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+// AddGoal сохраняет новую цель курильщика
+func (s *Storage) AddGoal(goal *models.Goal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.goalSeq++
+	goal.ID = strconv.Itoa(s.goalSeq)
+	s.goals[goal.Username] = append(s.goals[goal.Username], goal)
+}
+
+// GetGoals возвращает копии целей курильщика в порядке создания.
+// Менять цель можно только через методы хранилища
+func (s *Storage) GetGoals(username string) []*models.Goal {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	goals := make([]*models.Goal, 0, len(s.goals[username]))
+	for _, goal := range s.goals[username] {
+		found := *goal
+		goals = append(goals, &found)
+	}
+	return goals
+}
+
+// CompleteGoal отмечает цель выполненной. Уже выполненная цель не меняется
+func (s *Storage) CompleteGoal(username, id string, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, goal := range s.goals[username] {
+		if goal.ID == id && goal.CompletedAt == nil {
+			// Копии, выданные раньше, не должны увидеть изменение
+			completed := *goal
+			completed.CompletedAt = &at
+			s.goals[username][i] = &completed
+			return
+		}
+	}
+}
+
+// DeleteGoal удаляет цель курильщика и сообщает, была ли она найдена
+func (s *Storage) DeleteGoal(username, id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	goals := s.goals[username]
+	for i, goal := range goals {
+		if goal.ID == id {
+			s.goals[username] = append(goals[:i], goals[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+// AddInboxItem кладёт запись во входящие курильщика
+func (s *Storage) AddInboxItem(item *models.InboxItem) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.inboxSeq++
+	item.ID = strconv.Itoa(s.inboxSeq)
+	s.inbox[item.Username] = append(s.inbox[item.Username], item)
+}
+
+// GetInbox возвращает страницу входящих курильщика (новые первыми) и общее количество записей
+func (s *Storage) GetInbox(username string, offset, limit int) ([]models.InboxItem, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	items := s.inbox[username]
+	total := len(items)
+
+	page := make([]models.InboxItem, 0, limit)
+	for i := total - 1 - offset; i >= 0 && len(page) < limit; i-- {
+		page = append(page, *items[i])
+	}
+	return page, total
+}
+
+// CountUnread возвращает количество непрочитанных записей во входящих
+func (s *Storage) CountUnread(username string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	unread := 0
+	for _, item := range s.inbox[username] {
+		if item.ReadAt == nil {
+			unread++
+		}
+	}
+	return unread
+}
+
+// MarkInboxItemRead отмечает запись прочитанной и сообщает, была ли она найдена
+func (s *Storage) MarkInboxItemRead(username, id string, at time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, item := range s.inbox[username] {
+		if item.ID == id {
+			if item.ReadAt == nil {
+				item.ReadAt = &at
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// MarkAllInboxRead отмечает прочитанными все записи и возвращает, сколько их было непрочитано
+func (s *Storage) MarkAllInboxRead(username string, at time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	marked := 0
+	for _, item := range s.inbox[username] {
+		if item.ReadAt == nil {
+			item.ReadAt = &at
+			marked++
+		}
+	}
+	return marked
+}
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+// SaveNotificationSettings сохраняет настройки уведомлений курильщика
+func (s *Storage) SaveNotificationSettings(settings *models.NotificationSettings) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.notificationSettings[settings.Username] = settings
+}
+
+// GetNotificationSettings возвращает копию настроек уведомлений курильщика
+func (s *Storage) GetNotificationSettings(username string) (models.NotificationSettings, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	settings, ok := s.notificationSettings[username]
+	if !ok {
+		return models.NotificationSettings{}, false
+	}
+	return *settings, true
+}
+
+// AddNotification сохраняет уведомление и создаёт по доставке на каждый канал.
+// Если уведомление с таким ключом уже было, ничего не делает и возвращает false
+func (s *Storage) AddNotification(notification *models.Notification, channels []string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if notification.Key != "" {
+		if _, ok := s.notificationKeys[notification.Key]; ok {
+			return false
+		}
+		s.notificationKeys[notification.Key] = struct{}{}
+	}
+
+	s.notificationSeq++
+	notification.ID = strconv.Itoa(s.notificationSeq)
+	s.notifications[notification.ID] = notification
+
+	for _, channel := range channels {
+		s.deliverySeq++
+		delivery := &models.Delivery{
+			ID:             strconv.Itoa(s.deliverySeq),
+			NotificationID: notification.ID,
+			Username:       notification.Username,
+			Channel:        channel,
+			Status:         models.DeliveryPending,
+			NextAttempt:    notification.CreatedAt,
+		}
+		s.deliveries[delivery.ID] = delivery
+	}
+	return true
+}
+
+// GetNotification возвращает уведомление по id
+func (s *Storage) GetNotification(id string) (*models.Notification, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	notification, ok := s.notifications[id]
+	return notification, ok
+}
+
+// GetDueDeliveries возвращает копии ожидающих доставок, время попытки которых наступило
+func (s *Storage) GetDueDeliveries(now time.Time) []models.Delivery {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var due []models.Delivery
+	for _, delivery := range s.deliveries {
+		if delivery.Status == models.DeliveryPending && !delivery.NextAttempt.After(now) {
+			due = append(due, *delivery)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].NextAttempt.Before(due[j].NextAttempt)
+	})
+	return due
+}
+
+// UpdateDelivery сохраняет новое состояние доставки
+func (s *Storage) UpdateDelivery(delivery models.Delivery) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.deliveries[delivery.ID]; ok {
+		s.deliveries[delivery.ID] = &delivery
+	}
+}
+
+// GetDeliveries возвращает копии доставок уведомлений курильщика, новые первыми
+func (s *Storage) GetDeliveries(username string) []models.Delivery {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	deliveries := make([]models.Delivery, 0)
+	for _, delivery := range s.deliveries {
+		if delivery.Username == username {
+			deliveries = append(deliveries, *delivery)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		a, _ := strconv.Atoi(deliveries[i].ID)
+		b, _ := strconv.Atoi(deliveries[j].ID)
+		return a > b
+	})
+	return deliveries
+}
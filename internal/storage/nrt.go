@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"strconv"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+// AddNRTProduct сохраняет средство НЗТ курильщика
+func (s *Storage) AddNRTProduct(product *models.NRTProduct) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nrtSeq++
+	product.ID = strconv.Itoa(s.nrtSeq)
+	s.nrtProducts[product.Username] = append(s.nrtProducts[product.Username], product)
+}
+
+// GetNRTProducts возвращает средства НЗТ курильщика
+func (s *Storage) GetNRTProducts(username string) []*models.NRTProduct {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	products := make([]*models.NRTProduct, len(s.nrtProducts[username]))
+	copy(products, s.nrtProducts[username])
+	return products
+}
+
+// GetNRTProduct возвращает средство НЗТ курильщика по id
+func (s *Storage) GetNRTProduct(username, id string) (*models.NRTProduct, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, product := range s.nrtProducts[username] {
+		if product.ID == id {
+			return product, true
+		}
+	}
+	return nil, false
+}
+
+// AddNRTUsage записывает использование средства НЗТ
+func (s *Storage) AddNRTUsage(username string, usage *models.NRTUsage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nrtUsages[username] = append(s.nrtUsages[username], usage)
+}
+
+// GetNRTUsages возвращает все записи об использовании НЗТ курильщиком
+func (s *Storage) GetNRTUsages(username string) []*models.NRTUsage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	usages := make([]*models.NRTUsage, len(s.nrtUsages[username]))
+	copy(usages, s.nrtUsages[username])
+	return usages
+}
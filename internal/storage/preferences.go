@@ -0,0 +1,39 @@
+package storage
+
+import "github.com/NarthurN/QuitSmoking/internal/models"
+
+// SetLanguage сохраняет язык интерфейса, выбранный курильщиком
+func (s *Storage) SetLanguage(username, locale string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.languages[username] = locale
+}
+
+// GetLanguage возвращает выбранный курильщиком язык интерфейса, если он его выбирал
+func (s *Storage) GetLanguage(username string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	locale, ok := s.languages[username]
+	return locale, ok
+}
+
+// SetCurrency сохраняет валюту, в которой курильщик покупает сигареты
+func (s *Storage) SetCurrency(username, currency string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.currencies[username] = currency
+}
+
+// GetCurrency возвращает валюту курильщика. Пока он её не выбрал — models.DefaultCurrency
+func (s *Storage) GetCurrency(username string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if currency, ok := s.currencies[username]; ok {
+		return currency
+	}
+	return models.DefaultCurrency
+}
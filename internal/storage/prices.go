@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"sort"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+// SetPriceChange записывает новую цену пачки с даты change.From.
+// Изменение на ту же дату заменяет прежнее
+func (s *Storage) SetPriceChange(username string, change models.PriceChange) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prices := s.prices[username]
+	for i := range prices {
+		if prices[i].From.Equal(change.From) {
+			prices[i] = change
+			return
+		}
+	}
+
+	prices = append(prices, change)
+	sort.Slice(prices, func(i, j int) bool {
+		return prices[i].From.Before(prices[j].From)
+	})
+	s.prices[username] = prices
+}
+
+// GetPriceHistory возвращает изменения цены пачки курильщика по возрастанию даты
+func (s *Storage) GetPriceHistory(username string) []models.PriceChange {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	prices := make([]models.PriceChange, len(s.prices[username]))
+	copy(prices, s.prices[username])
+	return prices
+}
+
+// DeletePriceChange удаляет изменение цены с даты from и сообщает, было ли оно
+func (s *Storage) DeletePriceChange(username string, from time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prices := s.prices[username]
+	for i := range prices {
+		if prices[i].From.Equal(from) {
+			s.prices[username] = append(prices[:i:i], prices[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
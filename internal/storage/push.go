@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+// SavePushSubscription сохраняет подписку на Web Push. Подписка с тем же endpoint заменяется,
+// ведь браузер мог перевыпустить ключи или подписку мог оформить другой курильщик
+func (s *Storage) SavePushSubscription(subscription *models.PushSubscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pushSubscriptions[subscription.Endpoint] = subscription
+}
+
+// GetPushSubscriptions возвращает все подписки курильщика на Web Push
+func (s *Storage) GetPushSubscriptions(username string) []models.PushSubscription {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var subscriptions []models.PushSubscription
+	for _, subscription := range s.pushSubscriptions {
+		if subscription.Username == username {
+			subscriptions = append(subscriptions, *subscription)
+		}
+	}
+	return subscriptions
+}
+
+// DeletePushSubscription удаляет подписку курильщика и сообщает, была ли она найдена
+func (s *Storage) DeletePushSubscription(username, endpoint string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subscription, ok := s.pushSubscriptions[endpoint]
+	if !ok || subscription.Username != username {
+		return false
+	}
+	delete(s.pushSubscriptions, endpoint)
+	return true
+}
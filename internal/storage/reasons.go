@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"slices"
+	"strconv"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+// AddReason сохраняет мотивирующую запись
+func (s *Storage) AddReason(reason *models.Reason) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reasonSeq++
+	reason.ID = strconv.Itoa(s.reasonSeq)
+	s.reasons[reason.Username] = append(s.reasons[reason.Username], reason)
+}
+
+// GetReasons возвращает мотивирующие записи курильщика в порядке добавления
+func (s *Storage) GetReasons(username string) []*models.Reason {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	reasons := make([]*models.Reason, len(s.reasons[username]))
+	copy(reasons, s.reasons[username])
+	return reasons
+}
+
+// GetReason возвращает запись курильщика по id
+func (s *Storage) GetReason(username, id string) (*models.Reason, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := slices.IndexFunc(s.reasons[username], func(r *models.Reason) bool { return r.ID == id })
+	if i < 0 {
+		return nil, false
+	}
+	return s.reasons[username][i], true
+}
+
+// DeleteReason удаляет запись курильщика и возвращает её, чтобы можно было удалить и файл
+func (s *Storage) DeleteReason(username, id string) (*models.Reason, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.reasons[username], func(r *models.Reason) bool { return r.ID == id })
+	if i < 0 {
+		return nil, false
+	}
+	reason := s.reasons[username][i]
+	s.reasons[username] = slices.Delete(s.reasons[username], i, i+1)
+	return reason, true
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+// SetReductionPlan сохраняет план сокращения курильщика, заменяя предыдущий
+func (s *Storage) SetReductionPlan(plan *models.ReductionPlan) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reductionPlans[plan.Username] = plan
+}
+
+// GetReductionPlan возвращает план сокращения курильщика или nil
+func (s *Storage) GetReductionPlan(username string) *models.ReductionPlan {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.reductionPlans[username]
+}
+
+// AddCigarettes записывает выкуренные за день сигареты и возвращает итог за этот день
+func (s *Storage) AddCigarettes(username string, date time.Time, count int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	date = date.UTC().Truncate(24 * time.Hour)
+	smoked, ok := s.cigarettes[username]
+	if !ok {
+		smoked = make(map[time.Time]int)
+		s.cigarettes[username] = smoked
+	}
+	smoked[date] += count
+	return smoked[date]
+}
+
+// GetSmokedByDay возвращает количество выкуренных сигарет по дням
+func (s *Storage) GetSmokedByDay(username string) map[time.Time]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	smoked := make(map[time.Time]int, len(s.cigarettes[username]))
+	for date, count := range s.cigarettes[username] {
+		smoked[date] = count
+	}
+	return smoked
+}
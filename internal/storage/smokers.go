@@ -0,0 +1,112 @@
+package storage
+
+import "github.com/NarthurN/QuitSmoking/internal/models"
+
+// Курильщики хранятся по username. Наружу отдаются только копии,
+// поэтому менять курильщика можно лишь через UpdateSmoker
+
+// AddSmoker сохраняет нового курильщика. Если id или username заняты, возвращает false
+func (s *Storage) AddSmoker(smoker *models.Smoker) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.smokers[smoker.Username]; ok {
+		return false
+	}
+	if _, ok := s.findSmoker(smoker.ID); ok {
+		return false
+	}
+	stored := *smoker
+	s.smokers[smoker.Username] = &stored
+	return true
+}
+
+// GetSmoker возвращает копию курильщика по username
+func (s *Storage) GetSmoker(username string) (*models.Smoker, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	smoker, ok := s.smokers[username]
+	if !ok {
+		return nil, false
+	}
+	found := *smoker
+	return &found, true
+}
+
+// FindSmoker возвращает копию курильщика по id
+func (s *Storage) FindSmoker(id string) (*models.Smoker, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	smoker, ok := s.findSmoker(id)
+	if !ok {
+		return nil, false
+	}
+	found := *smoker
+	return &found, true
+}
+
+// AllSmokers снимок всех курильщиков
+func (s *Storage) AllSmokers() []*models.Smoker {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	all := make([]*models.Smoker, 0, len(s.smokers))
+	for _, smoker := range s.smokers {
+		found := *smoker
+		all = append(all, &found)
+	}
+	return all
+}
+
+// UpdateSmoker находит курильщика по id и под блокировкой передаёт его копию в update.
+// Если update вернул ошибку, курильщик не меняется. Иначе копия сохраняется
+// и возвращается. ok сообщает, нашёлся ли курильщик. update не должен обращаться к хранилищу
+func (s *Storage) UpdateSmoker(id string, update func(smoker *models.Smoker) error) (updated *models.Smoker, ok bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.findSmoker(id)
+	if !ok {
+		return nil, false, nil
+	}
+	next := *current
+	if err := update(&next); err != nil {
+		return nil, true, err
+	}
+	// id и username курильщика не меняются, но на случай ошибки в update
+	// не оставляем его под старым ключом
+	delete(s.smokers, current.Username)
+	s.smokers[next.Username] = &next
+
+	result := next
+	return &result, true, nil
+}
+
+// DeleteSmoker удаляет курильщика по id, если check, получив его копию, не вернул ошибку
+func (s *Storage) DeleteSmoker(id string, check func(smoker *models.Smoker) error) (deleted *models.Smoker, ok bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.findSmoker(id)
+	if !ok {
+		return nil, false, nil
+	}
+	found := *current
+	if err := check(&found); err != nil {
+		return nil, true, err
+	}
+	delete(s.smokers, current.Username)
+	return &found, true, nil
+}
+
+// findSmoker ищет курильщика по id. Вызывается под блокировкой
+func (s *Storage) findSmoker(id string) (*models.Smoker, bool) {
+	for _, smoker := range s.smokers {
+		if smoker.ID == id {
+			return smoker, true
+		}
+	}
+	return nil, false
+}
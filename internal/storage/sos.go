@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+// AddSOSSession сохраняет новую SOS-сессию
+func (s *Storage) AddSOSSession(session *models.SOSSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sosSeq++
+	session.ID = strconv.Itoa(s.sosSeq)
+	s.sosSessions[session.ID] = session
+}
+
+// GetSOSSession возвращает копию SOS-сессии по id
+func (s *Storage) GetSOSSession(id string) (*models.SOSSession, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, ok := s.sosSessions[id]
+	if !ok {
+		return nil, false
+	}
+	copied := *session
+	return &copied, true
+}
+
+// GetOpenSOSSession возвращает незавершённую сессию курильщика, которая ещё идёт в момент now
+func (s *Storage) GetOpenSOSSession(username string, now time.Time) (*models.SOSSession, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, session := range s.sosSessions {
+		if session.Username == username && session.Outcome == "" && now.Before(session.EndsAt) {
+			copied := *session
+			return &copied, true
+		}
+	}
+	return nil, false
+}
+
+// FinishSOSSession записывает итог сессии и заодно эпизод тяги в дневник.
+// Завершить сессию можно только один раз, повторный вызов вернёт false
+func (s *Storage) FinishSOSSession(id, outcome string, craving *models.Craving) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sosSessions[id]
+	if !ok || session.Outcome != "" {
+		return false
+	}
+
+	craving.ID = strconv.Itoa(len(s.cravings[craving.Username]) + 1)
+	s.cravings[craving.Username] = append(s.cravings[craving.Username], craving)
+
+	session.Outcome = outcome
+	session.CravingID = craving.ID
+	session.FinishedAt = &craving.At
+	return true
+}
+
+// SetSOSBuddiesNotified запоминает, скольких напарников позвали на помощь
+func (s *Storage) SetSOSBuddiesNotified(id string, count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if session, ok := s.sosSessions[id]; ok {
+		session.BuddiesNotified = count
+	}
+}
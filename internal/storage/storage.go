@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"sync"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+// Storage хранит данные приложения в памяти
+type Storage struct {
+	mu           sync.RWMutex
+	smokers      map[string]*models.Smoker
+	cravings     map[string][]*models.Craving
+	achievements map[string]map[string]*models.Achievement
+	goals        map[string][]*models.Goal
+	goalSeq      int
+
+	reductionPlans map[string]*models.ReductionPlan
+	cigarettes     map[string]map[time.Time]int
+
+	nrtProducts map[string][]*models.NRTProduct
+	nrtUsages   map[string][]*models.NRTUsage
+	nrtSeq      int
+
+	checkIns map[string]map[time.Time]*models.CheckIn
+
+	buddyInvites    map[string]*models.BuddyInvite
+	buddyships      map[string]*models.Buddyship
+	buddyMessages   map[string][]*models.BuddyMessage
+	buddyMessageSeq int
+
+	challenges       map[string]*models.Challenge
+	challengeMembers map[string][]*models.ChallengeMember
+	challengeResults map[string]map[string][]models.LeaderboardEntry
+	challengeSeq     int
+	challengeJoins   map[string]int
+
+	posts   map[string]*models.Post
+	postSeq int
+	reports []*models.PostReport
+	bans    map[string]*models.Ban
+
+	notificationSettings map[string]*models.NotificationSettings
+	notifications        map[string]*models.Notification
+	notificationKeys     map[string]struct{}
+	notificationSeq      int
+	deliveries           map[string]*models.Delivery
+	deliverySeq          int
+
+	inbox    map[string][]*models.InboxItem
+	inboxSeq int
+
+	pushSubscriptions map[string]*models.PushSubscription
+
+	chatMessages   map[string][]*models.ChatMessage
+	chatMessageSeq int
+
+	reasons     map[string][]*models.Reason
+	reasonSeq   int
+	sosSessions map[string]*models.SOSSession
+	sosSeq      int
+
+	languages  map[string]string
+	currencies map[string]string
+	prices     map[string][]models.PriceChange
+
+	accountLocks map[string]*models.AccountLock
+	// tokenGens поколение токенов курильщика. Токены прежних поколений отозваны
+	tokenGens    map[string]int
+	audit        []models.AuditEvent
+	auditSeq     int
+	auditArchive func(events []models.AuditEvent)
+}
+
+func New() *Storage {
+	return &Storage{
+		smokers:      make(map[string]*models.Smoker),
+		cravings:     make(map[string][]*models.Craving),
+		achievements: make(map[string]map[string]*models.Achievement),
+		goals:        make(map[string][]*models.Goal),
+
+		reductionPlans: make(map[string]*models.ReductionPlan),
+		cigarettes:     make(map[string]map[time.Time]int),
+
+		nrtProducts: make(map[string][]*models.NRTProduct),
+		nrtUsages:   make(map[string][]*models.NRTUsage),
+
+		checkIns: make(map[string]map[time.Time]*models.CheckIn),
+
+		buddyInvites:  make(map[string]*models.BuddyInvite),
+		buddyships:    make(map[string]*models.Buddyship),
+		buddyMessages: make(map[string][]*models.BuddyMessage),
+
+		challenges:       make(map[string]*models.Challenge),
+		challengeMembers: make(map[string][]*models.ChallengeMember),
+		challengeResults: make(map[string]map[string][]models.LeaderboardEntry),
+		challengeJoins:   make(map[string]int),
+
+		posts: make(map[string]*models.Post),
+		bans:  make(map[string]*models.Ban),
+
+		notificationSettings: make(map[string]*models.NotificationSettings),
+		notifications:        make(map[string]*models.Notification),
+		notificationKeys:     make(map[string]struct{}),
+		deliveries:           make(map[string]*models.Delivery),
+
+		inbox: make(map[string][]*models.InboxItem),
+
+		pushSubscriptions: make(map[string]*models.PushSubscription),
+
+		chatMessages: make(map[string][]*models.ChatMessage),
+
+		reasons:     make(map[string][]*models.Reason),
+		sosSessions: make(map[string]*models.SOSSession),
+
+		languages:  make(map[string]string),
+		currencies: make(map[string]string),
+		prices:     make(map[string][]models.PriceChange),
+
+		accountLocks: make(map[string]*models.AccountLock),
+		tokenGens:    make(map[string]int),
+	}
+}
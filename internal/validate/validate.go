@@ -0,0 +1,191 @@
+// Package validate проверяет данные запросов по декларативным правилам:
+//
+//	var smokerRules = validate.Rules[models.Smoker]{
+//		validate.Field("name", func(s *models.Smoker) string { return s.Name },
+//			validate.NotBlank(), validate.MaxLength(100)),
+//	}
+//
+// Для каждого поля правила проверяются по порядку до первого нарушения,
+// так что у одного поля в ответе не больше одной ошибки
+package validate
+
+import (
+	"cmp"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Коды нарушений. Сообщение для кода берётся из каталога i18n по ключу "validation.<код>"
+const (
+	CodeRequired     = "required"
+	CodeMinLength    = "min_length"
+	CodeMaxLength    = "max_length"
+	CodePattern      = "pattern"
+	CodeRange        = "range"
+	CodeFuture       = "future"
+	CodeTooEarly     = "too_early"
+	CodeImmutable    = "immutable"
+	CodeInvalid      = "invalid"
+	CodeUnknownField = "unknown_field"
+	CodeType         = "type"
+)
+
+// Codes все коды нарушений. У каждого должно быть сообщение в каталогах
+func Codes() []string {
+	return []string{
+		CodeRequired, CodeMinLength, CodeMaxLength, CodePattern, CodeRange,
+		CodeFuture, CodeTooEarly, CodeImmutable, CodeInvalid, CodeUnknownField, CodeType,
+	}
+}
+
+// Violation нарушение правила в поле Field. Args подставляются в сообщение
+type Violation struct {
+	Field string
+	Code  string
+	Args  []any
+}
+
+// Errors все нарушения, найденные в одном значении
+type Errors []Violation
+
+func (e Errors) Error() string {
+	parts := make([]string, 0, len(e))
+	for _, v := range e {
+		parts = append(parts, v.Field+": "+v.Code)
+	}
+	return "validate: " + strings.Join(parts, ", ")
+}
+
+// Rule проверяет значение поля и возвращает нарушение без имени поля или nil
+type Rule[V any] func(value V) *Violation
+
+// Check проверяет одно поле значения типа T
+type Check[T any] func(v *T) *Violation
+
+// Field описывает поле: имя в JSON, как достать значение и правила для него
+func Field[T, V any](name string, get func(*T) V, rules ...Rule[V]) Check[T] {
+	return func(v *T) *Violation {
+		value := get(v)
+		for _, rule := range rules {
+			if violation := rule(value); violation != nil {
+				violation.Field = name
+				return violation
+			}
+		}
+		return nil
+	}
+}
+
+// Rules правила для всех проверяемых полей типа T
+type Rules[T any] []Check[T]
+
+// Validate проверяет все поля и возвращает Errors или nil
+func (rs Rules[T]) Validate(v *T) error {
+	var errs Errors
+	for _, check := range rs {
+		if violation := check(v); violation != nil {
+			errs = append(errs, *violation)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func violation(code string, args ...any) *Violation {
+	return &Violation{Code: code, Args: args}
+}
+
+// Required значение не должно быть нулевым: пустой строкой, нулём, нулевой датой
+func Required[V comparable]() Rule[V] {
+	return func(value V) *Violation {
+		var zero V
+		if value == zero {
+			return violation(CodeRequired)
+		}
+		return nil
+	}
+}
+
+// NotBlank строка должна содержать что-то кроме пробелов
+func NotBlank() Rule[string] {
+	return func(value string) *Violation {
+		if strings.TrimSpace(value) == "" {
+			return violation(CodeRequired)
+		}
+		return nil
+	}
+}
+
+// MinLength строка не короче n символов
+func MinLength(n int) Rule[string] {
+	return func(value string) *Violation {
+		if utf8.RuneCountInString(value) < n {
+			return violation(CodeMinLength, n)
+		}
+		return nil
+	}
+}
+
+// MaxLength строка не длиннее n символов
+func MaxLength(n int) Rule[string] {
+	return func(value string) *Violation {
+		if utf8.RuneCountInString(value) > n {
+			return violation(CodeMaxLength, n)
+		}
+		return nil
+	}
+}
+
+// Match строка целиком подходит под re
+func Match(re *regexp.Regexp) Rule[string] {
+	return func(value string) *Violation {
+		if !re.MatchString(value) {
+			return violation(CodePattern)
+		}
+		return nil
+	}
+}
+
+// Range число от min до max включительно
+func Range[V cmp.Ordered](min, max V) Rule[V] {
+	return func(value V) *Violation {
+		if value < min || value > max {
+			return violation(CodeRange, min, max)
+		}
+		return nil
+	}
+}
+
+// NotFuture дата не позже now()
+func NotFuture(now func() time.Time) Rule[time.Time] {
+	return func(value time.Time) *Violation {
+		if value.After(now()) {
+			return violation(CodeFuture)
+		}
+		return nil
+	}
+}
+
+// NotBefore дата не раньше t
+func NotBefore(t time.Time) Rule[time.Time] {
+	return func(value time.Time) *Violation {
+		if value.Before(t) {
+			return violation(CodeTooEarly, t.Format(time.DateOnly))
+		}
+		return nil
+	}
+}
+
+// Unchanged значение должно остаться прежним, например id при изменении записи
+func Unchanged[V comparable](old V) Rule[V] {
+	return func(value V) *Violation {
+		if value != old {
+			return violation(CodeImmutable)
+		}
+		return nil
+	}
+}
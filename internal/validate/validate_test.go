@@ -0,0 +1,75 @@
+package validate
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/i18n"
+	"github.com/stretchr/testify/assert"
+)
+
+type account struct {
+	Login string
+	Age   int
+	Born  time.Time
+}
+
+var now = time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+var accountRules = Rules[account]{
+	Field("login", func(a *account) string { return a.Login },
+		NotBlank(), MinLength(3), MaxLength(8), Match(regexp.MustCompile(`^[a-z]+$`))),
+	Field("age", func(a *account) int { return a.Age }, Range(18, 120)),
+	Field("born", func(a *account) time.Time { return a.Born },
+		NotBefore(time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC)),
+		NotFuture(func() time.Time { return now })),
+}
+
+func TestValidateOk(t *testing.T) {
+	a := account{Login: "arthur", Age: 30, Born: time.Date(1995, time.May, 1, 0, 0, 0, 0, time.UTC)}
+	assert.NoError(t, accountRules.Validate(&a))
+}
+
+func TestValidateCollectsAllFields(t *testing.T) {
+	a := account{Login: "  ", Age: 7, Born: now.Add(time.Hour)}
+	err := accountRules.Validate(&a)
+
+	assert.Equal(t, Errors{
+		{Field: "login", Code: CodeRequired},
+		{Field: "age", Code: CodeRange, Args: []any{18, 120}},
+		{Field: "born", Code: CodeFuture},
+	}, err)
+}
+
+func TestFirstViolationPerField(t *testing.T) {
+	// Короткий логин с заглавными буквами: сообщаем только о длине
+	a := account{Login: "AB", Age: 30, Born: time.Date(1995, time.May, 1, 0, 0, 0, 0, time.UTC)}
+	err := accountRules.Validate(&a)
+
+	assert.Equal(t, Errors{{Field: "login", Code: CodeMinLength, Args: []any{3}}}, err)
+}
+
+func TestLengthCountsRunes(t *testing.T) {
+	assert.Nil(t, MaxLength(5)("привет"[:10]))
+	assert.NotNil(t, MaxLength(5)("привет"))
+}
+
+func TestZeroTimeIsTooEarly(t *testing.T) {
+	a := account{Login: "arthur", Age: 30}
+	err := accountRules.Validate(&a)
+
+	assert.Equal(t, Errors{{Field: "born", Code: CodeTooEarly, Args: []any{"1900-01-01"}}}, err)
+}
+
+func TestRequired(t *testing.T) {
+	assert.NotNil(t, Required[int]()(0))
+	assert.Nil(t, Required[int]()(1))
+}
+
+func TestCodesHaveMessages(t *testing.T) {
+	loc := i18n.Default().Localizer(i18n.DefaultLocale)
+	for _, code := range Codes() {
+		assert.NotEqual(t, "validation."+code, loc.T("validation."+code), code)
+	}
+}
@@ -0,0 +1,175 @@
+package webpush
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+// Шифрование содержимого aes128gcm (RFC 8188) с ключами Web Push (RFC 8291).
+// Сообщение отправляется одной записью размером не больше recordSize
+const (
+	recordSize = 4096
+	saltSize   = 16
+	keySize    = 65
+	authSize   = 16
+	headerSize = saltSize + 4 + 1 + keySize
+	// Заголовок записи, тег GCM и разделитель последней записи
+	MaxPayloadSize = recordSize - headerSize - aes.BlockSize - 1
+)
+
+var ErrPayloadTooLarge = errors.New("payload too large")
+
+// Encrypt шифрует сообщение для подписки браузера
+func Encrypt(keys models.PushKeys, plaintext []byte) ([]byte, error) {
+	op := "webpush.Encrypt"
+
+	uaPublic, authSecret, err := decodeKeys(keys)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	body, err := encrypt(uaPublic, authSecret, asPrivate, salt, plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return body, nil
+}
+
+// Decrypt расшифровывает сообщение на стороне браузера. Нужен фейковому push-сервису
+func Decrypt(uaPrivate *ecdh.PrivateKey, authSecret, body []byte) ([]byte, error) {
+	op := "webpush.Decrypt"
+
+	if len(body) < headerSize {
+		return nil, fmt.Errorf("%s: body too short", op)
+	}
+	salt := body[:saltSize]
+	if rs := binary.BigEndian.Uint32(body[saltSize:]); rs < uint32(len(body)-headerSize) {
+		return nil, fmt.Errorf("%s: multiple records are not supported", op)
+	}
+	if body[saltSize+4] != keySize {
+		return nil, fmt.Errorf("%s: unexpected key id length", op)
+	}
+	asPublic, err := ecdh.P256().NewPublicKey(body[saltSize+5 : headerSize])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	secret, err := uaPrivate.ECDH(asPublic)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	gcm, nonce, err := contentKeys(secret, authSecret, uaPrivate.PublicKey().Bytes(), asPublic.Bytes(), salt)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	record, err := gcm.Open(nil, nonce, body[headerSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	// Отбрасываем дополнение из нулей и разделитель последней записи
+	record = bytes.TrimRight(record, "\x00")
+	if len(record) == 0 || record[len(record)-1] != 0x02 {
+		return nil, fmt.Errorf("%s: invalid padding", op)
+	}
+	return record[:len(record)-1], nil
+}
+
+func encrypt(uaPublic *ecdh.PublicKey, authSecret []byte, asPrivate *ecdh.PrivateKey, salt, plaintext []byte) ([]byte, error) {
+	if len(plaintext) > MaxPayloadSize {
+		return nil, ErrPayloadTooLarge
+	}
+
+	secret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := asPrivate.PublicKey().Bytes()
+	gcm, nonce, err := contentKeys(secret, authSecret, uaPublic.Bytes(), asPublic, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	body := make([]byte, 0, headerSize+len(plaintext)+1+gcm.Overhead())
+	body = append(body, salt...)
+	body = binary.BigEndian.AppendUint32(body, recordSize)
+	body = append(body, keySize)
+	body = append(body, asPublic...)
+	record := append(bytes.Clone(plaintext), 0x02)
+	return gcm.Seal(body, nonce, record, nil), nil
+}
+
+// contentKeys выводит ключ шифрования и nonce по RFC 8291, раздел 3.4
+func contentKeys(secret, authSecret, uaPublic, asPublic, salt []byte) (cipher.AEAD, []byte, error) {
+	keyInfo := append([]byte("WebPush: info\x00"), uaPublic...)
+	keyInfo = append(keyInfo, asPublic...)
+	ikm := hkdfExpand(hkdfExtract(authSecret, secret), keyInfo, 32)
+
+	prk := hkdfExtract(salt, ikm)
+	cek := hkdfExpand(prk, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce := hkdfExpand(prk, []byte("Content-Encoding: nonce\x00"), 12)
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	return gcm, nonce, nil
+}
+
+func hkdfExtract(salt, ikm []byte) []byte {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write(ikm)
+	return mac.Sum(nil)
+}
+
+// hkdfExpand HKDF-Expand для длины не больше одного блока SHA-256, другой здесь не нужно
+func hkdfExpand(prk, info []byte, length int) []byte {
+	mac := hmac.New(sha256.New, prk)
+	mac.Write(info)
+	mac.Write([]byte{0x01})
+	return mac.Sum(nil)[:length]
+}
+
+func decodeKeys(keys models.PushKeys) (*ecdh.PublicKey, []byte, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(keys.P256dh)
+	if err != nil {
+		return nil, nil, fmt.Errorf("p256dh: %w", err)
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(raw)
+	if err != nil {
+		return nil, nil, fmt.Errorf("p256dh: %w", err)
+	}
+
+	authSecret, err := base64.RawURLEncoding.DecodeString(keys.Auth)
+	if err != nil {
+		return nil, nil, fmt.Errorf("auth: %w", err)
+	}
+	if len(authSecret) != authSize {
+		return nil, nil, fmt.Errorf("auth: must be %d bytes", authSize)
+	}
+	return uaPublic, authSecret, nil
+}
@@ -0,0 +1,112 @@
+package webpush
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Сколько живёт подпись VAPID. RFC 8292 запрещает срок больше суток
+const vapidTTL = 12 * time.Hour
+
+// VAPID ключ сервера приложения (RFC 8292), которым подписываются запросы к push-сервису.
+// Subject — контакт владельца сервера: mailto: или https: адрес
+type VAPID struct {
+	private *ecdsa.PrivateKey
+	subject string
+}
+
+// GenerateVAPID создаёт новый ключ P-256
+func GenerateVAPID(subject string) (*VAPID, error) {
+	op := "webpush.GenerateVAPID"
+
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &VAPID{private: private, subject: subject}, nil
+}
+
+// LoadVAPID читает ключ из PEM-файла, а если файла нет — создаёт ключ и сохраняет его.
+// Ключ должен переживать перезапуски, иначе все подписки браузеров станут недействительными
+func LoadVAPID(path, subject string) (*VAPID, error) {
+	op := "webpush.LoadVAPID"
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		vapid, err := GenerateVAPID(subject)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		der, err := x509.MarshalECPrivateKey(vapid.private)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		return vapid, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data in %s", op, path)
+	}
+	private, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if private.Curve != elliptic.P256() {
+		return nil, fmt.Errorf("%s: key is not P-256", op)
+	}
+	return &VAPID{private: private, subject: subject}, nil
+}
+
+// PublicKey возвращает публичный ключ в base64url — его браузер передаёт
+// в PushManager.subscribe как applicationServerKey
+func (v *VAPID) PublicKey() string {
+	return base64.RawURLEncoding.EncodeToString(v.publicKey())
+}
+
+func (v *VAPID) publicKey() []byte {
+	public, err := v.private.PublicKey.ECDH()
+	if err != nil {
+		// Ключ P-256 всегда приводится к ECDH
+		panic(err)
+	}
+	return public.Bytes()
+}
+
+// Authorization возвращает значение заголовка Authorization для запроса к endpoint
+func (v *VAPID) Authorization(endpoint string, now time.Time) (string, error) {
+	op := "webpush.VAPID.Authorization"
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": now.Add(vapidTTL).Unix(),
+		"sub": v.subject,
+	})
+	signed, err := token.SignedString(v.private)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	return fmt.Sprintf("vapid t=%s, k=%s", signed, v.PublicKey()), nil
+}
@@ -0,0 +1,114 @@
+package webpush
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/NarthurN/QuitSmoking/internal/netguard"
+)
+
+// ErrGone push-сервис сообщил, что подписки больше нет, и её нужно удалить
+var ErrGone = errors.New("push subscription is gone")
+
+// Client отправляет сообщения Web Push на endpoint подписки
+type Client struct {
+	vapid  *VAPID
+	client *http.Client
+}
+
+// NewClient создаёт клиента, который отправляет сообщения только на публичные адреса:
+// endpoint присылает браузер, и он не должен вести во внутреннюю сеть. В режиме
+// разработки (dev) разрешён и локальный push-сервис
+func NewClient(vapid *VAPID, dev bool) *Client {
+	client := &http.Client{Timeout: 10 * time.Second}
+	if !dev {
+		client.Transport = netguard.Transport()
+	}
+	return &Client{
+		vapid:  vapid,
+		client: client,
+	}
+}
+
+// Send шифрует payload для подписки и отправляет его. ttl — сколько push-сервис
+// хранит сообщение, пока браузер не в сети
+func (c *Client) Send(ctx context.Context, subscription models.PushSubscription, payload []byte, ttl time.Duration) error {
+	op := "webpush.Client.Send"
+
+	body, err := Encrypt(subscription.Keys, payload)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	authorization, err := c.vapid.Authorization(subscription.Endpoint, time.Now())
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", strconv.Itoa(int(ttl.Seconds())))
+	req.Header.Set("Authorization", authorization)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return fmt.Errorf("%s: %w", op, ErrGone)
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return fmt.Errorf("%s: unexpected status %d", op, resp.StatusCode)
+	}
+	return nil
+}
+
+// ValidateSubscription проверяет подписку, присланную браузером. Endpoint должен быть
+// https и вести только на публичные адреса, иначе возвращается netguard.ErrPrivateAddress.
+// В режиме разработки (dev) допускается локальный push-сервис, в том числе по http
+func ValidateSubscription(ctx context.Context, subscription models.PushSubscription, dev bool) error {
+	u, err := url.Parse(subscription.Endpoint)
+	if err != nil || u.Host == "" {
+		return errors.New("invalid endpoint")
+	}
+	switch u.Scheme {
+	case "https":
+	case "http":
+		if !dev || !isLoopback(u.Hostname()) {
+			return errors.New("endpoint must be https")
+		}
+	default:
+		return errors.New("invalid endpoint")
+	}
+	if !dev {
+		if err := netguard.CheckHost(ctx, u.Hostname()); err != nil {
+			return err
+		}
+	}
+
+	if _, _, err := decodeKeys(subscription.Keys); err != nil {
+		return err
+	}
+	return nil
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
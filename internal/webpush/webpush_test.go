@@ -0,0 +1,109 @@
+package webpush_test
+
+import (
+	"context"
+	"crypto/ecdh"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/NarthurN/QuitSmoking/internal/netguard"
+	"github.com/NarthurN/QuitSmoking/internal/webpush"
+	"github.com/NarthurN/QuitSmoking/internal/webpush/webpushtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecryptRFC8291Example(t *testing.T) {
+	// Пример из RFC 8291, приложение A
+	b64 := base64.RawURLEncoding
+	uaPrivateRaw, _ := b64.DecodeString("q1dXpw3UpT5VOmu_cf_v6ih07Aems3njxI-JWgLcM94")
+	authSecret, _ := b64.DecodeString("BTBZMqHH6r4Tts7J_aSIgg")
+	body, _ := b64.DecodeString("DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN")
+
+	uaPrivate, err := ecdh.P256().NewPrivateKey(uaPrivateRaw)
+	assert.NoError(t, err)
+
+	plaintext, err := webpush.Decrypt(uaPrivate, authSecret, body)
+	assert.NoError(t, err)
+	assert.Equal(t, "When I grow up, I want to be a watermelon", string(plaintext))
+}
+
+func TestSendToFakeService(t *testing.T) {
+	vapid, err := webpush.GenerateVAPID("mailto:admin@example.com")
+	assert.NoError(t, err)
+
+	service := webpushtest.NewServer(vapid.PublicKey())
+	defer service.Close()
+
+	client := webpush.NewClient(vapid, true)
+	subscription := service.Subscribe()
+	assert.NoError(t, webpush.ValidateSubscription(context.Background(), subscription, true))
+
+	err = client.Send(context.Background(), subscription, []byte(`{"title":"Неделя без сигарет"}`), time.Hour)
+	assert.NoError(t, err)
+
+	messages := service.Messages()
+	if assert.Len(t, messages, 1) {
+		assert.Equal(t, `{"title":"Неделя без сигарет"}`, string(messages[0].Payload))
+		assert.Equal(t, 3600, messages[0].TTL)
+	}
+
+	// Чужой ключ VAPID сервис не принимает
+	other, _ := webpush.GenerateVAPID("mailto:admin@example.com")
+	err = webpush.NewClient(other, true).Send(context.Background(), subscription, []byte("привет"), time.Hour)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, webpush.ErrGone)
+
+	// Отменённая подписка
+	service.Unsubscribe(subscription.Endpoint)
+	err = client.Send(context.Background(), subscription, []byte("привет"), time.Hour)
+	assert.ErrorIs(t, err, webpush.ErrGone)
+
+	big := make([]byte, webpush.MaxPayloadSize+1)
+	err = client.Send(context.Background(), service.Subscribe(), big, time.Hour)
+	assert.ErrorIs(t, err, webpush.ErrPayloadTooLarge)
+}
+
+func TestValidateSubscription(t *testing.T) {
+	keys := models.PushKeys{
+		P256dh: "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4",
+		Auth:   "BTBZMqHH6r4Tts7J_aSIgg",
+	}
+
+	ctx := context.Background()
+	validate := func(endpoint string, keys models.PushKeys, dev bool) error {
+		return webpush.ValidateSubscription(ctx, models.PushSubscription{Endpoint: endpoint, Keys: keys}, dev)
+	}
+
+	assert.NoError(t, validate("https://93.184.216.34/abc", keys, false))
+	assert.Error(t, validate("http://93.184.216.34/abc", keys, false))
+	assert.Error(t, validate("https://93.184.216.34/abc", models.PushKeys{P256dh: keys.P256dh, Auth: "c2hvcnQ"}, false))
+	assert.Error(t, validate("https://93.184.216.34/abc", models.PushKeys{P256dh: "AAAA", Auth: keys.Auth}, false))
+
+	// Endpoint во внутренней сети отклоняется, а локальный push-сервис — только при разработке
+	for _, endpoint := range []string{
+		"https://127.0.0.1:9000/push/1",
+		"https://localhost/push/1",
+		"https://10.0.0.5/push/1",
+		"https://192.168.1.1/push/1",
+		"https://169.254.169.254/latest/meta-data",
+		"https://[::1]/push/1",
+	} {
+		assert.ErrorIs(t, validate(endpoint, keys, false), netguard.ErrPrivateAddress, endpoint)
+	}
+	assert.Error(t, validate("http://127.0.0.1:9000/push/1", keys, false))
+	assert.NoError(t, validate("http://127.0.0.1:9000/push/1", keys, true))
+}
+
+func TestClientRefusesPrivateAddress(t *testing.T) {
+	vapid, err := webpush.GenerateVAPID("mailto:admin@example.com")
+	assert.NoError(t, err)
+	service := webpushtest.NewServer(vapid.PublicKey())
+	defer service.Close()
+
+	// Подписка, которая уже сохранена, всё равно не отправляет запрос во внутреннюю сеть
+	err = webpush.NewClient(vapid, false).Send(context.Background(), service.Subscribe(), []byte("привет"), time.Hour)
+	assert.ErrorIs(t, err, netguard.ErrPrivateAddress)
+	assert.Empty(t, service.Messages())
+}
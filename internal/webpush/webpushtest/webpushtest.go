@@ -0,0 +1,185 @@
+// Package webpushtest фейковый push-сервис, чтобы проверять Web Push без браузера
+// и настоящих серверов Mozilla или Google
+package webpushtest
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/NarthurN/QuitSmoking/internal/webpush"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Message сообщение, принятое и расшифрованное фейковым сервисом
+type Message struct {
+	Endpoint string
+	Payload  []byte
+	TTL      int
+}
+
+type subscriber struct {
+	private    *ecdh.PrivateKey
+	authSecret []byte
+}
+
+// Server принимает сообщения так же, как настоящий push-сервис: проверяет подпись VAPID,
+// расшифровывает тело ключами «браузера» и отвечает 201, а на отменённые подписки — 410
+type Server struct {
+	*httptest.Server
+
+	vapidKey string
+
+	mu          sync.Mutex
+	seq         int
+	subscribers map[string]*subscriber
+	messages    []Message
+}
+
+// NewServer запускает сервис, который принимает сообщения, подписанные ключом vapidKey
+func NewServer(vapidKey string) *Server {
+	s := &Server{
+		vapidKey:    vapidKey,
+		subscribers: make(map[string]*subscriber),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.push))
+	return s
+}
+
+// Subscribe создаёт подписку, как это делает PushManager.subscribe в браузере
+func (s *Server) Subscribe() models.PushSubscription {
+	private, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	authSecret := make([]byte, 16)
+	rand.Read(authSecret)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	endpoint := s.URL + "/push/" + strconv.Itoa(s.seq)
+	s.subscribers[endpoint] = &subscriber{private: private, authSecret: authSecret}
+
+	return models.PushSubscription{
+		Endpoint: endpoint,
+		Keys: models.PushKeys{
+			P256dh: base64.RawURLEncoding.EncodeToString(private.PublicKey().Bytes()),
+			Auth:   base64.RawURLEncoding.EncodeToString(authSecret),
+		},
+	}
+}
+
+// Unsubscribe отменяет подписку, будто пользователь запретил уведомления
+func (s *Server) Unsubscribe(endpoint string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.subscribers, endpoint)
+}
+
+// Messages возвращает принятые сообщения
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+func (s *Server) push(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	endpoint := s.URL + r.URL.Path
+
+	s.mu.Lock()
+	sub, ok := s.subscribers[endpoint]
+	s.mu.Unlock()
+	if !ok {
+		http.Error(w, http.StatusText(http.StatusGone), http.StatusGone)
+		return
+	}
+
+	if err := s.verifyVAPID(r.Header.Get("Authorization")); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if r.Header.Get("Content-Encoding") != "aes128gcm" {
+		http.Error(w, "unsupported content encoding", http.StatusUnsupportedMediaType)
+		return
+	}
+	ttl, err := strconv.Atoi(r.Header.Get("TTL"))
+	if err != nil {
+		http.Error(w, "missing TTL", http.StatusBadRequest)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 4097))
+	if err != nil || len(body) > 4096 {
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return
+	}
+	payload, err := webpush.Decrypt(sub.private, sub.authSecret, body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.messages = append(s.messages, Message{Endpoint: endpoint, Payload: payload, TTL: ttl})
+	s.mu.Unlock()
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) verifyVAPID(header string) error {
+	params := make(map[string]string)
+	scheme, rest, _ := strings.Cut(header, " ")
+	if scheme != "vapid" {
+		return errors.New("missing vapid authorization")
+	}
+	for _, part := range strings.Split(rest, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		params[key] = value
+	}
+	if params["k"] != s.vapidKey {
+		return errors.New("unknown vapid key")
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(params["k"])
+	if err != nil {
+		return err
+	}
+	// NewPublicKey заодно проверяет, что точка лежит на кривой
+	if _, err := ecdh.P256().NewPublicKey(raw); err != nil {
+		return err
+	}
+	public := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(raw[1:33]),
+		Y:     new(big.Int).SetBytes(raw[33:]),
+	}
+
+	_, err = jwt.Parse(params["t"], func(*jwt.Token) (any, error) {
+		return public, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodES256.Alg()}),
+		jwt.WithAudience(s.URL),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return fmt.Errorf("invalid vapid token: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package wellbeing
+
+import (
+	"math"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+)
+
+const day = 24 * time.Hour
+
+// WeeklySummary считает сводку самочувствия за 7 дней, заканчивающихся днём now,
+// и сопоставляет настроение с количеством эпизодов тяги и числом дней без сигарет
+func WeeklySummary(checkIns []*models.CheckIn, cravings []*models.Craving, stoppedSmoking, now time.Time) models.WeeklySummary {
+	to := now.UTC().Truncate(day)
+	from := to.Add(-6 * day)
+
+	summary := models.WeeklySummary{
+		From:     from,
+		To:       to,
+		Symptoms: make(map[string]int),
+	}
+
+	cravingsByDay := make(map[time.Time]int)
+	for _, c := range cravings {
+		date := c.At.UTC().Truncate(day)
+		if date.Before(from) || date.After(to) {
+			continue
+		}
+		cravingsByDay[date]++
+		summary.Cravings++
+	}
+
+	var moods, dayCravings, daysSmokeFree []float64
+	var sumMood, sumSleep, sumStress float64
+	for _, checkIn := range checkIns {
+		date := checkIn.Date.UTC().Truncate(day)
+		if date.Before(from) || date.After(to) {
+			continue
+		}
+
+		summary.CheckIns++
+		sumMood += float64(checkIn.Mood)
+		sumSleep += checkIn.SleepHours
+		sumStress += float64(checkIn.Stress)
+		for _, symptom := range checkIn.Symptoms {
+			summary.Symptoms[symptom]++
+		}
+
+		moods = append(moods, float64(checkIn.Mood))
+		dayCravings = append(dayCravings, float64(cravingsByDay[date]))
+		daysSmokeFree = append(daysSmokeFree, math.Max(0, math.Floor(date.Sub(stoppedSmoking).Hours()/24)))
+	}
+
+	if summary.CheckIns > 0 {
+		n := float64(summary.CheckIns)
+		summary.AverageMood = round(sumMood / n)
+		summary.AverageSleep = round(sumSleep / n)
+		summary.AverageStress = round(sumStress / n)
+	}
+	summary.MoodVsCravings = correlation(moods, dayCravings)
+	summary.MoodVsDaysSmokeFree = correlation(moods, daysSmokeFree)
+
+	return summary
+}
+
+// correlation возвращает коэффициент корреляции Пирсона или nil,
+// если точек меньше трёх или одна из величин не меняется
+func correlation(x, y []float64) *float64 {
+	n := float64(len(x))
+	if len(x) < 3 || len(x) != len(y) {
+		return nil
+	}
+
+	var sumX, sumY float64
+	for i := range x {
+		sumX += x[i]
+		sumY += y[i]
+	}
+	meanX, meanY := sumX/n, sumY/n
+
+	var cov, varX, varY float64
+	for i := range x {
+		dx, dy := x[i]-meanX, y[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return nil
+	}
+
+	r := round(cov / math.Sqrt(varX*varY))
+	return &r
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
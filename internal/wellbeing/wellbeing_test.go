@@ -0,0 +1,47 @@
+package wellbeing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWeeklySummary(t *testing.T) {
+	now := time.Date(2025, time.March, 10, 12, 0, 0, 0, time.UTC)
+	stopped := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	at := func(daysAgo int) time.Time { return now.Add(-time.Duration(daysAgo) * day) }
+
+	checkIns := []*models.CheckIn{
+		{Date: at(0), Mood: 5, SleepHours: 8, Stress: 1},
+		{Date: at(1), Mood: 3, SleepHours: 6, Stress: 3, Symptoms: []string{"anxiety"}},
+		{Date: at(2), Mood: 1, SleepHours: 4, Stress: 5, Symptoms: []string{"anxiety", "insomnia"}},
+		// Отметка за пределами недели не учитывается
+		{Date: at(10), Mood: 1, SleepHours: 2, Stress: 5},
+	}
+	cravings := []*models.Craving{
+		{At: at(1)},
+		{At: at(2)}, {At: at(2)}, {At: at(2)},
+	}
+
+	summary := WeeklySummary(checkIns, cravings, stopped, now)
+
+	assert.Equal(t, 3, summary.CheckIns)
+	assert.Equal(t, 3.0, summary.AverageMood)
+	assert.Equal(t, 4, summary.Cravings)
+	assert.Equal(t, 2, summary.Symptoms["anxiety"])
+	if assert.NotNil(t, summary.MoodVsCravings) {
+		assert.Less(t, *summary.MoodVsCravings, 0.0)
+	}
+	if assert.NotNil(t, summary.MoodVsDaysSmokeFree) {
+		assert.Equal(t, 1.0, *summary.MoodVsDaysSmokeFree)
+	}
+}
+
+func TestWeeklySummaryWithoutData(t *testing.T) {
+	summary := WeeklySummary(nil, nil, time.Now(), time.Now())
+
+	assert.Zero(t, summary.CheckIns)
+	assert.Nil(t, summary.MoodVsCravings)
+}
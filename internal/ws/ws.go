@@ -0,0 +1,336 @@
+// Package ws серверная часть протокола WebSocket (RFC 6455): рукопожатие,
+// чтение и запись кадров. Расширения и подпротоколы не поддерживаются
+package ws
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/NarthurN/QuitSmoking/internal/apperr"
+)
+
+// Коды операций
+const (
+	OpContinuation = 0x0
+	OpText         = 0x1
+	OpBinary       = 0x2
+	OpClose        = 0x8
+	OpPing         = 0x9
+	OpPong         = 0xA
+)
+
+// Коды закрытия соединения
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseUnsupportedData = 1003
+	CloseNoStatus        = 1005
+	CloseInvalidPayload  = 1007
+	ClosePolicyViolation = 1008
+	CloseTooBig          = 1009
+	CloseTryAgainLater   = 1013
+)
+
+// Максимальный размер сообщения по умолчанию
+const DefaultMaxMessageSize = 64 << 10
+
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+var (
+	ErrProtocol = errors.New("websocket: protocol error")
+	ErrTooBig   = errors.New("websocket: message too big")
+)
+
+// CloseError клиент закрыл соединение
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: closed with code %d %s", e.Code, e.Reason)
+}
+
+// Upgrade переключает HTTP-соединение на WebSocket. Запросы с чужим Origin отклоняются:
+// браузер отправляет cookie с токеном и на рукопожатие с чужого сайта.
+// Отклонённое рукопожатие возвращается как *apperr.Error, а ответ на него пишет вызывающий.
+// Любая другая ошибка случается, когда соединение уже перехвачено и отвечать некуда
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	op := "ws.Upgrade"
+
+	if r.Method != http.MethodGet {
+		return nil, apperr.New(http.StatusMethodNotAllowed, apperr.CodeMethodNotAllowed, r.Method).
+			Wrap(fmt.Errorf("%s: method %s", op, r.Method))
+	}
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		return nil, apperr.BadRequest(apperr.CodeWebSocketExpected).
+			Wrap(fmt.Errorf("%s: not a websocket handshake", op))
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, apperr.New(http.StatusUpgradeRequired, apperr.CodeWebSocketVersion, 13).
+			Wrap(fmt.Errorf("%s: unsupported version", op))
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, apperr.BadRequest(apperr.CodeBadRequest).Wrap(fmt.Errorf("%s: invalid key", op))
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		u, err := url.Parse(origin)
+		if err != nil || !strings.EqualFold(u.Host, r.Host) {
+			return nil, apperr.New(http.StatusForbidden, apperr.CodeForbidden).
+				Wrap(fmt.Errorf("%s: cross-origin request from %s", op, origin))
+		}
+	}
+
+	netConn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, apperr.Internal(fmt.Errorf("%s: %w", op, err))
+	}
+	// Снимаем таймауты http.Server, дальше ими управляет владелец соединения
+	netConn.SetDeadline(time.Time{})
+
+	brw.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	brw.WriteString("Upgrade: websocket\r\n")
+	brw.WriteString("Connection: Upgrade\r\n")
+	brw.WriteString("Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n")
+	// Заголовки, которые успели выставить middleware: X-Request-ID и обновлённый токен в cookie
+	w.Header().Write(brw)
+	brw.WriteString("\r\n")
+	if err := brw.Flush(); err != nil {
+		netConn.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Conn{
+		conn:           netConn,
+		br:             brw.Reader,
+		MaxMessageSize: DefaultMaxMessageSize,
+	}, nil
+}
+
+// Conn соединение WebSocket. ReadMessage вызывается из одной горутины,
+// писать можно из нескольких
+type Conn struct {
+	conn net.Conn
+	br   *bufio.Reader
+
+	// MaxMessageSize максимальный размер сообщения, больше — соединение закрывается с кодом 1009
+	MaxMessageSize int64
+	// ReadTimeout сколько ждать следующего кадра, включая pong. Ноль — ждать бесконечно
+	ReadTimeout time.Duration
+
+	writeMu   sync.Mutex
+	closeSent bool
+}
+
+// ReadMessage читает следующее сообщение с данными. На ping отвечает сам,
+// на закрытие со стороны клиента возвращает *CloseError
+func (c *Conn) ReadMessage() (int, []byte, error) {
+	var (
+		messageOp int
+		message   []byte
+	)
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if op >= OpClose {
+			switch op {
+			case OpPing:
+				if err := c.WriteMessage(OpPong, payload); err != nil {
+					return 0, nil, err
+				}
+			case OpClose:
+				closeErr := &CloseError{Code: CloseNoStatus}
+				if len(payload) >= 2 {
+					closeErr.Code = int(binary.BigEndian.Uint16(payload))
+					closeErr.Reason = string(payload[2:])
+				}
+				code := closeErr.Code
+				if code == CloseNoStatus {
+					code = CloseNormal
+				}
+				c.WriteClose(code, "")
+				return 0, nil, closeErr
+			}
+			continue
+		}
+
+		switch {
+		case op == OpContinuation && messageOp == 0:
+			return 0, nil, c.fail(CloseProtocolError, ErrProtocol)
+		case op != OpContinuation && messageOp != 0:
+			return 0, nil, c.fail(CloseProtocolError, ErrProtocol)
+		case op != OpContinuation:
+			messageOp = op
+		}
+
+		if int64(len(message)+len(payload)) > c.MaxMessageSize {
+			return 0, nil, c.fail(CloseTooBig, ErrTooBig)
+		}
+		message = append(message, payload...)
+
+		if fin {
+			if messageOp == OpText && !utf8.Valid(message) {
+				return 0, nil, c.fail(CloseInvalidPayload, ErrProtocol)
+			}
+			return messageOp, message, nil
+		}
+	}
+}
+
+func (c *Conn) readFrame() (bool, int, []byte, error) {
+	if c.ReadTimeout > 0 {
+		c.conn.SetReadDeadline(time.Now().Add(c.ReadTimeout))
+	}
+
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin := header[0]&0x80 != 0
+	op := int(header[0] & 0x0F)
+	masked := header[1]&0x80 != 0
+	length := int64(header[1] & 0x7F)
+
+	// Расширения не согласовывались, поэтому биты RSV должны быть нулевыми,
+	// а клиент обязан маскировать каждый кадр
+	if header[0]&0x70 != 0 || !masked {
+		return false, 0, nil, c.fail(CloseProtocolError, ErrProtocol)
+	}
+	switch op {
+	case OpContinuation, OpText, OpBinary:
+	case OpClose, OpPing, OpPong:
+		if !fin || length > 125 {
+			return false, 0, nil, c.fail(CloseProtocolError, ErrProtocol)
+		}
+	default:
+		return false, 0, nil, c.fail(CloseProtocolError, ErrProtocol)
+	}
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+	if length < 0 || length > c.MaxMessageSize {
+		return false, 0, nil, c.fail(CloseTooBig, ErrTooBig)
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, op, payload, nil
+}
+
+// WriteMessage отправляет сообщение одним кадром
+func (c *Conn) WriteMessage(op int, data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closeSent {
+		return net.ErrClosed
+	}
+	return c.writeFrame(op, data)
+}
+
+// WriteClose отправляет кадр закрытия. Повторные вызовы ничего не делают
+func (c *Conn) WriteClose(code int, reason string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closeSent {
+		return nil
+	}
+	c.closeSent = true
+
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	payload = append(payload, reason...)
+	if len(payload) > 125 {
+		payload = payload[:125]
+	}
+	return c.writeFrame(OpClose, payload)
+}
+
+func (c *Conn) writeFrame(op int, data []byte) error {
+	frame := make([]byte, 0, len(data)+10)
+	frame = append(frame, 0x80|byte(op))
+	switch n := len(data); {
+	case n <= 125:
+		frame = append(frame, byte(n))
+	case n <= 0xFFFF:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	frame = append(frame, data...)
+
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// SetWriteDeadline ограничивает время записи, чтобы медленный клиент не держал отправителя
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// Close закрывает соединение без кадра закрытия
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Conn) fail(code int, err error) error {
+	c.WriteClose(code, "")
+	return err
+}
+
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func headerContains(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package ws_test
+
+import (
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NarthurN/QuitSmoking/internal/apperr"
+	"github.com/NarthurN/QuitSmoking/internal/ws"
+	"github.com/NarthurN/QuitSmoking/internal/ws/wstest"
+	"github.com/stretchr/testify/assert"
+)
+
+func echoServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := ws.Upgrade(w, r)
+		if err != nil {
+			// Отклонённое рукопожатие Upgrade отдаёт вызывающему
+			http.Error(w, err.Error(), apperr.From(err).Status)
+			return
+		}
+		defer conn.Close()
+		conn.MaxMessageSize = 1024
+
+		for {
+			op, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.WriteMessage(op, data)
+		}
+	}))
+}
+
+func TestEcho(t *testing.T) {
+	srv := echoServer(t)
+	defer srv.Close()
+
+	client, resp, err := wstest.Dial(srv.URL, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer client.Close()
+	assert.NotEmpty(t, resp.Header.Get("Sec-WebSocket-Accept"))
+
+	assert.NoError(t, client.WriteText("привет"))
+	op, data, err := client.ReadFrame(time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, ws.OpText, op)
+	assert.Equal(t, "привет", string(data))
+
+	// Сообщение из нескольких кадров с ping посередине
+	assert.NoError(t, client.WriteFrame(false, ws.OpText, []byte("раз, ")))
+	assert.NoError(t, client.WriteFrame(true, ws.OpPing, []byte("ping")))
+	assert.NoError(t, client.WriteFrame(true, ws.OpContinuation, []byte("два")))
+
+	op, data, err = client.ReadFrame(time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, ws.OpPong, op)
+	assert.Equal(t, "ping", string(data))
+
+	op, data, err = client.ReadFrame(time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, ws.OpText, op)
+	assert.Equal(t, "раз, два", string(data))
+
+	// Закрытие: сервер отвечает тем же кодом
+	assert.NoError(t, client.WriteFrame(true, ws.OpClose, binary.BigEndian.AppendUint16(nil, ws.CloseGoingAway)))
+	op, data, err = client.ReadFrame(time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, ws.OpClose, op)
+	assert.Equal(t, uint16(ws.CloseGoingAway), binary.BigEndian.Uint16(data))
+}
+
+func TestTooBigMessage(t *testing.T) {
+	srv := echoServer(t)
+	defer srv.Close()
+
+	client, _, err := wstest.Dial(srv.URL, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer client.Close()
+
+	assert.NoError(t, client.WriteText(strings.Repeat("a", 2048)))
+	op, data, err := client.ReadFrame(time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, ws.OpClose, op)
+	assert.Equal(t, uint16(ws.CloseTooBig), binary.BigEndian.Uint16(data))
+}
+
+func TestUpgradeRejectsForeignOrigin(t *testing.T) {
+	srv := echoServer(t)
+	defer srv.Close()
+
+	_, resp, err := wstest.Dial(srv.URL, http.Header{"Origin": {"https://evil.example.com"}})
+	assert.Error(t, err)
+	if assert.NotNil(t, resp) {
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	}
+
+	client, _, err := wstest.Dial(srv.URL, http.Header{"Origin": {srv.URL}})
+	if assert.NoError(t, err) {
+		client.Close()
+	}
+}
@@ -0,0 +1,129 @@
+// Package wstest простой клиент WebSocket для тестов сервера
+package wstest
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Client клиентская сторона соединения: маскирует свои кадры, как требует RFC 6455
+type Client struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+// Dial выполняет рукопожатие с сервером по адресу http(s)://… или ws://…
+func Dial(rawURL string, header http.Header) (*Client, *http.Response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn, err := net.Dial("tcp", u.Host)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key := make([]byte, 16)
+	rand.Read(key)
+
+	req, _ := http.NewRequest(http.MethodGet, "http://"+u.Host+u.RequestURI(), nil)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", base64.StdEncoding.EncodeToString(key))
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		return nil, resp, fmt.Errorf("wstest: handshake status %d", resp.StatusCode)
+	}
+	return &Client{conn: conn, br: br}, resp, nil
+}
+
+// WriteFrame отправляет один кадр
+func (c *Client) WriteFrame(fin bool, op int, payload []byte) error {
+	first := byte(op)
+	if fin {
+		first |= 0x80
+	}
+	frame := []byte{first}
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, 0x80|byte(n))
+	case n <= 0xFFFF:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+
+	mask := make([]byte, 4)
+	rand.Read(mask)
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// WriteText отправляет текстовое сообщение
+func (c *Client) WriteText(text string) error {
+	return c.WriteFrame(true, 0x1, []byte(text))
+}
+
+// ReadFrame читает кадр сервера, ожидая не дольше timeout
+func (c *Client) ReadFrame(timeout time.Duration) (int, []byte, error) {
+	c.conn.SetReadDeadline(time.Now().Add(timeout))
+
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return 0, nil, err
+	}
+	return int(header[0] & 0x0F), payload, nil
+}
+
+// Close закрывает соединение без кадра закрытия
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
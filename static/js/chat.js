@@ -0,0 +1,113 @@
+// Чат в реальном времени: одно WebSocket-соединение на страницу и комната на каждый блок .chat
+(function () {
+    const boxes = {};
+    let socket;
+
+    function render(box) {
+        box.innerHTML = '';
+        box.online = document.createElement('p');
+        box.more = document.createElement('button');
+        box.more.type = 'button';
+        box.more.textContent = box.dataset.more;
+        box.more.hidden = true;
+        box.list = document.createElement('ul');
+        const form = document.createElement('form');
+        const input = document.createElement('input');
+        input.maxLength = 1000;
+        const submit = document.createElement('input');
+        submit.type = 'submit';
+        submit.value = box.dataset.send;
+        form.append(input, submit);
+        box.append(box.online, box.more, box.list, form);
+
+        box.more.addEventListener('click', () => {
+            const first = box.list.firstElementChild;
+            send({ type: 'history', room: box.room, before: first && first.dataset.id });
+        });
+        form.addEventListener('submit', (event) => {
+            event.preventDefault();
+            if (input.value.trim() !== '') {
+                send({ type: 'message', room: box.room, text: input.value });
+                input.value = '';
+            }
+        });
+    }
+
+    function item(box, message) {
+        const li = document.createElement('li');
+        li.dataset.id = message.id;
+        const from = document.createElement('b');
+        from.textContent = message.mine ? message.from + ' ' + box.dataset.you : message.from;
+        const time = new Date(message.sentAt).toLocaleTimeString([], { hour: '2-digit', minute: '2-digit' });
+        li.append(time + ' ', from, ': ' + message.text);
+        return li;
+    }
+
+    function send(message) {
+        if (socket && socket.readyState === WebSocket.OPEN) {
+            socket.send(JSON.stringify(message));
+        }
+    }
+
+    function connect() {
+        const scheme = location.protocol === 'https:' ? 'wss://' : 'ws://';
+        socket = new WebSocket(scheme + location.host + '/chat');
+
+        socket.addEventListener('open', () => {
+            document.querySelectorAll('.chat').forEach((box) => {
+                render(box);
+                send({ type: 'join', room: box.dataset.room });
+            });
+        });
+
+        socket.addEventListener('message', (event) => {
+            const msg = JSON.parse(event.data);
+            if (msg.type === 'joined') {
+                const box = document.querySelector('.chat[data-room="' + CSS.escape(msg.alias) + '"]');
+                if (box) {
+                    box.room = msg.room;
+                    boxes[msg.room] = box;
+                }
+                return;
+            }
+
+            const box = boxes[msg.room] || document.querySelector('.chat[data-room="' + CSS.escape(msg.room || '') + '"]');
+            if (msg.type === 'error') {
+                if (box) {
+                    box.online.textContent = msg.error;
+                } else {
+                    console.warn(msg.error);
+                }
+                return;
+            }
+            if (!box) {
+                return;
+            }
+
+            switch (msg.type) {
+            case 'history':
+                box.list.prepend(...(msg.messages || []).map((message) => item(box, message)));
+                box.more.hidden = !msg.hasMore;
+                break;
+            case 'message':
+                box.list.append(item(box, msg.message));
+                break;
+            case 'presence':
+                box.online.textContent = box.dataset.presence + ': ' + (msg.online || []).join(', ');
+                break;
+            }
+        });
+
+        socket.addEventListener('close', (event) => {
+            Object.keys(boxes).forEach((room) => delete boxes[room]);
+            // Сервер отключил за медленную работу или перезапускается — пробуем снова
+            if (event.code !== 1008) {
+                setTimeout(connect, 3000);
+            }
+        });
+    }
+
+    if (document.querySelector('.chat')) {
+        connect();
+    }
+})();
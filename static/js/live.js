@@ -0,0 +1,24 @@
+// Живой счётчик на странице профиля: время без сигарет, экономия и новые достижения
+(function () {
+    const source = new EventSource('/profile/live');
+
+    source.addEventListener('stats', (event) => {
+        const stats = JSON.parse(event.data);
+        document.getElementById('time-not-smoke').textContent = stats.timeNotSmoke;
+        document.getElementById('money-saved').textContent = stats.moneySavedText;
+    });
+
+    source.addEventListener('achievement', (event) => {
+        const achievement = JSON.parse(event.data);
+        const item = document.createElement('li');
+        const title = document.createElement('b');
+        title.textContent = achievement.title;
+        item.append(title, ' — ' + achievement.description + ' (' + document.getElementById('achievements').dataset.justNow + ')');
+        document.getElementById('achievements').append(item);
+
+        const empty = document.getElementById('no-achievements');
+        if (empty) {
+            empty.remove();
+        }
+    });
+})();
@@ -0,0 +1,57 @@
+// Подписка браузера на push-уведомления о достижениях и просьбах о поддержке
+(function () {
+    const status = document.getElementById('push-status');
+
+    if (!('serviceWorker' in navigator) || !('PushManager' in window)) {
+        status.textContent = status.dataset.unsupported;
+        return;
+    }
+
+    function urlBase64ToUint8Array(base64) {
+        const padding = '='.repeat((4 - base64.length % 4) % 4);
+        const raw = atob((base64 + padding).replace(/-/g, '+').replace(/_/g, '/'));
+        return Uint8Array.from(raw, (c) => c.charCodeAt(0));
+    }
+
+    async function subscribe() {
+        const permission = await Notification.requestPermission();
+        if (permission !== 'granted') {
+            status.textContent = status.dataset.denied;
+            return;
+        }
+
+        const { publicKey } = await (await fetch('/push/key')).json();
+        const registration = await navigator.serviceWorker.register('/static/js/sw.js');
+        const subscription = await registration.pushManager.subscribe({
+            userVisibleOnly: true,
+            applicationServerKey: urlBase64ToUint8Array(publicKey),
+        });
+
+        const response = await fetch('/push/subscriptions', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(subscription),
+        });
+        status.textContent = response.ok ? status.dataset.enabled : status.dataset.failed;
+    }
+
+    async function unsubscribe() {
+        const registration = await navigator.serviceWorker.getRegistration('/static/js/sw.js');
+        const subscription = registration && await registration.pushManager.getSubscription();
+        if (!subscription) {
+            status.textContent = status.dataset.notEnabled;
+            return;
+        }
+
+        await fetch('/push/subscriptions', {
+            method: 'DELETE',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ endpoint: subscription.endpoint }),
+        });
+        await subscription.unsubscribe();
+        status.textContent = status.dataset.disabled;
+    }
+
+    document.getElementById('push-subscribe').addEventListener('click', subscribe);
+    document.getElementById('push-unsubscribe').addEventListener('click', unsubscribe);
+})();
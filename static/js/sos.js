@@ -0,0 +1,25 @@
+// Сменяет шаги SOS-сессии по состоянию, которое считает сервер
+(function () {
+    const root = document.getElementById('sos');
+    const step = document.getElementById('sos-step');
+    const remaining = document.getElementById('sos-remaining');
+    if (!root || !remaining) {
+        return;
+    }
+
+    const timer = setInterval(async () => {
+        const response = await fetch('/sos/' + root.dataset.id + '/state');
+        if (!response.ok) {
+            return;
+        }
+        const state = await response.json();
+        if (state.done) {
+            clearInterval(timer);
+            step.textContent = root.dataset.done;
+            remaining.parentElement.remove();
+            return;
+        }
+        step.textContent = state.current.text;
+        remaining.textContent = state.remaining;
+    }, 1000);
+})();
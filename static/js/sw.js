@@ -0,0 +1,14 @@
+// Service worker показывает push-уведомления и открывает нужную страницу по клику
+self.addEventListener('push', (event) => {
+    const data = event.data ? event.data.json() : {};
+    event.waitUntil(self.registration.showNotification(data.title || 'QuitSmoking', {
+        body: data.body,
+        icon: '/static/logo/logo.webp',
+        data: { url: data.url || '/profile' },
+    }));
+});
+
+self.addEventListener('notificationclick', (event) => {
+    event.notification.close();
+    event.waitUntil(clients.openWindow(event.notification.data.url));
+});
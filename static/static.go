@@ -0,0 +1,20 @@
+// Package static шаблоны, скрипты и картинки фронтенда, встроенные в бинарник
+package static
+
+import (
+	"embed"
+	"io/fs"
+	"os"
+)
+
+//go:embed templates js logo
+var embedded embed.FS
+
+// FS возвращает файлы фронтенда. В режиме разработки они читаются с диска из dir,
+// чтобы правки шаблонов и скриптов были видны без пересборки
+func FS(dev bool, dir string) fs.FS {
+	if dev {
+		return os.DirFS(dir)
+	}
+	return embedded
+}